@@ -0,0 +1,158 @@
+// Package async provides a Future type for running functions concurrently
+// and collecting their results.
+//
+//	user := async.Go(ctx, func(ctx context.Context) (User, error) {
+//	    return users.Get(ctx, id)
+//	})
+//	orders := async.Go(ctx, func(ctx context.Context) ([]Order, error) {
+//	    return orders.List(ctx, id)
+//	})
+//	u, err := user.Await(ctx)
+//	...
+//
+// A panic inside the function is recovered and reported as a *PanicError
+// instead of crashing the process.
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrNoFutures is the error of a Race over no futures.
+var ErrNoFutures = errors.New("async: no futures")
+
+// PanicError is the error of a Future whose function panicked.
+type PanicError struct {
+	Value any    // the value passed to panic
+	Stack []byte // the goroutine stack at the time of the panic
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("async: panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// Future is the eventual result of a function started by Go.
+type Future[T any] struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	val    T
+	err    error
+}
+
+func newFuture[T any](cancel context.CancelFunc) *Future[T] {
+	return &Future[T]{done: make(chan struct{}), cancel: cancel}
+}
+
+func (f *Future[T]) resolve(val T, err error) {
+	f.val, f.err = val, err
+	close(f.done)
+	f.cancel()
+}
+
+// Go runs fn in a new goroutine and returns a Future for its result.
+// fn receives a context derived from ctx that is cancelled once fn returns,
+// or earlier if a combinator such as Race no longer needs the result.
+func Go[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) *Future[T] {
+	ctx, cancel := context.WithCancel(ctx)
+	f := newFuture[T](cancel)
+	go func() {
+		var val T
+		var err error
+		defer func() {
+			if r := recover(); r != nil {
+				var zero T
+				val, err = zero, &PanicError{Value: r, Stack: debug.Stack()}
+			}
+			f.resolve(val, err)
+		}()
+		val, err = fn(ctx)
+	}()
+	return f
+}
+
+// Done returns a channel that is closed when the result is available.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await waits for the result, or for ctx to be done. In the latter case it
+// returns ctx.Err() and the function keeps running.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// All returns a Future that resolves to the results of fs in order once
+// all have succeeded, or to the first error as soon as any fails; the
+// remaining futures are then cancelled.
+func All[T any](fs ...*Future[T]) *Future[[]T] {
+	all := newFuture[[]T](func() {})
+	go func() {
+		finished := fanIn(fs)
+		for range fs {
+			i := <-finished
+			if err := fs[i].err; err != nil {
+				cancelAll(fs)
+				all.resolve(nil, err)
+				return
+			}
+		}
+		vals := make([]T, len(fs))
+		for i, f := range fs {
+			vals[i] = f.val
+		}
+		all.resolve(vals, nil)
+	}()
+	return all
+}
+
+// Race returns a Future that resolves to the result, success or failure,
+// of whichever of fs finishes first; the others are then cancelled.
+// Race over no futures fails with ErrNoFutures.
+func Race[T any](fs ...*Future[T]) *Future[T] {
+	race := newFuture[T](func() {})
+	if len(fs) == 0 {
+		var zero T
+		race.resolve(zero, ErrNoFutures)
+		return race
+	}
+	go func() {
+		first := fs[<-fanIn(fs)]
+		cancelAll(fs)
+		race.resolve(first.val, first.err)
+	}()
+	return race
+}
+
+// fanIn returns a channel that receives the index of each future in fs as
+// it finishes.
+func fanIn[T any](fs []*Future[T]) <-chan int {
+	finished := make(chan int, len(fs))
+	for i, f := range fs {
+		go func(i int, f *Future[T]) {
+			<-f.done
+			finished <- i
+		}(i, f)
+	}
+	return finished
+}
+
+func cancelAll[T any](fs []*Future[T]) {
+	for _, f := range fs {
+		f.cancel()
+	}
+}
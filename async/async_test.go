@@ -0,0 +1,157 @@
+package async_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/async"
+)
+
+const testTimeout = 5 * time.Second
+
+func await[T any](t *testing.T, f *async.Future[T]) (T, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	v, err := f.Await(ctx)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() != nil {
+		t.Fatal("future did not resolve in time")
+	}
+	return v, err
+}
+
+// blocked returns a future that resolves only when its context is cancelled.
+func blocked(ctx context.Context) *async.Future[int] {
+	return async.Go(ctx, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+}
+
+func TestGo(t *testing.T) {
+	t.Run("value", func(t *testing.T) {
+		f := async.Go(context.Background(), func(context.Context) (int, error) { return 42, nil })
+		if v, err := await(t, f); v != 42 || err != nil {
+			t.Fatalf("Await() = %v, %v, want 42, nil", v, err)
+		}
+		select {
+		case <-f.Done():
+		default:
+			t.Fatal("Done() not closed after Await returned")
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		want := errors.New("failed")
+		f := async.Go(context.Background(), func(context.Context) (int, error) { return 1, want })
+		if _, err := await(t, f); !errors.Is(err, want) {
+			t.Fatalf("Await() error = %v, want %v", err, want)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		f := async.Go(context.Background(), func(context.Context) (int, error) { panic("boom") })
+		_, err := await(t, f)
+		var pe *async.PanicError
+		if !errors.As(err, &pe) {
+			t.Fatalf("Await() error = %v, want *PanicError", err)
+		}
+		if pe.Value != "boom" || len(pe.Stack) == 0 {
+			t.Fatalf("PanicError = %+v", pe)
+		}
+	})
+
+	t.Run("panic with error", func(t *testing.T) {
+		want := errors.New("wrapped")
+		f := async.Go(context.Background(), func(context.Context) (int, error) { panic(want) })
+		if _, err := await(t, f); !errors.Is(err, want) {
+			t.Fatalf("Await() error = %v, want %v", err, want)
+		}
+	})
+
+	t.Run("await context", func(t *testing.T) {
+		f := blocked(context.Background())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := f.Await(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Await() error = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("parent cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		f := blocked(ctx)
+		cancel()
+		if _, err := await(t, f); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Await() error = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestAll(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		fs := []*async.Future[int]{
+			async.Go(ctx, func(context.Context) (int, error) { time.Sleep(5 * time.Millisecond); return 1, nil }),
+			async.Go(ctx, func(context.Context) (int, error) { return 2, nil }),
+		}
+		got, err := await(t, async.All(fs...))
+		if err != nil || !reflect.DeepEqual(got, []int{1, 2}) {
+			t.Fatalf("All() = %v, %v, want [1 2], nil", got, err)
+		}
+	})
+
+	t.Run("first error cancels rest", func(t *testing.T) {
+		want := errors.New("failed")
+		slow := blocked(ctx)
+		fail := async.Go(ctx, func(context.Context) (int, error) { return 0, want })
+
+		if _, err := await(t, async.All(slow, fail)); !errors.Is(err, want) {
+			t.Fatalf("All() error = %v, want %v", err, want)
+		}
+		if _, err := await(t, slow); !errors.Is(err, context.Canceled) {
+			t.Fatalf("remaining future error = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		got, err := await(t, async.All[int]())
+		if err != nil || len(got) != 0 {
+			t.Fatalf("All() = %v, %v, want [], nil", got, err)
+		}
+	})
+}
+
+func TestRace(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("first wins and rest cancelled", func(t *testing.T) {
+		slow := blocked(ctx)
+		fast := async.Go(ctx, func(context.Context) (int, error) { return 7, nil })
+
+		if v, err := await(t, async.Race(slow, fast)); v != 7 || err != nil {
+			t.Fatalf("Race() = %v, %v, want 7, nil", v, err)
+		}
+		if _, err := await(t, slow); !errors.Is(err, context.Canceled) {
+			t.Fatalf("losing future error = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("first error wins", func(t *testing.T) {
+		want := errors.New("failed")
+		fail := async.Go(ctx, func(context.Context) (int, error) { return 0, want })
+		if _, err := await(t, async.Race(blocked(ctx), fail)); !errors.Is(err, want) {
+			t.Fatalf("Race() error = %v, want %v", err, want)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if _, err := await(t, async.Race[int]()); !errors.Is(err, async.ErrNoFutures) {
+			t.Fatalf("Race() error = %v, want ErrNoFutures", err)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+// Package bufpool provides pooled *bytes.Buffer values grouped by capacity.
+//
+//	buf := bufpool.Get()
+//	defer bufpool.Put(buf)
+//	json.NewEncoder(buf).Encode(v)
+//
+// Buffers are kept in power-of-two size classes so that a request for a
+// large buffer does not evict small ones, and so that a single oversized
+// buffer is never retained indefinitely.
+//
+// In race-enabled builds (go test -race), Put panics when a buffer is
+// returned twice, which would otherwise hand the same buffer to two users,
+// and Get panics on a pooled buffer that was written to after Put.
+package bufpool
+
+import (
+	"bytes"
+	"math/bits"
+	"sync"
+)
+
+const (
+	minClassShift = 9  // 512 B
+	maxClassShift = 24 // 16 MiB
+	numClasses    = maxClassShift - minClassShift + 1
+)
+
+// MaxSize is the largest capacity retained by the pool. Buffers that grew
+// beyond it are dropped by Put and left to the garbage collector.
+const MaxSize = 1 << maxClassShift
+
+var pools [numClasses]sync.Pool
+
+// Get returns an empty buffer from the smallest size class.
+func Get() *bytes.Buffer {
+	return get(0)
+}
+
+// GetN returns an empty buffer whose capacity is at least min.
+// Requests above MaxSize are served by a new, unpooled buffer.
+func GetN(min int) *bytes.Buffer {
+	if min > MaxSize {
+		return bytes.NewBuffer(make([]byte, 0, min))
+	}
+	return get(classFor(min))
+}
+
+func get(class int) *bytes.Buffer {
+	if b, ok := pools[class].Get().(*bytes.Buffer); ok {
+		checkout(b)
+		return b
+	}
+	return bytes.NewBuffer(make([]byte, 0, 1<<(class+minClassShift)))
+}
+
+// Put resets b and returns it to the pool. b must not be used afterwards.
+// Put(nil) is a no-op.
+func Put(b *bytes.Buffer) {
+	if b == nil {
+		return
+	}
+	c := b.Cap()
+	if c < 1<<minClassShift || c > MaxSize {
+		return
+	}
+	b.Reset()
+	checkin(b)
+	// Round down so every buffer in class i has capacity >= 1<<(i+minClassShift).
+	class := bits.Len(uint(c)) - 1 - minClassShift
+	pools[class].Put(b)
+}
+
+// classFor returns the smallest class whose buffers hold n bytes.
+func classFor(n int) int {
+	if n <= 1<<minClassShift {
+		return 0
+	}
+	return bits.Len(uint(n-1)) - minClassShift
+}
@@ -0,0 +1,77 @@
+package bufpool_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rin2yh/gouse/bufpool"
+)
+
+func TestGet(t *testing.T) {
+	b := bufpool.Get()
+	if b.Len() != 0 {
+		t.Fatalf("Len() = %d, want 0", b.Len())
+	}
+	if b.Cap() < 512 {
+		t.Fatalf("Cap() = %d, want >= 512", b.Cap())
+	}
+	b.WriteString("hello")
+	bufpool.Put(b)
+
+	if b := bufpool.Get(); b.Len() != 0 {
+		t.Fatalf("pooled buffer not reset: %q", b.String())
+	}
+}
+
+func TestGetN(t *testing.T) {
+	tests := map[string]int{
+		"zero":        0,
+		"small":       100,
+		"class edge":  4096,
+		"above edge":  4097,
+		"large":       1 << 20,
+		"max":         bufpool.MaxSize,
+		"above max":   bufpool.MaxSize + 1,
+		"huge unpool": 3 * bufpool.MaxSize,
+	}
+	for name, n := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := bufpool.GetN(n)
+			if b.Cap() < n {
+				t.Fatalf("GetN(%d).Cap() = %d", n, b.Cap())
+			}
+			if b.Len() != 0 {
+				t.Fatalf("GetN(%d).Len() = %d, want 0", n, b.Len())
+			}
+			bufpool.Put(b)
+		})
+	}
+
+	t.Run("grown buffer reused", func(t *testing.T) {
+		// A buffer that grew past its class must still satisfy GetN for
+		// the class it is returned to.
+		for i := 0; i < 100; i++ {
+			b := bufpool.Get()
+			b.Write(make([]byte, 3000+i))
+			bufpool.Put(b)
+			if got := bufpool.GetN(2048); got.Cap() < 2048 {
+				t.Fatalf("GetN(2048).Cap() = %d", got.Cap())
+			}
+		}
+	})
+}
+
+func TestPut(t *testing.T) {
+	bufpool.Put(nil)                                      // no-op
+	bufpool.Put(new(bytes.Buffer))                        // too small to pool
+	bufpool.Put(bytes.NewBuffer(make([]byte, 0, 64<<20))) // too large to pool
+}
+
+func BenchmarkGetPut(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := bufpool.Get()
+		buf.WriteString("payload")
+		bufpool.Put(buf)
+	}
+}
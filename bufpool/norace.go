@@ -0,0 +1,8 @@
+//go:build !race
+
+package bufpool
+
+import "bytes"
+
+func checkin(*bytes.Buffer)  {}
+func checkout(*bytes.Buffer) {}
@@ -0,0 +1,32 @@
+//go:build race
+
+package bufpool
+
+import "bytes"
+
+// poison is written into the spare capacity of pooled buffers, so that
+// returning one twice is reported instead of silently sharing it between
+// two callers. Marking the buffer itself, rather than recording it outside
+// the pool, leaves the garbage collector free to drop pooled buffers.
+const poison = "\xde\xad\xbe\xef bufpool: buffer is in the pool"
+
+// checkin is called by Put on a reset buffer about to be pooled.
+func checkin(b *bytes.Buffer) {
+	if poisoned(b) {
+		panic("bufpool: buffer returned to pool twice")
+	}
+	_ = append(b.AvailableBuffer(), poison...)
+}
+
+// checkout is called by Get on a buffer taken from the pool.
+func checkout(b *bytes.Buffer) {
+	if !poisoned(b) {
+		panic("bufpool: buffer written to after Put")
+	}
+	clear(b.AvailableBuffer()[:len(poison)])
+}
+
+func poisoned(b *bytes.Buffer) bool {
+	avail := b.AvailableBuffer()
+	return cap(avail) >= len(poison) && string(avail[:len(poison)]) == poison
+}
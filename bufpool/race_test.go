@@ -0,0 +1,27 @@
+//go:build race
+
+package bufpool_test
+
+import (
+	"testing"
+
+	"github.com/rin2yh/gouse/bufpool"
+)
+
+func TestPutTwicePanics(t *testing.T) {
+	b := bufpool.Get()
+	bufpool.Put(b)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on double Put")
+		}
+	}()
+	bufpool.Put(b)
+}
+
+func TestGetPut(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		b := bufpool.Get()
+		bufpool.Put(b) // unused buffers must not look pooled
+	}
+}
@@ -0,0 +1,185 @@
+// Package bytesize parses and formats byte counts such as "512KiB" and
+// "1.5GB".
+//
+// Size implements flag.Value and encoding.TextUnmarshaler, so it can be used
+// directly in flags and text-based configuration:
+//
+//	var limit = bytesize.MiB
+//	flag.Var(&limit, "max-body", "maximum request body size")
+package bytesize
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Size is a number of bytes.
+type Size uint64
+
+// SI (decimal) units.
+const (
+	B  Size = 1
+	KB Size = 1000 * B
+	MB Size = 1000 * KB
+	GB Size = 1000 * MB
+	TB Size = 1000 * GB
+	PB Size = 1000 * TB
+	EB Size = 1000 * PB
+)
+
+// Binary (IEC) units.
+const (
+	KiB Size = 1 << (10 * (iota + 1))
+	MiB
+	GiB
+	TiB
+	PiB
+	EiB
+)
+
+// ErrSyntax is returned (wrapped) by Parse when s is not a valid size.
+var ErrSyntax = errors.New("bytesize: invalid size")
+
+// ErrRange is returned (wrapped) by Parse when s does not fit in a Size.
+var ErrRange = errors.New("bytesize: size out of range")
+
+var units = map[string]Size{
+	"":    B,
+	"b":   B,
+	"kb":  KB,
+	"mb":  MB,
+	"gb":  GB,
+	"tb":  TB,
+	"pb":  PB,
+	"eb":  EB,
+	"kib": KiB,
+	"mib": MiB,
+	"gib": GiB,
+	"tib": TiB,
+	"pib": PiB,
+	"eib": EiB,
+}
+
+// Parse parses a size such as "512KiB", "1.5GB", "10 MB" or "4096".
+// Units are case-insensitive; KB, MB, ... are powers of 1000 and KiB,
+// MiB, ... are powers of 1024. A number without a unit is in bytes.
+// Fractional results are truncated to whole bytes.
+func Parse(s string) (Size, error) {
+	in := strings.TrimSpace(s)
+	i := 0
+	for i < len(in) && (in[i] == '.' || '0' <= in[i] && in[i] <= '9') {
+		i++
+	}
+	num, unit := in[:i], strings.ToLower(strings.TrimSpace(in[i:]))
+
+	scale, ok := units[unit]
+	if !ok || num == "" {
+		return 0, errorf(ErrSyntax, s)
+	}
+
+	if !strings.Contains(num, ".") {
+		n, err := strconv.ParseUint(num, 10, 64)
+		if err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				return 0, errorf(ErrRange, s)
+			}
+			return 0, errorf(ErrSyntax, s)
+		}
+		hi, lo := bits.Mul64(n, uint64(scale))
+		if hi != 0 {
+			return 0, errorf(ErrRange, s)
+		}
+		return Size(lo), nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, errorf(ErrSyntax, s)
+	}
+	v := f * float64(scale)
+	if v >= math.MaxUint64 {
+		return 0, errorf(ErrRange, s)
+	}
+	return Size(v), nil
+}
+
+// MustParse is like Parse but panics if s cannot be parsed.
+func MustParse(s string) Size {
+	n, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func errorf(err error, s string) error {
+	return &parseError{err: err, input: s}
+}
+
+type parseError struct {
+	err   error
+	input string
+}
+
+func (e *parseError) Error() string { return e.err.Error() + " " + strconv.Quote(e.input) }
+func (e *parseError) Unwrap() error { return e.err }
+
+// String formats s with binary units, e.g. "1.5GiB", using at most two
+// decimal places. It implements fmt.Stringer and flag.Value.
+func (s Size) String() string {
+	return format(s, 1024, []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"})
+}
+
+// SI formats s with decimal units, e.g. "1.5GB", using at most two decimal
+// places.
+func (s Size) SI() string {
+	return format(s, 1000, []string{"B", "KB", "MB", "GB", "TB", "PB", "EB"})
+}
+
+func format(s Size, base float64, names []string) string {
+	v := float64(s)
+	i := 0
+	for i < len(names)-1 && v >= base {
+		v /= base
+		i++
+	}
+	str := strconv.FormatFloat(v, 'f', 2, 64)
+	// Rounding can carry into the next unit: 1023.999KiB -> "1MiB".
+	if str == strconv.FormatFloat(base, 'f', 2, 64) && i < len(names)-1 {
+		str, i = "1", i+1
+	}
+	if strings.Contains(str, ".") {
+		str = strings.TrimRight(strings.TrimRight(str, "0"), ".")
+	}
+	return str + names[i]
+}
+
+// Set parses value into s. It implements flag.Value.
+func (s *Size) Set(value string) error {
+	n, err := Parse(value)
+	if err != nil {
+		return err
+	}
+	*s = n
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Size) UnmarshalText(text []byte) error {
+	return s.Set(string(text))
+}
+
+// MarshalText implements encoding.TextMarshaler. It uses String when that
+// is exact and falls back to a plain byte count otherwise (including
+// values String rounds out of range), so the result always unmarshals to
+// the same Size.
+func (s Size) MarshalText() ([]byte, error) {
+	str := s.String()
+	if n, err := Parse(str); err == nil && n == s {
+		return []byte(str), nil
+	}
+	return []byte(strconv.FormatUint(uint64(s), 10)), nil
+}
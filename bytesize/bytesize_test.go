@@ -0,0 +1,122 @@
+package bytesize_test
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"math"
+	"testing"
+
+	"github.com/rin2yh/gouse/bytesize"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    bytesize.Size
+		wantErr error
+	}{
+		"bytes":           {"4096", 4096, nil},
+		"bytes unit":      {"10B", 10, nil},
+		"binary":          {"512KiB", 512 * 1024, nil},
+		"si":              {"2MB", 2_000_000, nil},
+		"fractional":      {"1.5GB", 1_500_000_000, nil},
+		"fractional bin":  {"1.5GiB", 3 * bytesize.GiB / 2, nil},
+		"space":           {"10 MiB", 10 * bytesize.MiB, nil},
+		"case":            {"1kib", bytesize.KiB, nil},
+		"truncated":       {"1.5B", 1, nil},
+		"max":             {"18446744073709551615", math.MaxUint64, nil},
+		"empty":           {"", 0, bytesize.ErrSyntax},
+		"unit only":       {"KB", 0, bytesize.ErrSyntax},
+		"unknown unit":    {"1KX", 0, bytesize.ErrSyntax},
+		"bare prefix":     {"1K", 0, bytesize.ErrSyntax},
+		"negative":        {"-1KB", 0, bytesize.ErrSyntax},
+		"bad number":      {"1.2.3MB", 0, bytesize.ErrSyntax},
+		"overflow":        {"17EiB", 0, bytesize.ErrRange},
+		"overflow frac":   {"16.5EiB", 0, bytesize.ErrRange},
+		"overflow digits": {"18446744073709551616", 0, bytesize.ErrRange},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := bytesize.Parse(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[string]struct {
+		size   bytesize.Size
+		binary string
+		si     string
+	}{
+		"zero":     {0, "0B", "0B"},
+		"bytes":    {999, "999B", "999B"},
+		"kilo":     {1024, "1KiB", "1.02KB"},
+		"fraction": {3 * bytesize.GiB / 2, "1.5GiB", "1.61GB"},
+		"si exact": {1_500_000_000, "1.4GiB", "1.5GB"},
+		"carry":    {bytesize.MiB - 1, "1MiB", "1.05MB"},
+		"max":      {math.MaxUint64, "16EiB", "18.45EB"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.size.String(); got != tt.binary {
+				t.Errorf("String() = %q, want %q", got, tt.binary)
+			}
+			if got := tt.size.SI(); got != tt.si {
+				t.Errorf("SI() = %q, want %q", got, tt.si)
+			}
+		})
+	}
+}
+
+func TestFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	limit := bytesize.MiB
+	fs.Var(&limit, "limit", "")
+
+	if err := fs.Parse([]string{"-limit", "256KiB"}); err != nil {
+		t.Fatal(err)
+	}
+	if limit != 256*bytesize.KiB {
+		t.Fatalf("limit = %v, want 256KiB", limit)
+	}
+	if err := fs.Parse([]string{"-limit", "lots"}); err == nil {
+		t.Fatal("expected error for invalid flag value")
+	}
+}
+
+func TestText(t *testing.T) {
+	var cfg struct {
+		Max bytesize.Size `json:"max"`
+	}
+	if err := json.Unmarshal([]byte(`{"max":"1.5MB"}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Max != 1_500_000 {
+		t.Fatalf("Max = %d, want 1500000", cfg.Max)
+	}
+
+	tests := map[string]bytesize.Size{
+		"exact":   2 * bytesize.GiB,
+		"inexact": 1_500_000,
+		"max":     math.MaxUint64,
+	}
+	for name, size := range tests {
+		t.Run(name, func(t *testing.T) {
+			text, err := size.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytesize.Size
+			if err := got.UnmarshalText(text); err != nil || got != size {
+				t.Fatalf("round trip via %q = %d, %v, want %d", text, got, err, size)
+			}
+		})
+	}
+}
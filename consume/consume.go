@@ -0,0 +1,204 @@
+// Package consume provides an at-least-once message processing loop that
+// can be adapted to queue clients such as SQS, Kafka or NATS.
+//
+// A message is acknowledged only after its handler succeeds (or after it
+// has been handed to the dead-letter callback), so a crash or shutdown
+// never loses work; the broker redelivers anything left unacknowledged.
+//
+//	err := consume.Loop(ctx, queue, func(ctx context.Context, m *sqs.Message) error {
+//	    return process(ctx, m)
+//	}, &consume.Config[*sqs.Message]{
+//	    Concurrency: 8,
+//	    Timeout:     30 * time.Second,
+//	    MaxRetries:  3,
+//	})
+//
+// Like graceful.Run, Loop stops receiving when ctx is cancelled and waits
+// for in-flight messages to finish before returning.
+package consume
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBackoffBase = 100 * time.Millisecond
+	defaultBackoffMax  = 10 * time.Second
+)
+
+// Source is the interface required by Loop.
+//
+// Receive should block until a message is available or ctx is done.
+// Ack marks msg as processed so it is not delivered again.
+type Source[M any] interface {
+	Receive(ctx context.Context) (M, error)
+	Ack(ctx context.Context, msg M) error
+}
+
+// Handler processes a single message.
+type Handler[M any] func(ctx context.Context, msg M) error
+
+// Config holds optional configuration for Loop. The zero value is valid.
+type Config[M any] struct {
+	// Concurrency is the maximum number of messages handled at once.
+	// Defaults to 1 if zero.
+	Concurrency int
+
+	// Timeout bounds each handler call. Zero means no timeout.
+	Timeout time.Duration
+
+	// MaxRetries is the number of times a failed handler call is retried
+	// before the message is dead-lettered. Zero disables retries.
+	MaxRetries int
+
+	// Backoff returns the delay before retry attempt n (starting at 1).
+	// Defaults to exponential backoff from 100ms, capped at 10s.
+	Backoff func(n int) time.Duration
+
+	// DeadLetter is called with the last handler error once retries are
+	// exhausted. The message is acknowledged only if DeadLetter returns nil.
+	// If nil, exhausted messages are left unacknowledged for redelivery.
+	DeadLetter func(ctx context.Context, msg M, err error) error
+
+	// DrainTimeout bounds how long in-flight messages may keep running
+	// after ctx is cancelled; their contexts are cancelled once it expires.
+	// Zero means wait until they finish.
+	DrainTimeout time.Duration
+
+	// OnError is called when Ack or DeadLetter fails. Optional.
+	OnError func(msg M, err error)
+}
+
+// Loop receives messages from src and handles them with h until ctx is
+// cancelled or Receive fails.
+//
+// On cancellation Loop stops receiving, waits for in-flight messages to
+// finish (see Config.DrainTimeout) and returns nil. If Receive fails for any
+// other reason, Loop drains in the same way and returns that error.
+//
+// If cfg is nil, messages are handled one at a time without retries.
+func Loop[M any](ctx context.Context, src Source[M], h Handler[M], cfg *Config[M]) error {
+	if cfg == nil {
+		cfg = &Config[M]{}
+	}
+	concurrency := 1
+	if cfg.Concurrency > 0 {
+		concurrency = cfg.Concurrency
+	}
+
+	// In-flight work outlives ctx so that messages being processed at
+	// shutdown can complete; workCtx is cancelled only by DrainTimeout.
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	var loopErr error
+	for {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		msg, err := src.Receive(ctx)
+		if err != nil {
+			<-sem
+			if ctx.Err() == nil {
+				loopErr = err
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			process(workCtx, src, h, cfg, msg)
+		}()
+	}
+
+	if cfg.DrainTimeout > 0 {
+		timer := time.AfterFunc(cfg.DrainTimeout, cancelWork)
+		defer timer.Stop()
+	}
+	wg.Wait()
+
+	return loopErr
+}
+
+// process handles msg with retries and acknowledges it on success or after
+// successful dead-lettering.
+func process[M any](ctx context.Context, src Source[M], h Handler[M], cfg *Config[M], msg M) {
+	backoff := cfg.Backoff
+	if backoff == nil {
+		backoff = defaultBackoff
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = call(ctx, h, cfg.Timeout, msg); err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			// Drain timeout expired: leave msg unacknowledged.
+			return
+		}
+		if attempt >= cfg.MaxRetries {
+			break
+		}
+
+		timer := time.NewTimer(backoff(attempt + 1))
+		select {
+		case <-ctx.Done():
+			// Drain timeout expired: leave msg unacknowledged.
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+
+	if err != nil {
+		if cfg.DeadLetter == nil {
+			return
+		}
+		if dlErr := cfg.DeadLetter(ctx, msg, err); dlErr != nil {
+			report(cfg, msg, dlErr)
+			return
+		}
+	}
+
+	if ackErr := src.Ack(ctx, msg); ackErr != nil {
+		report(cfg, msg, ackErr)
+	}
+}
+
+func call[M any](ctx context.Context, h Handler[M], timeout time.Duration, msg M) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return h(ctx, msg)
+}
+
+func report[M any](cfg *Config[M], msg M, err error) {
+	if cfg.OnError != nil {
+		cfg.OnError(msg, err)
+	}
+}
+
+func defaultBackoff(n int) time.Duration {
+	d := defaultBackoffBase
+	for i := 1; i < n && d < defaultBackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, defaultBackoffMax)
+}
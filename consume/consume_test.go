@@ -0,0 +1,297 @@
+package consume_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/consume"
+)
+
+const testTimeout = 5 * time.Second
+
+// chanSource delivers messages from a channel and records acknowledgements.
+type chanSource struct {
+	msgs       chan int
+	receiveErr error
+
+	mu    sync.Mutex
+	acked []int
+}
+
+func newChanSource(msgs ...int) *chanSource {
+	ch := make(chan int, len(msgs))
+	for _, m := range msgs {
+		ch <- m
+	}
+	return &chanSource{msgs: ch}
+}
+
+func (s *chanSource) Receive(ctx context.Context) (int, error) {
+	select {
+	case m := <-s.msgs:
+		return m, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+	if s.receiveErr != nil {
+		return 0, s.receiveErr
+	}
+	select {
+	case m := <-s.msgs:
+		return m, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func (s *chanSource) Ack(_ context.Context, m int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acked = append(s.acked, m)
+	return nil
+}
+
+func (s *chanSource) ackedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.acked)
+}
+
+// runLoop starts Loop in a goroutine; cancel is registered with t.Cleanup.
+func runLoop(t *testing.T, src *chanSource, h consume.Handler[int], cfg *consume.Config[int]) (context.CancelFunc, <-chan error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	done := make(chan error, 1)
+	go func() { done <- consume.Loop(ctx, src, h, cfg) }()
+	return cancel, done
+}
+
+func awaitLoop(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(testTimeout):
+		t.Fatal("Loop did not return in time")
+		return nil
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLoop(t *testing.T) {
+	src := newChanSource(1, 2, 3)
+	cancel, done := runLoop(t, src, func(context.Context, int) error { return nil }, nil)
+
+	waitFor(t, func() bool { return src.ackedCount() == 3 })
+	cancel()
+	if err := awaitLoop(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+}
+
+func TestLoopConcurrency(t *testing.T) {
+	const limit = 3
+	src := newChanSource(1, 2, 3, 4, 5, 6, 7, 8, 9)
+
+	var active, peak atomic.Int32
+	release := make(chan struct{})
+	cancel, done := runLoop(t, src, func(context.Context, int) error {
+		n := active.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		active.Add(-1)
+		return nil
+	}, &consume.Config[int]{Concurrency: limit})
+
+	waitFor(t, func() bool { return active.Load() == limit })
+	close(release)
+	waitFor(t, func() bool { return src.ackedCount() == 9 })
+	cancel()
+	_ = awaitLoop(t, done)
+
+	if p := peak.Load(); p != limit {
+		t.Fatalf("peak concurrency = %d, want %d", p, limit)
+	}
+}
+
+func TestLoopRetry(t *testing.T) {
+	src := newChanSource(1)
+	var calls atomic.Int32
+	cancel, done := runLoop(t, src, func(context.Context, int) error {
+		if calls.Add(1) < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	}, &consume.Config[int]{
+		MaxRetries: 2,
+		Backoff:    func(int) time.Duration { return time.Millisecond },
+	})
+
+	waitFor(t, func() bool { return src.ackedCount() == 1 })
+	cancel()
+	_ = awaitLoop(t, done)
+
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("handler calls = %d, want 3", n)
+	}
+}
+
+func TestLoopDeadLetter(t *testing.T) {
+	errHandler := errors.New("bad message")
+
+	t.Run("acknowledged after dead letter", func(t *testing.T) {
+		src := newChanSource(7)
+		var got error
+		dead := make(chan int, 1)
+		cancel, done := runLoop(t, src, func(context.Context, int) error { return errHandler }, &consume.Config[int]{
+			MaxRetries: 1,
+			Backoff:    func(int) time.Duration { return time.Millisecond },
+			DeadLetter: func(_ context.Context, m int, err error) error {
+				got = err
+				dead <- m
+				return nil
+			},
+		})
+
+		select {
+		case m := <-dead:
+			if m != 7 {
+				t.Fatalf("dead-lettered %d, want 7", m)
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("message was not dead-lettered")
+		}
+		waitFor(t, func() bool { return src.ackedCount() == 1 })
+		cancel()
+		_ = awaitLoop(t, done)
+
+		if !errors.Is(got, errHandler) {
+			t.Fatalf("DeadLetter received %v, want %v", got, errHandler)
+		}
+	})
+
+	t.Run("not acknowledged when dead letter fails", func(t *testing.T) {
+		src := newChanSource(7)
+		errDL := errors.New("dlq unavailable")
+		reported := make(chan error, 1)
+		cancel, done := runLoop(t, src, func(context.Context, int) error { return errHandler }, &consume.Config[int]{
+			DeadLetter: func(context.Context, int, error) error { return errDL },
+			OnError:    func(_ int, err error) { reported <- err },
+		})
+
+		select {
+		case err := <-reported:
+			if !errors.Is(err, errDL) {
+				t.Fatalf("OnError received %v, want %v", err, errDL)
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("OnError was not called")
+		}
+		cancel()
+		_ = awaitLoop(t, done)
+
+		if n := src.ackedCount(); n != 0 {
+			t.Fatalf("acked = %d, want 0", n)
+		}
+	})
+}
+
+func TestLoopTimeout(t *testing.T) {
+	src := newChanSource(1)
+	errs := make(chan error, 1)
+	cancel, done := runLoop(t, src, func(ctx context.Context, _ int) error {
+		<-ctx.Done()
+		errs <- ctx.Err()
+		return ctx.Err()
+	}, &consume.Config[int]{Timeout: 10 * time.Millisecond})
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("handler ctx error = %v, want DeadlineExceeded", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("handler was not timed out")
+	}
+	cancel()
+	_ = awaitLoop(t, done)
+}
+
+func TestLoopDrain(t *testing.T) {
+	t.Run("waits for in-flight", func(t *testing.T) {
+		src := newChanSource(1)
+		started := make(chan struct{})
+		release := make(chan struct{})
+		cancel, done := runLoop(t, src, func(ctx context.Context, _ int) error {
+			close(started)
+			<-release
+			return ctx.Err()
+		}, nil)
+
+		<-started
+		cancel()
+		select {
+		case <-done:
+			t.Fatal("Loop returned before in-flight message finished")
+		case <-time.After(20 * time.Millisecond):
+		}
+		close(release)
+		if err := awaitLoop(t, done); err != nil {
+			t.Fatalf("expected nil error, got: %v", err)
+		}
+		if n := src.ackedCount(); n != 1 {
+			t.Fatalf("acked = %d, want 1", n)
+		}
+	})
+
+	t.Run("drain timeout", func(t *testing.T) {
+		src := newChanSource(1)
+		started := make(chan struct{})
+		cancel, done := runLoop(t, src, func(ctx context.Context, _ int) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}, &consume.Config[int]{DrainTimeout: 10 * time.Millisecond})
+
+		<-started
+		cancel()
+		if err := awaitLoop(t, done); err != nil {
+			t.Fatalf("expected nil error, got: %v", err)
+		}
+		if n := src.ackedCount(); n != 0 {
+			t.Fatalf("acked = %d, want 0", n)
+		}
+	})
+}
+
+func TestLoopReceiveError(t *testing.T) {
+	want := errors.New("connection reset")
+	src := newChanSource()
+	src.receiveErr = want
+
+	_, done := runLoop(t, src, func(context.Context, int) error { return nil }, nil)
+	if err := awaitLoop(t, done); !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+}
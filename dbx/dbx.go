@@ -0,0 +1,118 @@
+// Package dbx provides lifecycle helpers for database pools.
+//
+// Ping waits for a database to become reachable at startup, and Cleanup
+// returns a function suitable for graceful.Config.Cleanups so pools are
+// closed only after the HTTP server has stopped serving requests:
+//
+//	db, err := sql.Open("postgres", dsn)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := dbx.Ping(ctx, db, nil); err != nil {
+//	    log.Fatal(err)
+//	}
+//	err = graceful.Run(ctx, srv, &graceful.Config{
+//	    Cleanups: []func(context.Context) error{dbx.Cleanup(db)},
+//	})
+package dbx
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	defaultPingAttempts = 5
+	defaultPingInterval = time.Second
+)
+
+// Closer is implemented by database pools such as *sql.DB.
+type Closer interface {
+	Close() error
+}
+
+// Pinger is implemented by database pools such as *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingConfig holds optional configuration for Ping. The zero value is valid.
+type PingConfig struct {
+	// Attempts is the maximum number of pings before giving up.
+	// Defaults to 5 if zero.
+	Attempts int
+
+	// Interval is the delay between failed attempts.
+	// Defaults to 1 second if zero.
+	Interval time.Duration
+}
+
+// Ping pings db until it succeeds, the attempts are exhausted, or ctx is
+// cancelled. It returns the last ping error, or ctx.Err() if ctx ended first.
+//
+// If cfg is nil, 5 attempts are made 1 second apart.
+func Ping(ctx context.Context, db Pinger, cfg *PingConfig) error {
+	if cfg == nil {
+		cfg = &PingConfig{}
+	}
+	attempts := defaultPingAttempts
+	if cfg.Attempts > 0 {
+		attempts = cfg.Attempts
+	}
+	interval := defaultPingInterval
+	if cfg.Interval > 0 {
+		interval = cfg.Interval
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = db.PingContext(ctx); err == nil {
+			return nil
+		}
+		if i == attempts-1 {
+			break
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return err
+}
+
+// Close closes each pool in order and returns the joined Close errors.
+// If ctx ends before every pool has closed, Close returns ctx.Err()
+// without waiting for the remaining pools.
+func Close(ctx context.Context, pools ...Closer) error {
+	done := make(chan error, 1)
+	go func() {
+		var errs []error
+		for _, p := range pools {
+			if err := p.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		done <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cleanup returns a function that closes pools, for use in
+// graceful.Config.Cleanups. The pools are abandoned if the shutdown deadline
+// carried by the context passes first.
+func Cleanup(pools ...Closer) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return Close(ctx, pools...)
+	}
+}
@@ -0,0 +1,107 @@
+package dbx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/dbx"
+)
+
+type fakePool struct {
+	pingErrs []error // returned by successive pings; nil once exhausted
+	pings    int
+	closeErr error
+	block    chan struct{}
+	closed   bool
+}
+
+func (p *fakePool) PingContext(ctx context.Context) error {
+	p.pings++
+	if len(p.pingErrs) == 0 {
+		return nil
+	}
+	err := p.pingErrs[0]
+	p.pingErrs = p.pingErrs[1:]
+	return err
+}
+
+func (p *fakePool) Close() error {
+	if p.block != nil {
+		<-p.block
+	}
+	p.closed = true
+	return p.closeErr
+}
+
+func TestPing(t *testing.T) {
+	errDown := errors.New("connection refused")
+	cfg := &dbx.PingConfig{Attempts: 3, Interval: time.Millisecond}
+
+	tests := map[string]struct {
+		pingErrs  []error
+		wantErr   error
+		wantPings int
+	}{
+		"first attempt": {nil, nil, 1},
+		"after retries": {[]error{errDown, errDown}, nil, 3},
+		"exhausted":     {[]error{errDown, errDown, errDown}, errDown, 3},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &fakePool{pingErrs: tt.pingErrs}
+			if err := dbx.Ping(context.Background(), p, cfg); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Ping() error = %v, want %v", err, tt.wantErr)
+			}
+			if p.pings != tt.wantPings {
+				t.Fatalf("pings = %d, want %d", p.pings, tt.wantPings)
+			}
+		})
+	}
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		p := &fakePool{pingErrs: []error{errDown, errDown}}
+		err := dbx.Ping(ctx, p, &dbx.PingConfig{Attempts: 3, Interval: time.Hour})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Ping() error = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestClose(t *testing.T) {
+	t.Run("closes all", func(t *testing.T) {
+		errClose := errors.New("close failed")
+		a, b := &fakePool{closeErr: errClose}, &fakePool{}
+		if err := dbx.Close(context.Background(), a, b); !errors.Is(err, errClose) {
+			t.Fatalf("Close() error = %v, want %v", err, errClose)
+		}
+		if !a.closed || !b.closed {
+			t.Fatal("expected every pool to be closed")
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		p := &fakePool{block: make(chan struct{})}
+		t.Cleanup(func() { close(p.block) })
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := dbx.Close(ctx, p); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Close() error = %v, want context.DeadlineExceeded", err)
+		}
+	})
+}
+
+func TestCleanup(t *testing.T) {
+	errClose := errors.New("close failed")
+	p := &fakePool{closeErr: errClose}
+
+	if err := dbx.Cleanup(p)(context.Background()); !errors.Is(err, errClose) {
+		t.Fatalf("Cleanup() error = %v, want %v", err, errClose)
+	}
+	if !p.closed {
+		t.Fatal("expected pool to be closed")
+	}
+}
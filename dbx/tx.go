@@ -0,0 +1,87 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// TxConfig holds optional configuration for WithTx. The zero value is valid.
+type TxConfig struct {
+	// TxOptions is passed to BeginTx (isolation level, read-only).
+	TxOptions *sql.TxOptions
+
+	// MaxRetries is the number of times the transaction is retried after a
+	// retryable failure. Zero disables retries.
+	MaxRetries int
+
+	// IsRetryable reports whether err should cause the transaction to be
+	// retried. Defaults to IsSerializationFailure if nil.
+	IsRetryable func(err error) bool
+}
+
+// WithTx runs fn inside a transaction on db.
+//
+// The transaction is committed if fn returns nil and rolled back if fn
+// returns an error or panics; a panic is re-raised after the rollback.
+// If fn or Commit fails with a retryable error, the whole transaction is
+// run again, up to cfg.MaxRetries times, so fn must be safe to repeat.
+//
+// If cfg is nil, the default transaction options are used without retries.
+func WithTx(ctx context.Context, db *sql.DB, cfg *TxConfig, fn func(*sql.Tx) error) error {
+	if cfg == nil {
+		cfg = &TxConfig{}
+	}
+	retryable := cfg.IsRetryable
+	if retryable == nil {
+		retryable = IsSerializationFailure
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, db, cfg.TxOptions, fn)
+		if err == nil || attempt >= cfg.MaxRetries || !retryable(err) {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return errors.Join(err, ctxErr)
+		}
+	}
+}
+
+func runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(*sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsSerializationFailure reports whether err carries SQLSTATE 40001
+// (serialization_failure) or 40P01 (deadlock_detected).
+// It recognises driver errors exposing a SQLState() string method,
+// as provided by pgx and lib/pq.
+func IsSerializationFailure(err error) bool {
+	var se interface{ SQLState() string }
+	if !errors.As(err, &se) {
+		return false
+	}
+	switch se.SQLState() {
+	case "40001", "40P01":
+		return true
+	}
+	return false
+}
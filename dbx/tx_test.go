@@ -0,0 +1,169 @@
+package dbx_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/rin2yh/gouse/dbx"
+)
+
+// txRecorder is a minimal database/sql driver that records transaction
+// outcomes. Statements are not supported.
+type txRecorder struct {
+	mu        sync.Mutex
+	begins    int
+	commits   int
+	rollbacks int
+	commitErr []error // returned by successive commits; nil once exhausted
+}
+
+func (r *txRecorder) Connect(context.Context) (driver.Conn, error) { return &recConn{r}, nil }
+func (r *txRecorder) Driver() driver.Driver                        { return nil }
+
+type recConn struct{ r *txRecorder }
+
+func (c *recConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *recConn) Close() error                        { return nil }
+func (c *recConn) Begin() (driver.Tx, error) {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+	c.r.begins++
+	return &recTx{c.r}, nil
+}
+
+type recTx struct{ r *txRecorder }
+
+func (t *recTx) Commit() error {
+	t.r.mu.Lock()
+	defer t.r.mu.Unlock()
+	t.r.commits++
+	if len(t.r.commitErr) == 0 {
+		return nil
+	}
+	err := t.r.commitErr[0]
+	t.r.commitErr = t.r.commitErr[1:]
+	return err
+}
+
+func (t *recTx) Rollback() error {
+	t.r.mu.Lock()
+	defer t.r.mu.Unlock()
+	t.r.rollbacks++
+	return nil
+}
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func openRecorder(t *testing.T, commitErr ...error) (*sql.DB, *txRecorder) {
+	t.Helper()
+	r := &txRecorder{commitErr: commitErr}
+	db := sql.OpenDB(r)
+	t.Cleanup(func() { db.Close() })
+	return db, r
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("commit", func(t *testing.T) {
+		db, r := openRecorder(t)
+		if err := dbx.WithTx(ctx, db, nil, func(*sql.Tx) error { return nil }); err != nil {
+			t.Fatalf("WithTx() error = %v", err)
+		}
+		if r.commits != 1 || r.rollbacks != 0 {
+			t.Fatalf("commits = %d, rollbacks = %d, want 1, 0", r.commits, r.rollbacks)
+		}
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		db, r := openRecorder(t)
+		want := errors.New("insert failed")
+		if err := dbx.WithTx(ctx, db, nil, func(*sql.Tx) error { return want }); !errors.Is(err, want) {
+			t.Fatalf("WithTx() error = %v, want %v", err, want)
+		}
+		if r.commits != 0 || r.rollbacks != 1 {
+			t.Fatalf("commits = %d, rollbacks = %d, want 0, 1", r.commits, r.rollbacks)
+		}
+	})
+
+	t.Run("rollback on panic", func(t *testing.T) {
+		db, r := openRecorder(t)
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Fatalf("expected panic %q to be re-raised, got %v", "boom", v)
+			}
+			if r.rollbacks != 1 {
+				t.Fatalf("rollbacks = %d, want 1", r.rollbacks)
+			}
+		}()
+		_ = dbx.WithTx(ctx, db, nil, func(*sql.Tx) error { panic("boom") })
+	})
+
+	t.Run("retry serialization failure", func(t *testing.T) {
+		db, r := openRecorder(t)
+		calls := 0
+		err := dbx.WithTx(ctx, db, &dbx.TxConfig{MaxRetries: 3}, func(*sql.Tx) error {
+			calls++
+			if calls < 3 {
+				return sqlStateError("40001")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithTx() error = %v", err)
+		}
+		if calls != 3 || r.rollbacks != 2 || r.commits != 1 {
+			t.Fatalf("calls = %d, rollbacks = %d, commits = %d, want 3, 2, 1", calls, r.rollbacks, r.commits)
+		}
+	})
+
+	t.Run("retry commit failure", func(t *testing.T) {
+		db, r := openRecorder(t, sqlStateError("40P01"))
+		if err := dbx.WithTx(ctx, db, &dbx.TxConfig{MaxRetries: 1}, func(*sql.Tx) error { return nil }); err != nil {
+			t.Fatalf("WithTx() error = %v", err)
+		}
+		if r.begins != 2 || r.commits != 2 {
+			t.Fatalf("begins = %d, commits = %d, want 2, 2", r.begins, r.commits)
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		db, r := openRecorder(t)
+		want := sqlStateError("40001")
+		err := dbx.WithTx(ctx, db, &dbx.TxConfig{MaxRetries: 2}, func(*sql.Tx) error { return want })
+		if !errors.Is(err, want) {
+			t.Fatalf("WithTx() error = %v, want %v", err, want)
+		}
+		if r.begins != 3 {
+			t.Fatalf("begins = %d, want 3", r.begins)
+		}
+	})
+
+	t.Run("non-retryable error", func(t *testing.T) {
+		db, r := openRecorder(t)
+		_ = dbx.WithTx(ctx, db, &dbx.TxConfig{MaxRetries: 2}, func(*sql.Tx) error { return sqlStateError("23505") })
+		if r.begins != 1 {
+			t.Fatalf("begins = %d, want 1", r.begins)
+		}
+	})
+
+	t.Run("custom retryable", func(t *testing.T) {
+		db, r := openRecorder(t)
+		errBusy := errors.New("database is locked")
+		cfg := &dbx.TxConfig{
+			MaxRetries:  1,
+			IsRetryable: func(err error) bool { return errors.Is(err, errBusy) },
+		}
+		_ = dbx.WithTx(ctx, db, cfg, func(*sql.Tx) error { return errBusy })
+		if r.begins != 2 {
+			t.Fatalf("begins = %d, want 2", r.begins)
+		}
+	})
+}
@@ -43,6 +43,31 @@ func Is(value any) bool {
 	}
 }
 
+// IsZero checks if v is the zero value of its type, without reflection,
+// for typed call sites on hot paths. It agrees with Is for strings,
+// numbers, bools, pointers and interfaces. Unlike Is, it reports true for
+// a zero struct or a zero-filled array; use IsSlice and IsMap for
+// containers, which are not comparable.
+func IsZero[T comparable](v T) bool {
+	var zero T
+	return v == zero
+}
+
+// IsSlice checks if s is nil or has length 0, like Is, without reflection.
+func IsSlice[S ~[]E, E any](s S) bool {
+	return len(s) == 0
+}
+
+// IsMap checks if m is nil or has length 0, like Is, without reflection.
+func IsMap[M ~map[K]V, K comparable, V any](m M) bool {
+	return len(m) == 0
+}
+
+// IsNil checks if p is nil, like Is, without reflection.
+func IsNil[T any](p *T) bool {
+	return p == nil
+}
+
 // IsNot checks if a value is not empty.
 func IsNot(value any) bool {
 	return !Is(value)
@@ -66,3 +91,24 @@ func Any(values ...any) bool {
 func All(values ...any) bool {
 	return !slices.ContainsFunc(values, IsNot)
 }
+
+// Coalesce returns the first of vals that is not empty, as reported by Is,
+// or the zero value if there is none. It replaces chains of fallbacks:
+//
+//	addr := empty.Coalesce(flagAddr, os.Getenv("ADDR"), ":8080")
+func Coalesce[T any](vals ...T) T {
+	v, _ := CoalesceOK(vals...)
+	return v
+}
+
+// CoalesceOK is like Coalesce but also reports whether a non-empty value
+// was found.
+func CoalesceOK[T any](vals ...T) (T, bool) {
+	for _, v := range vals {
+		if IsNot(v) {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
@@ -229,3 +229,87 @@ func TestAll(t *testing.T) {
 		})
 	}
 }
+
+func TestIsZero(t *testing.T) {
+	type point struct{ X, Y int }
+	n := 1
+	tests := map[string]struct {
+		got, want bool
+	}{
+		"empty string":     {empty.IsZero(""), true},
+		"non-empty string": {empty.IsZero("a"), false},
+		"zero int":         {empty.IsZero(0), true},
+		"non-zero float":   {empty.IsZero(1.5), false},
+		"false":            {empty.IsZero(false), true},
+		"nil pointer":      {empty.IsZero[*int](nil), true},
+		"pointer":          {empty.IsZero(&n), false},
+		"zero struct":      {empty.IsZero(point{}), true},
+		"struct":           {empty.IsZero(point{X: 1}), false},
+		"nil slice":        {empty.IsSlice([]int(nil)), true},
+		"empty slice":      {empty.IsSlice([]string{}), true},
+		"slice":            {empty.IsSlice([]int{1}), false},
+		"nil map":          {empty.IsMap(map[string]int(nil)), true},
+		"empty map":        {empty.IsMap(map[string]int{}), true},
+		"map":              {empty.IsMap(map[string]int{"a": 1}), false},
+		"IsNil nil":        {empty.IsNil[int](nil), true},
+		"IsNil pointer":    {empty.IsNil(&n), false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkIs(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		empty.Is("hello")
+	}
+}
+
+func BenchmarkIsZero(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		empty.IsZero("hello")
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	t.Run("strings", func(t *testing.T) {
+		tests := map[string]struct {
+			vals   []string
+			want   string
+			wantOK bool
+		}{
+			"first":       {[]string{"a", "b"}, "a", true},
+			"skips empty": {[]string{"", "", "c"}, "c", true},
+			"all empty":   {[]string{"", ""}, "", false},
+			"none":        {nil, "", false},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				got, ok := empty.CoalesceOK(tt.vals...)
+				if got != tt.want || ok != tt.wantOK {
+					t.Errorf("CoalesceOK(%q) = %q, %v; want %q, %v", tt.vals, got, ok, tt.want, tt.wantOK)
+				}
+				if got := empty.Coalesce(tt.vals...); got != tt.want {
+					t.Errorf("Coalesce(%q) = %q, want %q", tt.vals, got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("slices", func(t *testing.T) {
+		got := empty.Coalesce(nil, []int{}, []int{1, 2})
+		if len(got) != 2 {
+			t.Errorf("Coalesce() = %v, want [1 2]", got)
+		}
+	})
+
+	t.Run("numbers", func(t *testing.T) {
+		if got := empty.Coalesce(0, 0, 8080); got != 8080 {
+			t.Errorf("Coalesce() = %d, want 8080", got)
+		}
+	})
+}
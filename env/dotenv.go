@@ -0,0 +1,314 @@
+// Package env loads environment variables from .env files.
+//
+//	// Values already present in the environment take precedence.
+//	if err := env.LoadDotenv(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+//	    log.Fatal(err)
+//	}
+//
+// The .env syntax supported:
+//
+//	# comment
+//	PLAIN=value            # inline comment after whitespace
+//	export EXPORTED=value
+//	SINGLE='literal $NOT_EXPANDED'
+//	DOUBLE="escapes \n and ${EXPANDED}"
+//	MULTI="first line
+//	second line"
+//	URL=http://${HOST}:$PORT
+package env
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LoadDotenv reads each file in paths (".env" if none are given) and sets
+// the variables it defines. Variables that are already set, either in the
+// environment or by an earlier file, are left unchanged.
+func LoadDotenv(paths ...string) error {
+	return load(paths, false)
+}
+
+// OverloadDotenv is like LoadDotenv but overwrites variables that are
+// already set, so later files take precedence over earlier ones and over
+// the environment.
+func OverloadDotenv(paths ...string) error {
+	return load(paths, true)
+}
+
+func load(paths []string, overload bool) error {
+	if len(paths) == 0 {
+		paths = []string{".env"}
+	}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		err = parse(path, b, os.Getenv, func(key, value string) error {
+			if _, exists := os.LookupEnv(key); exists && !overload {
+				return nil
+			}
+			return os.Setenv(key, value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseDotenv parses .env content from r without modifying the environment.
+// Variable references are expanded from earlier definitions in r, falling
+// back to the environment.
+func ParseDotenv(r io.Reader) (map[string]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string)
+	lookup := func(key string) string {
+		if v, ok := vars[key]; ok {
+			return v
+		}
+		return os.Getenv(key)
+	}
+	err = parse("<input>", b, lookup, func(key, value string) error {
+		vars[key] = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// parser walks .env content, calling set for each definition in order.
+type parser struct {
+	name   string
+	src    []byte
+	pos    int
+	line   int
+	lookup func(string) string
+}
+
+func parse(name string, src []byte, lookup func(string) string, set func(key, value string) error) error {
+	src = bytes.TrimPrefix(src, []byte("\xef\xbb\xbf")) // UTF-8 BOM
+	p := &parser{name: name, src: src, line: 1, lookup: lookup}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.src) {
+			return nil
+		}
+		line := p.line
+		key, value, err := p.definition()
+		if err != nil {
+			return fmt.Errorf("env: %s:%d: %w", name, line, err)
+		}
+		if err := set(key, value); err != nil {
+			return fmt.Errorf("env: %s:%d: %w", name, line, err)
+		}
+	}
+}
+
+// skipBlank skips whitespace, blank lines and comment lines.
+func (p *parser) skipBlank() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '#':
+			p.skipLine()
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) skipLine() {
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *parser) definition() (key, value string, err error) {
+	key = p.ident()
+	if key == "export" && p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.skipSpaces()
+		key = p.ident()
+	}
+	if key == "" {
+		return "", "", fmt.Errorf("invalid variable name")
+	}
+
+	p.skipSpaces()
+	if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+		return "", "", fmt.Errorf("missing '=' after %s", key)
+	}
+	p.pos++
+	p.skipSpaces()
+
+	if p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\'':
+			value, err = p.singleQuoted()
+		case '"':
+			value, err = p.doubleQuoted()
+		default:
+			value = p.unquoted()
+		}
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	// Only whitespace or a comment may follow the value.
+	p.skipSpaces()
+	if p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' && p.src[p.pos] != '#' {
+		return "", "", fmt.Errorf("unexpected characters after value of %s", key)
+	}
+	p.skipLine()
+	return key, value, nil
+}
+
+func (p *parser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) && isIdentByte(p.src[p.pos], p.pos == start) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func isIdentByte(c byte, first bool) bool {
+	switch {
+	case c == '_', 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+		return true
+	case '0' <= c && c <= '9', c == '.':
+		return !first
+	}
+	return false
+}
+
+func (p *parser) unquoted() string {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		// "#" starts a comment only after whitespace: a=b#c keeps "b#c".
+		if p.src[p.pos] == '#' && p.pos > start && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			break
+		}
+		p.pos++
+	}
+	return p.expand(strings.TrimSpace(string(p.src[start:p.pos])))
+}
+
+func (p *parser) singleQuoted() (string, error) {
+	p.pos++ // opening quote
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != '\'' {
+		if p.src[p.pos] == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return "", fmt.Errorf("unterminated single-quoted value")
+	}
+	value := string(p.src[start:p.pos])
+	p.pos++ // closing quote
+	return value, nil
+}
+
+func (p *parser) doubleQuoted() (string, error) {
+	p.pos++ // opening quote
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return p.expand(b.String()), nil
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.pos++
+			switch e := p.src[p.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '$':
+				b.WriteByte(literalDollar)
+			case '"', '\\':
+				b.WriteByte(e)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			b.WriteByte(c)
+		}
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated double-quoted value")
+}
+
+// literalDollar stands in for an escaped "\$" until expand has run.
+// NUL cannot appear in environment variables, so it never clashes.
+const literalDollar = 0
+
+// expand replaces $VAR and ${VAR} references using p.lookup.
+func (p *parser) expand(s string) string {
+	if !strings.ContainsAny(s, "$\x00") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == literalDollar {
+			b.WriteByte('$')
+			continue
+		}
+		if c != '$' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteString(p.lookup(s[i+2 : i+2+end]))
+			i += 2 + end
+			continue
+		}
+
+		j := i + 1
+		for j < len(s) && isIdentByte(s[j], j == i+1) && s[j] != '.' {
+			j++
+		}
+		if j == i+1 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString(p.lookup(s[i+1 : j]))
+		i = j - 1
+	}
+	return b.String()
+}
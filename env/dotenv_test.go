@@ -0,0 +1,183 @@
+package env_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rin2yh/gouse/env"
+)
+
+func TestParseDotenv(t *testing.T) {
+	t.Setenv("DOTENV_TEST_HOST", "example.com")
+
+	tests := map[string]struct {
+		input string
+		want  map[string]string
+	}{
+		"plain": {
+			input: "A=1\nB = two \n",
+			want:  map[string]string{"A": "1", "B": "two"},
+		},
+		"comments and blanks": {
+			input: "# header\n\nA=1 # trailing\n  # indented\nB=c#d\n",
+			want:  map[string]string{"A": "1", "B": "c#d"},
+		},
+		"export": {
+			input: "export A=1\nexport=2\n",
+			want:  map[string]string{"A": "1", "export": "2"},
+		},
+		"empty value": {
+			input: "A=\nB=''\nC=\"\"",
+			want:  map[string]string{"A": "", "B": "", "C": ""},
+		},
+		"single quoted": {
+			input: "A='literal $HOME \\n # not comment'",
+			want:  map[string]string{"A": "literal $HOME \\n # not comment"},
+		},
+		"double quoted": {
+			input: `A="tab\tnewline\nquote\"backslash\\dollar\$X"`,
+			want:  map[string]string{"A": "tab\tnewline\nquote\"backslash\\dollar$X"},
+		},
+		"multi-line": {
+			input: "A=\"line1\nline2\"\nB='x\ny'\nC=3",
+			want:  map[string]string{"A": "line1\nline2", "B": "x\ny", "C": "3"},
+		},
+		"expansion": {
+			input: "PORT=8080\nURL=http://${DOTENV_TEST_HOST}:$PORT/\nQ=\"$PORT\"\nMISSING=[$DOTENV_TEST_UNSET]",
+			want: map[string]string{
+				"PORT":    "8080",
+				"URL":     "http://example.com:8080/",
+				"Q":       "8080",
+				"MISSING": "[]",
+			},
+		},
+		"literal dollar": {
+			input: "A=$\nB=cost$ 5\nC=${unclosed",
+			want:  map[string]string{"A": "$", "B": "cost$ 5", "C": "${unclosed"},
+		},
+		"crlf": {
+			input: "A=1\r\nB=\"2\"\r\n",
+			want:  map[string]string{"A": "1", "B": "2"},
+		},
+		"bom": {
+			input: "\xef\xbb\xbfA=1",
+			want:  map[string]string{"A": "1"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := env.ParseDotenv(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ParseDotenv() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDotenv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := map[string]struct {
+			input    string
+			wantLine string
+		}{
+			"missing equals":   {"A=1\nB\n", ":2:"},
+			"bad name":         {"1A=x", ":1:"},
+			"unterminated":     {"A=\"abc\nB=1", ":1:"},
+			"unterminated sq":  {"A='abc", ":1:"},
+			"trailing garbage": {"A=\"x\" y", ":1:"},
+			"line after multi": {"A='x\ny'\n-", ":3:"},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := env.ParseDotenv(strings.NewReader(tt.input))
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), tt.wantLine) {
+					t.Fatalf("error %q does not report line %s", err, tt.wantLine)
+				}
+			})
+		}
+	})
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDotenv(t *testing.T) {
+	t.Run("preserve existing", func(t *testing.T) {
+		t.Setenv("DOTENV_TEST_A", "from-env")
+		t.Setenv("DOTENV_TEST_B", "")
+		os.Unsetenv("DOTENV_TEST_B")
+		first := writeFile(t, "first.env", "DOTENV_TEST_A=file\nDOTENV_TEST_B=first\n")
+		second := writeFile(t, "second.env", "DOTENV_TEST_B=second\n")
+
+		if err := env.LoadDotenv(first, second); err != nil {
+			t.Fatal(err)
+		}
+		if got := os.Getenv("DOTENV_TEST_A"); got != "from-env" {
+			t.Errorf("DOTENV_TEST_A = %q, want %q", got, "from-env")
+		}
+		if got := os.Getenv("DOTENV_TEST_B"); got != "first" {
+			t.Errorf("DOTENV_TEST_B = %q, want %q", got, "first")
+		}
+	})
+
+	t.Run("overload", func(t *testing.T) {
+		t.Setenv("DOTENV_TEST_A", "from-env")
+		first := writeFile(t, "first.env", "DOTENV_TEST_A=first\n")
+		second := writeFile(t, "second.env", "DOTENV_TEST_A=second\nDOTENV_TEST_C=${DOTENV_TEST_A}!\n")
+		t.Setenv("DOTENV_TEST_C", "")
+
+		if err := env.OverloadDotenv(first, second); err != nil {
+			t.Fatal(err)
+		}
+		if got := os.Getenv("DOTENV_TEST_A"); got != "second" {
+			t.Errorf("DOTENV_TEST_A = %q, want %q", got, "second")
+		}
+		if got := os.Getenv("DOTENV_TEST_C"); got != "second!" {
+			t.Errorf("DOTENV_TEST_C = %q, want %q", got, "second!")
+		}
+	})
+
+	t.Run("expansion sees effective value", func(t *testing.T) {
+		t.Setenv("DOTENV_TEST_A", "from-env")
+		t.Setenv("DOTENV_TEST_C", "")
+		os.Unsetenv("DOTENV_TEST_C")
+		path := writeFile(t, ".env", "DOTENV_TEST_A=file\nDOTENV_TEST_C=$DOTENV_TEST_A\n")
+
+		if err := env.LoadDotenv(path); err != nil {
+			t.Fatal(err)
+		}
+		if got := os.Getenv("DOTENV_TEST_C"); got != "from-env" {
+			t.Errorf("DOTENV_TEST_C = %q, want %q", got, "from-env")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		err := env.LoadDotenv(filepath.Join(t.TempDir(), "missing.env"))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("expected fs.ErrNotExist, got %v", err)
+		}
+	})
+
+	t.Run("syntax error names file", func(t *testing.T) {
+		path := writeFile(t, "bad.env", "OK=1\nnot valid\n")
+		err := env.LoadDotenv(path)
+		if err == nil || !strings.Contains(err.Error(), path+":2:") {
+			t.Fatalf("expected error naming %s:2, got %v", path, err)
+		}
+	})
+}
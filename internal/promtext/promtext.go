@@ -0,0 +1,75 @@
+// Package promtext writes the Prometheus text exposition format, shared by
+// the metrics handlers that avoid depending on the Prometheus client
+// library.
+package promtext
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ContentType is the Content-Type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Writer writes exposition lines through a buffer, counting the bytes
+// written and remembering the first error.
+type Writer struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+// NewWriter returns a Writer writing to w. Call Flush when done.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Printf writes a formatted line, unless an earlier write failed.
+func (w *Writer) Printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+// Metric writes the HELP and TYPE lines for a metric.
+func (w *Writer) Metric(name, typ, help string) {
+	w.Printf("# HELP %s %s\n# TYPE %s %s\n", name, helpEscaper.Replace(help), name, typ)
+}
+
+// Gauge writes an unlabelled gauge with its HELP and TYPE lines.
+func (w *Writer) Gauge(name, help string, v float64) {
+	w.Metric(name, "gauge", help)
+	w.Printf("%s %g\n", name, v)
+}
+
+// Counter writes an unlabelled counter with its HELP and TYPE lines.
+func (w *Writer) Counter(name, help string, v uint64) {
+	w.Metric(name, "counter", help)
+	w.Printf("%s %d\n", name, v)
+}
+
+// Flush flushes the buffer and returns the bytes written and the first
+// error, as io.WriterTo does.
+func (w *Writer) Flush() (int64, error) {
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	return w.n, w.err
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// Label formats a name="value" label pair. Only backslash, double quote
+// and newline are escaped, as the format requires; other characters,
+// including non-ASCII ones, are written as UTF-8.
+func Label(name, value string) string {
+	return name + `="` + labelEscaper.Replace(value) + `"`
+}
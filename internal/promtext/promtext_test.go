@@ -0,0 +1,61 @@
+package promtext_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rin2yh/gouse/internal/promtext"
+)
+
+func TestLabel(t *testing.T) {
+	tests := map[string]struct {
+		value string
+		want  string
+	}{
+		"plain":     {"/users/{id}", `route="/users/{id}"`},
+		"non-ascii": {"/café", `route="/café"`},
+		"quote":     {`say "hi"`, `route="say \"hi\""`},
+		"backslash": {`a\b`, `route="a\\b"`},
+		"newline":   {"a\nb", `route="a\nb"`},
+		"tab":       {"a\tb", "route=\"a\tb\""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := promtext.Label("route", tt.value); got != tt.want {
+				t.Errorf("Label(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := promtext.NewWriter(&buf)
+	w.Gauge("up", "Whether the\nserver is up.", 1)
+	w.Counter("requests_total", "Requests served.", 3)
+	n, err := w.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP up Whether the\\nserver is up.\n# TYPE up gauge\nup 1\n" +
+		"# HELP requests_total Requests served.\n# TYPE requests_total counter\nrequests_total 3\n"
+	if got := buf.String(); got != want || n != int64(len(want)) {
+		t.Fatalf("wrote %d bytes %q, want %d bytes %q", n, got, len(want), want)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) { return 0, errWrite }
+
+var errWrite = errors.New("write failed")
+
+func TestWriterError(t *testing.T) {
+	w := promtext.NewWriter(failWriter{})
+	w.Printf("%s\n", bytes.Repeat([]byte("x"), 8192))
+	w.Counter("requests_total", "Requests served.", 3)
+	if _, err := w.Flush(); !errors.Is(err, errWrite) {
+		t.Fatalf("Flush() = %v, want %v", err, errWrite)
+	}
+}
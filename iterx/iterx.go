@@ -0,0 +1,212 @@
+//go:build go1.23
+
+// Package iterx provides combinators for iter.Seq and iter.Seq2, plus
+// adapters to and from slices and channels.
+//
+//	evens := iterx.Filter(iterx.FromSlice(nums), func(n int) bool { return n%2 == 0 })
+//	first := iterx.Collect(iterx.Take(evens, 10))
+//
+// Sequences are lazy: nothing runs until the result is ranged over, and
+// stopping early stops the underlying sequence as well.
+//
+// This package requires Go 1.23 or later and is excluded from builds with
+// older toolchains.
+package iterx
+
+import (
+	"context"
+	"iter"
+)
+
+// Map returns a sequence of f applied to each element of seq.
+func Map[T, U any](seq iter.Seq[T], f func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			if !yield(f(v)) {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns a sequence of the elements of seq for which keep returns true.
+func Filter[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if keep(v) && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Take returns a sequence of at most the first n elements of seq.
+func Take[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			if i++; i == n {
+				return
+			}
+		}
+	}
+}
+
+// Chunk returns a sequence of consecutive slices of up to size elements of
+// seq; only the last chunk may be shorter. Each chunk is a new slice.
+// Chunk panics if size is less than 1.
+func Chunk[T any](seq iter.Seq[T], size int) iter.Seq[[]T] {
+	if size < 1 {
+		panic("iterx: chunk size must be at least 1")
+	}
+	return func(yield func([]T) bool) {
+		chunk := make([]T, 0, size)
+		for v := range seq {
+			chunk = append(chunk, v)
+			if len(chunk) == size {
+				if !yield(chunk) {
+					return
+				}
+				chunk = make([]T, 0, size)
+			}
+		}
+		if len(chunk) > 0 {
+			yield(chunk)
+		}
+	}
+}
+
+// Zip returns a sequence of pairs of elements from a and b, stopping when
+// either is exhausted.
+func Zip[A, B any](a iter.Seq[A], b iter.Seq[B]) iter.Seq2[A, B] {
+	return func(yield func(A, B) bool) {
+		nextB, stop := iter.Pull(b)
+		defer stop()
+		for va := range a {
+			vb, ok := nextB()
+			if !ok || !yield(va, vb) {
+				return
+			}
+		}
+	}
+}
+
+// Map2 returns a sequence of f applied to each pair of seq.
+func Map2[K, V, K2, V2 any](seq iter.Seq2[K, V], f func(K, V) (K2, V2)) iter.Seq2[K2, V2] {
+	return func(yield func(K2, V2) bool) {
+		for k, v := range seq {
+			if !yield(f(k, v)) {
+				return
+			}
+		}
+	}
+}
+
+// Filter2 returns a sequence of the pairs of seq for which keep returns true.
+func Filter2[K, V any](seq iter.Seq2[K, V], keep func(K, V) bool) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range seq {
+			if keep(k, v) && !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Take2 returns a sequence of at most the first n pairs of seq.
+func Take2[K, V any](seq iter.Seq2[K, V], n int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for k, v := range seq {
+			if !yield(k, v) {
+				return
+			}
+			if i++; i == n {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns a sequence of the first elements of the pairs of seq.
+func Keys[K, V any](seq iter.Seq2[K, V]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range seq {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns a sequence of the second elements of the pairs of seq.
+func Values[K, V any](seq iter.Seq2[K, V]) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range seq {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// FromSlice returns a sequence of the elements of s.
+func FromSlice[T any](s []T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, v := range s {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Collect returns the elements of seq as a slice. It returns nil for an
+// empty sequence.
+func Collect[T any](seq iter.Seq[T]) []T {
+	var s []T
+	for v := range seq {
+		s = append(s, v)
+	}
+	return s
+}
+
+// FromChan returns a sequence of the values received from ch until it is
+// closed.
+func FromChan[T any](ch <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// ToChan sends the elements of seq on the returned channel from a new
+// goroutine, closing it when seq is exhausted or ctx is done.
+// Cancel ctx if the channel is abandoned before it is closed, otherwise the
+// goroutine leaks.
+func ToChan[T any](ctx context.Context, seq iter.Seq[T]) <-chan T {
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for v := range seq {
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,154 @@
+//go:build go1.23
+
+package iterx_test
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/rin2yh/gouse/iterx"
+	"github.com/rin2yh/gouse/unisort"
+)
+
+// counting returns an infinite sequence 0, 1, 2, ... and a pointer to the
+// number of elements produced so far.
+func counting() (func(func(int) bool), *int) {
+	n := 0
+	return func(yield func(int) bool) {
+		for {
+			if !yield(n) {
+				return
+			}
+			n++
+		}
+	}, &n
+}
+
+func TestMap(t *testing.T) {
+	got := iterx.Collect(iterx.Map(iterx.FromSlice([]int{1, 2, 3}), strconv.Itoa))
+	if want := []string{"1", "2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Map() = %v, want %v", got, want)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	got := iterx.Collect(iterx.Filter(iterx.FromSlice([]int{1, 2, 3, 4}), func(n int) bool { return n%2 == 0 }))
+	if want := []int{2, 4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Filter() = %v, want %v", got, want)
+	}
+}
+
+func TestTake(t *testing.T) {
+	tests := map[string]struct {
+		n    int
+		want []int
+	}{
+		"zero":     {0, nil},
+		"negative": {-1, nil},
+		"some":     {3, []int{0, 1, 2}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			seq, produced := counting()
+			if got := iterx.Collect(iterx.Take(seq, tt.n)); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Take(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+			// Take must not pull an element beyond the n it returns.
+			if want := max(tt.n-1, 0); *produced != want {
+				t.Fatalf("produced %d elements, want %d", *produced, want)
+			}
+		})
+	}
+
+	t.Run("shorter sequence", func(t *testing.T) {
+		got := iterx.Collect(iterx.Take(iterx.FromSlice([]int{1}), 5))
+		if want := []int{1}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Take() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestChunk(t *testing.T) {
+	tests := map[string]struct {
+		in   []int
+		size int
+		want [][]int
+	}{
+		"even":   {[]int{1, 2, 3, 4}, 2, [][]int{{1, 2}, {3, 4}}},
+		"uneven": {[]int{1, 2, 3}, 2, [][]int{{1, 2}, {3}}},
+		"empty":  {nil, 2, nil},
+		"single": {[]int{1, 2}, 1, [][]int{{1}, {2}}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := iterx.Collect(iterx.Chunk(iterx.FromSlice(tt.in), tt.size)); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Chunk() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid size", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic for size 0")
+			}
+		}()
+		iterx.Chunk(iterx.FromSlice([]int{1}), 0)
+	})
+}
+
+func TestZip(t *testing.T) {
+	names := iterx.FromSlice([]string{"a", "b", "c"})
+	nums, _ := counting()
+	got := maps.Collect(iterx.Zip(names, nums))
+	if want := map[string]int{"a": 0, "b": 1, "c": 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Zip() = %v, want %v", got, want)
+	}
+}
+
+func TestSeq2(t *testing.T) {
+	pairs := iterx.Zip(iterx.FromSlice([]string{"a", "b", "c"}), iterx.FromSlice([]int{1, 2, 3}))
+
+	odd := iterx.Filter2(pairs, func(_ string, n int) bool { return n%2 == 1 })
+	if got, want := iterx.Collect(iterx.Keys(odd)), []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Keys(Filter2()) = %v, want %v", got, want)
+	}
+
+	doubled := iterx.Map2(pairs, func(k string, n int) (string, int) { return k + k, n * 2 })
+	if got, want := iterx.Collect(iterx.Values(iterx.Take2(doubled, 2))), []int{2, 4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Values(Take2(Map2())) = %v, want %v", got, want)
+	}
+}
+
+func TestChan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	seq, _ := counting()
+	ch := iterx.ToChan(ctx, iterx.Take(seq, 3))
+	if got, want := iterx.Collect(iterx.FromChan(ch)), []int{0, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("FromChan(ToChan()) = %v, want %v", got, want)
+	}
+
+	t.Run("cancel stops producer", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		seq, _ := counting()
+		ch := iterx.ToChan(ctx, seq)
+		<-ch
+		cancel()
+		for range ch { // drains until the producer closes ch
+		}
+	})
+}
+
+func TestInterop(t *testing.T) {
+	// iterx sequences interoperate with slices and unisort.
+	seq := iterx.Map(slices.Values([]int{3, 1, 3, 2}), func(n int) int { return n * 10 })
+	if got, want := unisort.UniqueSortNaturalInts(iterx.Collect(seq)), []int{10, 20, 30}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package graceful
+
+import (
+	"context"
+	"net"
+)
+
+type drainingKey struct{}
+
+// ShuttingDown returns a channel that is closed once shutdown begins, for the
+// context of a request served by an *http.Server under Run. Handlers doing
+// long work can select on it to wrap up early:
+//
+//	select {
+//	case res := <-work:
+//	    // ...
+//	case <-graceful.ShuttingDown(r.Context()):
+//	    http.Error(w, "shutting down", http.StatusServiceUnavailable)
+//	}
+//
+// For other contexts it returns nil, which blocks forever in a select.
+//
+// The request context itself is cancelled when the shutdown deadline
+// expires.
+func ShuttingDown(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(drainingKey{}).(chan struct{})
+	return ch
+}
+
+// requestBase is the base of the request contexts of the *http.Servers
+// under Run.
+type requestBase struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	draining chan struct{}
+}
+
+// newRequestBase returns a requestBase carrying parent's values but not
+// its cancellation, which triggers shutdown rather than aborting requests.
+func newRequestBase(parent context.Context) *requestBase {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	return &requestBase{ctx: ctx, cancel: cancel, draining: make(chan struct{})}
+}
+
+// install sets the BaseContext of every *http.Server among srvs, wrapping
+// any BaseContext already set.
+func (b *requestBase) install(srvs []Server) {
+	for _, srv := range srvs {
+		hs, ok := httpServer(srv)
+		if !ok {
+			continue
+		}
+		next := hs.BaseContext
+		hs.BaseContext = func(ln net.Listener) context.Context {
+			ctx := b.ctx
+			if next != nil {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(next(ln))
+				context.AfterFunc(b.ctx, cancel)
+			}
+			return context.WithValue(ctx, drainingKey{}, b.draining)
+		}
+	}
+}
+
+// drain closes the channel returned by ShuttingDown.
+func (b *requestBase) drain() {
+	close(b.draining)
+}
+
+// expireWith cancels the request contexts once ctx is done.
+func (b *requestBase) expireWith(ctx context.Context) {
+	context.AfterFunc(ctx, b.cancel)
+}
@@ -27,10 +27,10 @@ func newBenchmarkServer() *controllableServer {
 	}
 }
 
-func noopCleanups(n int) []func() {
-	fns := make([]func(), n)
+func noopCleanups(n int) []func(context.Context) error {
+	fns := make([]func(context.Context) error, n)
 	for i := range fns {
-		fns[i] = func() {}
+		fns[i] = func(context.Context) error { return nil }
 	}
 	return fns
 }
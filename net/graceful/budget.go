@@ -0,0 +1,43 @@
+package graceful
+
+import (
+	"context"
+	"time"
+)
+
+// Budget splits one overall shutdown deadline between draining the servers
+// and the cleanups, e.g. to fit a Kubernetes terminationGracePeriodSeconds.
+type Budget struct {
+	// Total is the time from the shutdown trigger until the cleanups must
+	// be done, covering PreShutdown, ShutdownDelay, draining and the
+	// cleanups. Budget is disabled if zero.
+	Total time.Duration
+
+	// MinCleanup is the time reserved for the cleanups: draining must end
+	// MinCleanup before Total runs out, and the cleanups get whatever is
+	// left of Total after draining, at least MinCleanup.
+	MinCleanup time.Duration
+}
+
+// drainTimeout returns how long the servers may drain: ShutdownTimeout, or
+// with a Budget, what is left of it before the cleanup reserve, given that
+// elapsed has passed since the shutdown trigger.
+func (c *Config) drainTimeout(elapsed time.Duration) time.Duration {
+	if c.Budget.Total > 0 {
+		return max(c.Budget.Total-c.Budget.MinCleanup-elapsed, 0)
+	}
+	if c.ShutdownTimeout > 0 {
+		return c.ShutdownTimeout
+	}
+	return defaultShutdownTimeout
+}
+
+// cleanupContext returns the context the cleanups run in. Without a Budget
+// it is drainCtx, so cleanups share the shutdown deadline; with one, it
+// expires when Total runs out.
+func (c *Config) cleanupContext(base, drainCtx context.Context, elapsed time.Duration) (context.Context, context.CancelFunc) {
+	if c.Budget.Total <= 0 {
+		return drainCtx, func() {}
+	}
+	return c.withTimeout(base, max(c.Budget.Total-elapsed, c.Budget.MinCleanup))
+}
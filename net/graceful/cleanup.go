@@ -0,0 +1,148 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// CleanupOrder is the order in which sequential cleanups run.
+type CleanupOrder int
+
+const (
+	// FIFO runs cleanups in registration order.
+	FIFO CleanupOrder = iota
+	// LIFO runs cleanups in reverse registration order, so resources
+	// registered in dependency order are released dependents first.
+	LIFO
+)
+
+// runCleanups runs cfg.Cleanups, sequentially or in parallel, then the
+// cleanups registered with AddCleanup. It returns their errors indexed like
+// cfg.Cleanups followed by the named cleanups in registration order.
+func runCleanups(ctx context.Context, cfg *Config) []error {
+	errs := runPlainCleanups(ctx, cfg)
+	if len(cfg.namedCleanups) > 0 {
+		errs = append(errs, runNamedCleanups(ctx, cfg)...)
+	}
+	return errs
+}
+
+// runPlainCleanups runs cfg.Cleanups and returns their errors indexed like
+// cfg.Cleanups.
+func runPlainCleanups(ctx context.Context, cfg *Config) []error {
+	fns := traced(cfg, cfg.Cleanups)
+	if cfg.CleanupTimeout > 0 {
+		for i, fn := range fns {
+			fns[i] = withTimeout(cfg, fn, cfg.CleanupTimeout)
+		}
+	}
+	if cfg.ParallelCleanups {
+		return callParallel(ctx, fns)
+	}
+	if cfg.CleanupOrder == LIFO {
+		errs := callAll(ctx, reversed(fns))
+		return reversed(errs)
+	}
+	return callAll(ctx, fns)
+}
+
+// reversed returns a reversed copy of s.
+func reversed[T any](s []T) []T {
+	r := make([]T, len(s))
+	for i, v := range s {
+		r[len(s)-1-i] = v
+	}
+	return r
+}
+
+// abandonGrace is how long a cleanup may take to return after its context
+// expires before it is abandoned.
+const abandonGrace = 50 * time.Millisecond
+
+// ErrCleanupAbandoned is returned (joined with the context error) for a
+// cleanup that was still running when its CleanupTimeout expired.
+var ErrCleanupAbandoned = errors.New("graceful: cleanup abandoned")
+
+// withTimeout returns fn with its context bounded by timeout on cfg's clock.
+// fn runs in its own goroutine; if it has not returned shortly after its
+// context expires, it is left running and ErrCleanupAbandoned is returned.
+func withTimeout(cfg *Config, fn func(context.Context) error, timeout time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ctx, cancel := cfg.withTimeout(ctx, timeout)
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() { done <- call(ctx, fn) }()
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+		}
+
+		grace := cfg.clock().NewTimer(abandonGrace)
+		defer grace.Stop()
+		select {
+		case err := <-done:
+			return err
+		case <-grace.C():
+			return fmt.Errorf("%w: %w", ErrCleanupAbandoned, ctx.Err())
+		}
+	}
+}
+
+// callAll calls each fn in order with ctx and returns their errors, indexed
+// like fns (nil for those that succeeded). A panicking fn does not stop the
+// others; its panic is returned as a *PanicError.
+func callAll(ctx context.Context, fns []func(context.Context) error) []error {
+	errs := make([]error, len(fns))
+	for i, fn := range fns {
+		errs[i] = call(ctx, fn)
+	}
+	return errs
+}
+
+// callParallel is like callAll but calls every fn concurrently.
+func callParallel(ctx context.Context, fns []func(context.Context) error) []error {
+	errs := make([]error, len(fns))
+	var wg sync.WaitGroup
+	for i, fn := range fns {
+		wg.Add(1)
+		go func(i int, fn func(context.Context) error) {
+			defer wg.Done()
+			errs[i] = call(ctx, fn)
+		}(i, fn)
+	}
+	wg.Wait()
+	return errs
+}
+
+// call calls fn, converting a panic into a *PanicError.
+func call(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx)
+}
+
+// PanicError is returned (joined with any other errors) when a PreShutdown
+// or cleanup function, or a Server's ListenAndServe, panics.
+type PanicError struct {
+	Value any    // the value passed to panic
+	Stack []byte // the goroutine stack at the time of the panic
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("graceful: panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
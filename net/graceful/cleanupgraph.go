@@ -0,0 +1,114 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrCleanupCycle is returned by AddCleanup when the dependencies would
+// form a cycle.
+var ErrCleanupCycle = errors.New("graceful: cleanup dependency cycle")
+
+// namedCleanup is a cleanup registered with AddCleanup.
+type namedCleanup struct {
+	name      string
+	fn        func(context.Context) error
+	dependsOn []string
+}
+
+// AddCleanup registers a named cleanup that runs only after the cleanups
+// named in dependsOn have finished, e.g. closing a connection after
+// flushing the queue that writes to it:
+//
+//	cfg.AddCleanup("flush", queue.Flush)
+//	cfg.AddCleanup("close", func(ctx context.Context) error { return conn.Close() }, "flush")
+//
+// Named cleanups run after Config.Cleanups, each as soon as its
+// dependencies are done, so independent ones run in parallel. A cleanup
+// still runs if a dependency failed. Dependencies may name cleanups that
+// are registered later; AddCleanup returns an error wrapping
+// ErrCleanupCycle, and registers nothing, if the new cleanup would close a
+// cycle. It also rejects duplicate names. Run reports dependencies on
+// names that were never registered.
+func (c *Config) AddCleanup(name string, fn func(context.Context) error, dependsOn ...string) error {
+	for _, nc := range c.namedCleanups {
+		if nc.name == name {
+			return fmt.Errorf("graceful: cleanup %q already registered", name)
+		}
+	}
+	deps := make(map[string][]string, len(c.namedCleanups)+1)
+	for _, nc := range c.namedCleanups {
+		deps[nc.name] = nc.dependsOn
+	}
+	deps[name] = dependsOn
+
+	// The new cleanup closes a cycle iff it can reach itself.
+	seen := make(map[string]bool)
+	var reaches func(from string) bool
+	reaches = func(from string) bool {
+		for _, d := range deps[from] {
+			if d == name {
+				return true
+			}
+			if !seen[d] {
+				seen[d] = true
+				if reaches(d) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if reaches(name) {
+		return fmt.Errorf("%w: %q", ErrCleanupCycle, name)
+	}
+
+	c.namedCleanups = append(c.namedCleanups, namedCleanup{name: name, fn: fn, dependsOn: dependsOn})
+	return nil
+}
+
+// runNamedCleanups runs cfg's named cleanups in dependency order and
+// returns their errors in registration order.
+func runNamedCleanups(ctx context.Context, cfg *Config) []error {
+	ncs := cfg.namedCleanups
+	done := make(map[string]chan struct{}, len(ncs))
+	for _, nc := range ncs {
+		done[nc.name] = make(chan struct{})
+	}
+
+	errs := make([]error, len(ncs))
+	var wg sync.WaitGroup
+	for i, nc := range ncs {
+		var missing []string
+		for _, d := range nc.dependsOn {
+			if _, ok := done[d]; !ok {
+				missing = append(missing, d)
+			}
+		}
+		if len(missing) > 0 {
+			errs[i] = fmt.Errorf("graceful: cleanup %q depends on unknown cleanups %q", nc.name, missing)
+			close(done[nc.name])
+			continue
+		}
+
+		fn := tracedFn(cfg, "graceful.cleanup."+nc.name, nc.fn)
+		if cfg.CleanupTimeout > 0 {
+			fn = withTimeout(cfg, fn, cfg.CleanupTimeout)
+		}
+		wg.Add(1)
+		go func(i int, nc namedCleanup, fn func(context.Context) error) {
+			defer wg.Done()
+			defer close(done[nc.name])
+			for _, d := range nc.dependsOn {
+				<-done[d]
+			}
+			if err := call(ctx, fn); err != nil {
+				errs[i] = fmt.Errorf("graceful: cleanup %q: %w", nc.name, err)
+			}
+		}(i, nc, fn)
+	}
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,80 @@
+package graceful_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/graceful"
+)
+
+func TestAddCleanupCycle(t *testing.T) {
+	var cfg graceful.Config
+	noop := func(context.Context) error { return nil }
+	if err := cfg.AddCleanup("a", noop, "b"); err != nil {
+		t.Fatalf("AddCleanup(a) error: %v", err)
+	}
+	if err := cfg.AddCleanup("b", noop, "c"); err != nil {
+		t.Fatalf("AddCleanup(b) error: %v", err)
+	}
+	if err := cfg.AddCleanup("c", noop, "a"); !errors.Is(err, graceful.ErrCleanupCycle) {
+		t.Fatalf("AddCleanup(c) = %v, want %v", err, graceful.ErrCleanupCycle)
+	}
+	if err := cfg.AddCleanup("self", noop, "self"); !errors.Is(err, graceful.ErrCleanupCycle) {
+		t.Fatalf("AddCleanup(self) = %v, want %v", err, graceful.ErrCleanupCycle)
+	}
+	if err := cfg.AddCleanup("a", noop); err == nil {
+		t.Fatal("expected an error for a duplicate name")
+	}
+}
+
+func TestRunCleanupGraph(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	cfg := &graceful.Config{}
+	// Registered before its dependency on purpose.
+	mustAdd(t, cfg, "close conn", record("close conn"), "flush queue", "flush cache")
+	mustAdd(t, cfg, "flush queue", record("flush queue"))
+	mustAdd(t, cfg, "flush cache", record("flush cache"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := graceful.Run(ctx, newBenchmarkServer(), cfg); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if len(order) != 3 || order[2] != "close conn" {
+		t.Fatalf("order = %v, want close conn last", order)
+	}
+}
+
+func TestRunCleanupGraphUnknownDependency(t *testing.T) {
+	cfg := &graceful.Config{}
+	mustAdd(t, cfg, "close", func(context.Context) error { return nil }, "missing")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := graceful.Run(ctx, newBenchmarkServer(), cfg)
+	if err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Fatalf("expected an unknown dependency error, got: %v", err)
+	}
+}
+
+func mustAdd(t *testing.T, cfg *graceful.Config, name string, fn func(context.Context) error, dependsOn ...string) {
+	t.Helper()
+	if err := cfg.AddCleanup(name, fn, dependsOn...); err != nil {
+		t.Fatalf("AddCleanup(%q) error: %v", name, err)
+	}
+}
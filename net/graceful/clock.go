@@ -0,0 +1,124 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Clock is the source of time for Run's shutdown deadlines, ShutdownDelay
+// and the durations in ShutdownStats. Tests can pass a fake (see
+// gracefultest.FakeClock) to advance time without sleeping.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the subset of *time.Timer used by Run.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                 { return time.Now() }
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+func (t realTimer) Stop() bool          { return t.t.Stop() }
+
+// clock returns the Clock used by Run.
+func (c *Config) clock() Clock {
+	if c.Clock != nil {
+		return c.Clock
+	}
+	return realClock{}
+}
+
+// since returns the time elapsed since t on the Config's clock.
+func (c *Config) since(t time.Time) time.Duration {
+	return c.clock().Now().Sub(t)
+}
+
+// sleep pauses for d or until ctx is done.
+func (c *Config) sleep(ctx context.Context, d time.Duration) {
+	timer := c.clock().NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+	case <-ctx.Done():
+	}
+}
+
+// withTimeout is context.WithTimeout on the Config's clock. With a custom
+// Clock, the returned context reports context.DeadlineExceeded once the
+// clock's timer fires.
+func (c *Config) withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if c.Clock == nil {
+		return context.WithTimeout(ctx, d)
+	}
+	at := c.Clock.Now().Add(d)
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := c.Clock.NewTimer(d)
+	go func() {
+		select {
+		case <-timer.C():
+			cancel(context.DeadlineExceeded)
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}()
+	vctx := context.WithValue(ctx, deadlineKey{}, clockDeadline{at: at, clock: c.Clock})
+	return clockCtx{vctx}, func() { cancel(context.Canceled) }
+}
+
+// clockCtx reports context.DeadlineExceeded when it was cancelled by its
+// clock's timer, like a context created by context.WithTimeout.
+type clockCtx struct{ context.Context }
+
+func (c clockCtx) Err() error {
+	err := c.Context.Err()
+	if err != nil && errors.Is(context.Cause(c.Context), context.DeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+type deadlineKey struct{}
+
+// clockDeadline is a deadline set by Config.withTimeout on a custom Clock,
+// which context.Context.Deadline cannot report.
+type clockDeadline struct {
+	at    time.Time
+	clock Clock
+}
+
+// Deadline returns the time by which the work done with ctx must finish,
+// for the contexts Run passes to PreShutdown, Deregister and the cleanups.
+// Unlike ctx.Deadline, it also reports deadlines measured on Config.Clock.
+// ok is false if ctx has no deadline.
+func Deadline(ctx context.Context) (deadline time.Time, ok bool) {
+	deadline, ok = ctx.Deadline()
+	if cd, found := ctx.Value(deadlineKey{}).(clockDeadline); found && (!ok || cd.at.Before(deadline)) {
+		return cd.at, true
+	}
+	return deadline, ok
+}
+
+// Remaining returns the time left until Deadline(ctx), so a cleanup can
+// size its work to it (e.g. flush only as many events as fit). It is
+// negative once the deadline has passed; ok is false if ctx has no deadline.
+func Remaining(ctx context.Context) (d time.Duration, ok bool) {
+	deadline, ok := Deadline(ctx)
+	if !ok {
+		return 0, false
+	}
+	var clock Clock = realClock{}
+	if cd, found := ctx.Value(deadlineKey{}).(clockDeadline); found {
+		clock = cd.clock
+	}
+	return deadline.Sub(clock.Now()), true
+}
@@ -0,0 +1,40 @@
+package graceful
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultDeregisterTimeout = 10 * time.Second
+	deregisterBackoff        = 100 * time.Millisecond
+	maxDeregisterBackoff     = 2 * time.Second
+)
+
+// deregister calls cfg.Deregister until it succeeds, backing off from 100ms
+// up to 2s between attempts, or until cfg.DeregisterTimeout elapses. ctx
+// must not be cancelled by the shutdown trigger itself.
+func deregister(ctx context.Context, cfg *Config) error {
+	timeout := defaultDeregisterTimeout
+	if cfg.DeregisterTimeout > 0 {
+		timeout = cfg.DeregisterTimeout
+	}
+	ctx, cancel := cfg.withTimeout(ctx, timeout)
+	defer cancel()
+
+	d := deregisterBackoff
+	for attempt := 1; ; attempt++ {
+		err := call(ctx, cfg.Deregister)
+		if err == nil {
+			return nil
+		}
+		cfg.log(slog.LevelWarn, "deregister failed", slog.Int("attempt", attempt), slog.Any("error", err))
+		cfg.sleep(ctx, d)
+		if ctx.Err() != nil {
+			return fmt.Errorf("graceful: deregister gave up after %d attempts: %w", attempt, err)
+		}
+		d = min(2*d, maxDeregisterBackoff)
+	}
+}
@@ -0,0 +1,161 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultDrainProgressInterval = time.Second
+	connPollInterval             = 10 * time.Millisecond
+)
+
+// httpServer returns the *http.Server behind srv, if there is one.
+func httpServer(srv Server) (*http.Server, bool) {
+	switch s := srv.(type) {
+	case *http.Server:
+		return s, true
+	case *listenerServer:
+		return s.srv, true
+	}
+	return nil, false
+}
+
+// registerOnShutdown registers fns with every server among srvs that has a
+// RegisterOnShutdown method, or is backed by an *http.Server.
+func registerOnShutdown(srvs []Server, fns []func()) {
+	if len(fns) == 0 {
+		return
+	}
+	for _, srv := range srvs {
+		r, ok := srv.(interface{ RegisterOnShutdown(func()) })
+		if !ok {
+			if r, ok = httpServer(srv); !ok {
+				continue
+			}
+		}
+		for _, fn := range fns {
+			r.RegisterOnShutdown(fn)
+		}
+	}
+}
+
+// connCounter counts the open connections of the servers it tracks.
+type connCounter struct {
+	active atomic.Int64
+}
+
+// count returns the open connections tracked by c plus those reported by
+// the servers among srvs with an ActiveConns method.
+func (c *connCounter) count(srvs []Server) int {
+	n := int(c.active.Load())
+	for _, srv := range srvs {
+		if a, ok := srv.(interface{ ActiveConns() int }); ok {
+			n += a.ActiveConns()
+		}
+	}
+	return n
+}
+
+// trackConns counts the connections of every *http.Server among srvs by
+// chaining onto its ConnState hook. It must be called before the servers
+// start.
+func trackConns(srvs []Server) *connCounter {
+	c := &connCounter{}
+	for _, srv := range srvs {
+		hs, ok := httpServer(srv)
+		if !ok {
+			continue
+		}
+		next := hs.ConnState
+		hs.ConnState = func(conn net.Conn, state http.ConnState) {
+			switch state {
+			case http.StateNew:
+				c.active.Add(1)
+			case http.StateHijacked, http.StateClosed:
+				c.active.Add(-1)
+			}
+			if next != nil {
+				next(conn, state)
+			}
+		}
+	}
+	return c
+}
+
+// drain shuts srvs down like shutdownAll, reporting progress to
+// cfg.DrainProgress meanwhile. If the deadline of ctx expires first, the
+// errors are wrapped in a *DrainError describing what was left, captured
+// before any connection is force-closed.
+func drain(ctx context.Context, cfg *Config, srvs []Server, conns *connCounter, inflight *requestTracker) []error {
+	done := make(chan struct{})
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		if cfg.DrainProgress != nil {
+			reportDrain(cfg, conns, done)
+		}
+	}()
+	errs := shutdownAll(ctx, srvs)
+	close(done)
+	<-reported
+	if !errors.Is(errors.Join(errs...), context.DeadlineExceeded) {
+		return errs
+	}
+	derr := &DrainError{ActiveConns: conns.count(srvs)}
+	derr.Path, derr.Elapsed = inflight.longest()
+	if cfg.ForceCloseAfterTimeout {
+		awaitConns(context.WithoutCancel(ctx), cfg, srvs, conns)
+		closeTimedOut(srvs, errs)
+	}
+	derr.Err = errors.Join(errs...)
+	return []error{derr}
+}
+
+// awaitConns waits up to cfg.ForceCloseGrace for the connections of srvs
+// to close on their own.
+func awaitConns(ctx context.Context, cfg *Config, srvs []Server, conns *connCounter) {
+	if cfg.ForceCloseGrace <= 0 {
+		return
+	}
+	begin := cfg.clock().Now()
+	for conns.count(srvs) > 0 && cfg.since(begin) < cfg.ForceCloseGrace {
+		cfg.sleep(ctx, min(connPollInterval, cfg.ForceCloseGrace-cfg.since(begin)))
+	}
+}
+
+// closeTimedOut closes every server among srvs whose Shutdown error in errs
+// is context.DeadlineExceeded, joining the Close error into errs.
+func closeTimedOut(srvs []Server, errs []error) {
+	for i, srv := range srvs {
+		if !errors.Is(errs[i], context.DeadlineExceeded) {
+			continue
+		}
+		if c, ok := srv.(closer); ok {
+			errs[i] = errors.Join(errs[i], c.Close())
+		}
+	}
+}
+
+// reportDrain calls cfg.DrainProgress with the active connection count
+// immediately and then every cfg.DrainProgressInterval until done is closed.
+func reportDrain(cfg *Config, c *connCounter, done <-chan struct{}) {
+	interval := defaultDrainProgressInterval
+	if cfg.DrainProgressInterval > 0 {
+		interval = cfg.DrainProgressInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cfg.DrainProgress(int(c.active.Load()))
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
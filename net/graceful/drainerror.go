@@ -0,0 +1,107 @@
+package graceful
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DrainError is joined into Run's result when the servers have not drained
+// by the shutdown deadline. It wraps the Shutdown errors, so
+// errors.Is(err, context.DeadlineExceeded) still holds.
+type DrainError struct {
+	// ActiveConns is the number of connections still open at the deadline,
+	// counted for *http.Server-backed servers and servers with an
+	// ActiveConns() int method.
+	ActiveConns int
+
+	// Path is the URL path of the longest-running request still in flight
+	// and Elapsed how long it had been running. Path is empty unless
+	// Config.TrackRequests is set.
+	Path    string
+	Elapsed time.Duration
+
+	// Err joins the Shutdown errors.
+	Err error
+}
+
+func (e *DrainError) Error() string {
+	msg := fmt.Sprintf("graceful: drain timed out with %d active connections", e.ActiveConns)
+	if e.Path != "" {
+		msg += fmt.Sprintf(", longest request %s running for %v", e.Path, e.Elapsed)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *DrainError) Unwrap() error { return e.Err }
+
+// inflightRequest is a request being served under Config.TrackRequests.
+type inflightRequest struct {
+	path  string
+	start time.Time
+}
+
+// requestTracker records the in-flight requests of the servers it tracks.
+type requestTracker struct {
+	clock    Clock
+	mu       sync.Mutex
+	next     uint64
+	inflight map[uint64]inflightRequest
+}
+
+// trackRequests wraps the Handler of every *http.Server among srvs to
+// record its in-flight requests. It must be called before the servers
+// start.
+func trackRequests(srvs []Server, clock Clock) *requestTracker {
+	t := &requestTracker{clock: clock, inflight: make(map[uint64]inflightRequest)}
+	for _, srv := range srvs {
+		hs, ok := httpServer(srv)
+		if !ok {
+			continue
+		}
+		next := hs.Handler
+		if next == nil {
+			next = http.DefaultServeMux
+		}
+		hs.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer t.done(t.begin(r.URL.Path))
+			next.ServeHTTP(w, r)
+		})
+	}
+	return t
+}
+
+func (t *requestTracker) begin(path string) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.next++
+	t.inflight[t.next] = inflightRequest{path: path, start: t.clock.Now()}
+	return t.next
+}
+
+func (t *requestTracker) done(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.inflight, id)
+}
+
+// longest returns the path and running time of the oldest in-flight
+// request. It returns an empty path if t is nil or nothing is in flight.
+func (t *requestTracker) longest() (string, time.Duration) {
+	if t == nil {
+		return "", 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var oldest inflightRequest
+	for _, r := range t.inflight {
+		if oldest.path == "" || r.start.Before(oldest.start) {
+			oldest = r
+		}
+	}
+	if oldest.path == "" {
+		return "", 0
+	}
+	return oldest.path, t.clock.Now().Sub(oldest.start)
+}
@@ -0,0 +1,81 @@
+package graceful
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rin2yh/gouse/parsex"
+)
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvShutdownTimeout        = "GRACEFUL_SHUTDOWN_TIMEOUT"
+	EnvShutdownDelay          = "GRACEFUL_SHUTDOWN_DELAY"
+	EnvPreShutdownTimeout     = "GRACEFUL_PRE_SHUTDOWN_TIMEOUT"
+	EnvCleanupTimeout         = "GRACEFUL_CLEANUP_TIMEOUT"
+	EnvStartupTimeout         = "GRACEFUL_STARTUP_TIMEOUT"
+	EnvForceCloseAfterTimeout = "GRACEFUL_FORCE_CLOSE_AFTER_TIMEOUT"
+	EnvForceCloseGrace        = "GRACEFUL_FORCE_CLOSE_GRACE"
+	EnvForceOnSecondSignal    = "GRACEFUL_FORCE_ON_SECOND_SIGNAL"
+	EnvParallelCleanups       = "GRACEFUL_PARALLEL_CLEANUPS"
+)
+
+// ConfigFromEnv returns a Config whose timing and shutdown behaviour is
+// read from the GRACEFUL_* environment variables, so containers can tune
+// draining without recompiling. Unset variables leave the field zero, i.e.
+// at its default. Durations accept parsex.ParseDuration syntax ("30s",
+// "1m30s", "1d"); booleans accept parsex.ParseBool syntax ("true", "yes",
+// "on", ...). Functions such as Cleanups can be set on the result.
+//
+// Invalid values are reported together in the returned error, along with a
+// Config holding the valid ones.
+func ConfigFromEnv() (*Config, error) {
+	cfg := &Config{}
+	var errs []error
+	durations := []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{EnvShutdownTimeout, &cfg.ShutdownTimeout},
+		{EnvShutdownDelay, &cfg.ShutdownDelay},
+		{EnvPreShutdownTimeout, &cfg.PreShutdownTimeout},
+		{EnvCleanupTimeout, &cfg.CleanupTimeout},
+		{EnvStartupTimeout, &cfg.StartupTimeout},
+		{EnvForceCloseGrace, &cfg.ForceCloseGrace},
+	}
+	for _, d := range durations {
+		s, ok := os.LookupEnv(d.name)
+		if !ok || s == "" {
+			continue
+		}
+		v, err := parsex.ParseDuration(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("graceful: %s: %w", d.name, err))
+			continue
+		}
+		*d.dst = v
+	}
+	bools := []struct {
+		name string
+		dst  *bool
+	}{
+		{EnvForceCloseAfterTimeout, &cfg.ForceCloseAfterTimeout},
+		{EnvForceOnSecondSignal, &cfg.ForceOnSecondSignal},
+		{EnvParallelCleanups, &cfg.ParallelCleanups},
+	}
+	for _, b := range bools {
+		s, ok := os.LookupEnv(b.name)
+		if !ok || s == "" {
+			continue
+		}
+		v, err := parsex.ParseBool(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("graceful: %s: %w", b.name, err))
+			continue
+		}
+		*b.dst = v
+	}
+	return cfg, errors.Join(errs...)
+}
@@ -0,0 +1,48 @@
+package graceful_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/graceful"
+	"github.com/rin2yh/gouse/parsex"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(graceful.EnvShutdownTimeout, "30s")
+	t.Setenv(graceful.EnvShutdownDelay, "5s")
+	t.Setenv(graceful.EnvForceCloseAfterTimeout, "yes")
+	t.Setenv(graceful.EnvParallelCleanups, "")
+
+	cfg, err := graceful.ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("ConfigFromEnv() error: %v", err)
+	}
+	if cfg.ShutdownTimeout != 30*time.Second {
+		t.Errorf("ShutdownTimeout = %v, want %v", cfg.ShutdownTimeout, 30*time.Second)
+	}
+	if cfg.ShutdownDelay != 5*time.Second {
+		t.Errorf("ShutdownDelay = %v, want %v", cfg.ShutdownDelay, 5*time.Second)
+	}
+	if !cfg.ForceCloseAfterTimeout {
+		t.Error("ForceCloseAfterTimeout = false, want true")
+	}
+	if cfg.ParallelCleanups || cfg.PreShutdownTimeout != 0 {
+		t.Errorf("unset variables changed the config: %+v", cfg)
+	}
+}
+
+func TestConfigFromEnvInvalid(t *testing.T) {
+	t.Setenv(graceful.EnvShutdownTimeout, "soon")
+	t.Setenv(graceful.EnvForceOnSecondSignal, "maybe")
+	t.Setenv(graceful.EnvShutdownDelay, "2s")
+
+	cfg, err := graceful.ConfigFromEnv()
+	if !errors.Is(err, parsex.ErrSyntax) {
+		t.Fatalf("ConfigFromEnv() error = %v, want %v", err, parsex.ErrSyntax)
+	}
+	if cfg.ShutdownDelay != 2*time.Second {
+		t.Errorf("ShutdownDelay = %v, want valid values kept", cfg.ShutdownDelay)
+	}
+}
@@ -0,0 +1,83 @@
+package graceful
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Events receives the main lifecycle events of Run, so they can be sent to
+// any logger (zap, zerolog, ...) or event sink. Implementations must be safe
+// to call from the goroutine running Run.
+type Events interface {
+	// ServerStarted is called once the servers have been started.
+	ServerStarted(servers int)
+
+	// ShutdownTriggered is called when shutdown begins.
+	ShutdownTriggered(reason ShutdownReason)
+
+	// CleanupFailed is called for each cleanup that returned an error or
+	// panicked. For an entry of Config.Cleanups, name is empty and index is
+	// its position there; for a cleanup registered with Config.AddCleanup,
+	// name is its name and index its position in registration order.
+	CleanupFailed(name string, index int, err error)
+
+	// ShutdownFinished is called once shutdown has completed, with the
+	// error Run is about to return and the time shutdown took.
+	ShutdownFinished(err error, took time.Duration)
+}
+
+// NopEvents is an Events that discards every event.
+type NopEvents struct{}
+
+func (NopEvents) ServerStarted(int)                     {}
+func (NopEvents) ShutdownTriggered(ShutdownReason)      {}
+func (NopEvents) CleanupFailed(string, int, error)      {}
+func (NopEvents) ShutdownFinished(error, time.Duration) {}
+
+// SlogEvents returns an Events that writes to l. It is what Run uses when
+// Config.Events is nil and Config.Logger is set.
+func SlogEvents(l *slog.Logger) Events {
+	return slogEvents{l}
+}
+
+type slogEvents struct {
+	l *slog.Logger
+}
+
+func (e slogEvents) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if e.l != nil {
+		e.l.LogAttrs(context.Background(), level, msg, attrs...)
+	}
+}
+
+func (e slogEvents) ServerStarted(servers int) {
+	e.log(slog.LevelInfo, "servers started", slog.Int("servers", servers))
+}
+
+func (e slogEvents) ShutdownTriggered(r ShutdownReason) {
+	switch r.Trigger {
+	case TriggerServerError:
+		e.log(slog.LevelError, "server failed", slog.Any("error", r.Err))
+	case TriggerSignal:
+		e.log(slog.LevelInfo, "shutdown triggered", slog.String("reason", r.Trigger.String()), slog.String("signal", r.Signal.String()))
+	default:
+		e.log(slog.LevelInfo, "shutdown triggered", slog.String("reason", r.Trigger.String()))
+	}
+}
+
+func (e slogEvents) CleanupFailed(name string, index int, err error) {
+	if name != "" {
+		e.log(slog.LevelError, "cleanup failed", slog.String("name", name), slog.Any("error", err))
+		return
+	}
+	e.log(slog.LevelError, "cleanup failed", slog.Int("index", index), slog.Any("error", err))
+}
+
+func (e slogEvents) ShutdownFinished(err error, took time.Duration) {
+	if err != nil {
+		e.log(slog.LevelError, "shutdown complete", slog.Duration("duration", took), slog.Any("error", err))
+	} else {
+		e.log(slog.LevelInfo, "shutdown complete", slog.Duration("duration", took))
+	}
+}
@@ -0,0 +1,37 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/rin2yh/gouse/signalx"
+)
+
+// ErrForced is joined into Run's result when a second signal cut the
+// graceful shutdown short (see Config.ForceOnSecondSignal).
+var ErrForced = errors.New("graceful: shutdown forced by second signal")
+
+// watchForce returns a copy of ctx that is cancelled when one of
+// cfg.Signals arrives during shutdown; every server with a Close method is
+// then closed. signalx.Received reports whether that happened. The returned
+// stop must be called once shutdown is over.
+func watchForce(ctx context.Context, cfg *Config, srvs []Server) (context.Context, func()) {
+	fctx, stopNotify := signalx.NotifyContext(ctx, cfg.Notifier, cfg.signals()...)
+	stopAfter := context.AfterFunc(fctx, func() {
+		sig, ok := signalx.Received(fctx)
+		if !ok {
+			return
+		}
+		cfg.log(slog.LevelWarn, "shutdown forced", slog.String("signal", sig.String()))
+		for _, srv := range srvs {
+			if c, ok := srv.(closer); ok {
+				_ = c.Close()
+			}
+		}
+	})
+	return fctx, func() {
+		stopAfter()
+		stopNotify()
+	}
+}
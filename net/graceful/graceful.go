@@ -25,113 +25,574 @@
 //
 //	if err := graceful.Run(ctx, srv, &graceful.Config{
 //	    ShutdownTimeout: 10 * time.Second,
-//	    Cleanups:        []func(){db.Close},
+//	    Cleanups: []func(context.Context) error{
+//	        func(ctx context.Context) error { return db.Close() },
+//	    },
 //	}); err != nil {
 //	    log.Fatal(err)
 //	}
+//
+// Non-blocking start, stopped later by the caller:
+//
+//	h := graceful.Start(ctx, srv, nil)
+//	// ...
+//	err := h.Stop(stopCtx)
+//
+// Several servers in one process (e.g. public API and admin/metrics):
+//
+//	if err := graceful.RunAll(ctx, []graceful.Server{api, admin}, nil); err != nil {
+//	    log.Fatal(err)
+//	}
 package graceful
 
 import (
 	"context"
 	"errors"
-	"net/http"
-	"os/signal"
-	"syscall"
+	"log/slog"
+	"os"
+	"sync"
 	"time"
+
+	"github.com/rin2yh/gouse/signalx"
 )
 
-const defaultShutdownTimeout = 5 * time.Second
+const (
+	defaultShutdownTimeout    = 5 * time.Second
+	defaultPreShutdownTimeout = 5 * time.Second
+)
 
 // Server is the interface required by Run.
 // *http.Server satisfies this interface.
 //
-// ListenAndServe should return http.ErrServerClosed when Shutdown is called;
-// any other non-nil return value is treated as a startup failure by Run.
+// ListenAndServe should return http.ErrServerClosed (or an error listed in
+// Config.IgnoreServeErrors) when Shutdown is called; any other non-nil
+// return value is treated as a startup failure by Run.
 type Server interface {
 	ListenAndServe() error
 	Shutdown(ctx context.Context) error
 }
 
+// closer is implemented by servers that can drop their connections
+// immediately, such as *http.Server.
+type closer interface {
+	Close() error
+}
+
 // Config holds optional configuration for Run. The zero value is valid.
 type Config struct {
 	// ShutdownTimeout is the maximum duration Shutdown waits for in-flight
 	// requests to complete. If exceeded, Shutdown returns an error; active
 	// connections are not forcibly closed.
-	// Defaults to 5 seconds if zero.
+	// Defaults to 5 seconds if zero. Ignored if Budget is set.
 	ShutdownTimeout time.Duration
 
-	// Cleanups are functions called in order after the server shuts down
-	// (e.g. closing database connections, flushing caches).
-	// If a cleanup panics, all remaining cleanups still run before the
-	// panic is re-raised.
-	Cleanups []func()
+	// Budget, if its Total is set, replaces ShutdownTimeout with one
+	// overall shutdown deadline that reserves Budget.MinCleanup for the
+	// Cleanups.
+	Budget Budget
+
+	// ForceCloseAfterTimeout closes the servers' remaining connections when
+	// Shutdown exceeds ShutdownTimeout, so the process can exit
+	// deterministically. Requires servers with a Close method, such as
+	// *http.Server; others are left as they are.
+	ForceCloseAfterTimeout bool
+
+	// ForceCloseGrace is how long ForceCloseAfterTimeout waits past
+	// ShutdownTimeout before closing the connections that remain. Request
+	// contexts are cancelled at the timeout, so handlers that honour them
+	// can still finish their responses meanwhile; the wait ends early once
+	// every connection has closed. Zero closes them at the timeout.
+	ForceCloseGrace time.Duration
+
+	// DrainProgress, if set, is called with the number of open connections
+	// when Shutdown begins and then every DrainProgressInterval until it
+	// returns. Connections are counted for servers backed by *http.Server,
+	// whose ConnState hook is wrapped for this purpose.
+	DrainProgress func(active int)
+
+	// DrainProgressInterval is how often DrainProgress is called.
+	// Defaults to 1 second if zero.
+	DrainProgressInterval time.Duration
+
+	// TrackRequests records the in-flight requests of servers backed by
+	// *http.Server, whose Handler is wrapped for this purpose, so that the
+	// *DrainError returned when Shutdown times out names the
+	// longest-running one.
+	TrackRequests bool
+
+	// OnShutdownNotify are registered with RegisterOnShutdown on every
+	// server that supports it, such as *http.Server, and so are called in
+	// their own goroutines when Shutdown begins. Shutdown does not close
+	// hijacked or long-lived connections (WebSocket, SSE); use these to tell
+	// them to finish.
+	OnShutdownNotify []func()
+
+	// ForceOnSecondSignal makes a SIGINT or SIGTERM received during
+	// shutdown abandon it: the servers are closed, the remaining phases get
+	// an already-cancelled context, and ErrForced is joined into the result.
+	ForceOnSecondSignal bool
+
+	// PreShutdown are functions called in order once shutdown is triggered,
+	// while the servers are still accepting connections (e.g. deregistering
+	// from service discovery). Their errors are joined into Run's result but
+	// do not prevent shutdown.
+	PreShutdown []func(context.Context) error
+
+	// PreShutdownTimeout bounds the PreShutdown functions as a whole.
+	// Defaults to 5 seconds if zero.
+	PreShutdownTimeout time.Duration
+
+	// Deregister, if set, is called after PreShutdown and before
+	// ShutdownDelay to take the instance out of service discovery or a
+	// load-balancer target group. Unlike PreShutdown it is retried, with
+	// exponential backoff from 100ms up to 2s, until it succeeds or
+	// DeregisterTimeout elapses; the last error is then joined into Run's
+	// result.
+	Deregister func(context.Context) error
+
+	// DeregisterTimeout bounds the Deregister attempts as a whole.
+	// Defaults to 10 seconds if zero.
+	DeregisterTimeout time.Duration
+
+	// ShutdownDelay is how long Run keeps serving after shutdown is
+	// triggered (and after PreShutdown), before calling Shutdown. It gives
+	// load balancers time to stop routing traffic to this instance, like a
+	// Kubernetes preStop sleep. Zero means no delay.
+	ShutdownDelay time.Duration
+
+	// Cleanups are functions called in CleanupOrder after the server shuts
+	// down (e.g. closing database connections, flushing caches).
+	// Each receives a context carrying the shutdown deadline, which is shared
+	// with draining the server (see Remaining for the time left). Their
+	// errors are joined into Run's result.
+	// If a cleanup panics, the remaining cleanups still run and the panic
+	// is reported as a *PanicError in the result.
+	Cleanups []func(context.Context) error
+
+	// CleanupOrder is the order in which the cleanups run. Defaults to FIFO;
+	// LIFO tears down in reverse registration order. Ignored when
+	// ParallelCleanups is set.
+	CleanupOrder CleanupOrder
+
+	// ParallelCleanups runs the cleanups concurrently instead of in order,
+	// for independent cleanups that would otherwise exceed the shutdown
+	// budget one after another.
+	ParallelCleanups bool
+
+	// CleanupTimeout bounds each cleanup individually. The cleanup context
+	// expires at whichever comes first: this timeout or the shutdown
+	// deadline. A cleanup that does not return once its context expires is
+	// abandoned in the background and reported as ErrCleanupAbandoned, so a
+	// hung cleanup cannot block exit. Zero means only the shutdown deadline
+	// applies and cleanups are always waited for.
+	CleanupTimeout time.Duration
+
+	// Readiness, if set, is switched to report 503 as soon as shutdown is
+	// triggered, before the PreShutdown functions and Shutdown run.
+	Readiness *Readiness
+
+	// OnStart is called once the servers have been started and, if set,
+	// StartupProbe has succeeded. Optional.
+	OnStart func()
+
+	// OnReload, if set, is called each time SIGHUP arrives while the servers
+	// are running, without shutting down; SIGINT and SIGTERM still
+	// terminate. Its error is logged. The context is cancelled once shutdown
+	// begins. Unix only: other platforms have no SIGHUP.
+	OnReload func(context.Context) error
+
+	// OnSignal is called with the signal that triggered shutdown (e.g. to
+	// tell SIGTERM from an orchestrator apart from SIGINT from a terminal),
+	// before OnShutdownBegin. It is not called when shutdown was triggered
+	// otherwise. Optional.
+	OnSignal func(os.Signal)
+
+	// OnShutdownBegin is called as soon as shutdown is triggered, before
+	// the PreShutdown functions run. Optional.
+	OnShutdownBegin func()
+
+	// OnShutdownDone is called after the cleanups with the error Run is
+	// about to return. Optional.
+	OnShutdownDone func(err error)
+
+	// Metrics, if set, receives ShutdownStats once shutdown completes.
+	Metrics Metrics
+
+	// Tasks are background goroutines supervised alongside the servers,
+	// like an errgroup with signal handling. Each runs until its context is
+	// cancelled, which happens when the servers are shut down; Run waits for
+	// them (within ShutdownTimeout) before the cleanups. A task returning an
+	// error triggers shutdown and the error is joined into Run's result.
+	Tasks []func(context.Context) error
+
+	// Warmups are functions called in order before the servers start
+	// serving, e.g. to prime caches or open connections, so the first
+	// request does not pay for them. If one fails, Run returns its error
+	// without starting the servers or running the cleanups.
+	Warmups []func(context.Context) error
+
+	// WarmupTimeout bounds the Warmups as a whole.
+	// Defaults to 30 seconds if zero.
+	WarmupTimeout time.Duration
+
+	// StartupProbe, if set, is called after the servers start, every 100ms
+	// until it returns nil (e.g. a request to the service's own health
+	// endpoint). If it does not succeed within StartupTimeout, Run shuts
+	// down and returns an error wrapping ErrStartupTimeout.
+	StartupProbe func(context.Context) error
+
+	// StartupTimeout bounds StartupProbe. Defaults to 30 seconds if zero.
+	StartupTimeout time.Duration
+
+	// IgnoreServeErrors lists errors that, like http.ErrServerClosed, mean a
+	// server stopped cleanly when ListenAndServe returns them (matched with
+	// errors.Is), e.g. net.ErrClosed from custom listeners.
+	IgnoreServeErrors []error
+
+	// RestartPolicy, if set, restarts a server whose ListenAndServe fails
+	// instead of shutting down. Nil means a failure triggers shutdown.
+	RestartPolicy *RestartPolicy
+
+	// Upgrade enables zero-downtime restarts on Unix: on SIGUSR2 the running
+	// executable is started again and inherits every listener obtained
+	// through Listen. Once the new process has started its servers it sends
+	// SIGTERM to this one, which then drains as usual. Ignored on other
+	// platforms.
+	Upgrade bool
+
+	// Logger receives lifecycle events: startup, what triggered shutdown,
+	// timeouts, cleanup failures and completion. Nil disables logging.
+	Logger *slog.Logger
+
+	// Tracer, if set, wraps the shutdown sequence and each of its phases
+	// and cleanups in spans. The cleanups' contexts carry their span.
+	Tracer Tracer
+
+	// Events, if set, receives the startup, shutdown trigger, cleanup
+	// failure and completion events instead of Logger, e.g. to log them with
+	// another library. Logger still receives the remaining events.
+	Events Events
+
+	// Notifier delivers the shutdown signals. Defaults to signalx.OS if nil;
+	// tests can pass a *signalx.Fake to simulate SIGINT/SIGTERM.
+	Notifier signalx.Notifier
+
+	// Signals trigger shutdown. Defaults to os.Interrupt and SIGTERM if
+	// empty (os.Interrupt only on platforms other than Unix and Windows).
+	// On Windows, SIGTERM is delivered for console close, logoff and system
+	// shutdown events.
+	Signals []os.Signal
+
+	// Clock measures the shutdown deadlines (ShutdownTimeout,
+	// PreShutdownTimeout, CleanupTimeout), ShutdownDelay and the
+	// ShutdownStats durations. Defaults to the system clock if nil; tests
+	// can pass a gracefultest.FakeClock to expire timeouts without sleeping.
+	Clock Clock
+
+	// namedCleanups are the cleanups registered with AddCleanup.
+	namedCleanups []namedCleanup
 }
 
 // Run starts srv and blocks until SIGINT/SIGTERM is received (or parent is
-// cancelled), then shuts down gracefully within the configured timeout and
-// runs each cleanup function in order.
+// cancelled), runs the pre-shutdown functions, then shuts down gracefully
+// within the configured timeout and runs each cleanup function in order.
+// The returned error joins any shutdown and cleanup errors.
 //
 // If cfg is nil, a 5-second shutdown timeout is used with no cleanups.
 func Run(parent context.Context, srv Server, cfg *Config) error {
+	return RunAll(parent, []Server{srv}, cfg)
+}
+
+// RunAll is like Run but manages several servers at once, e.g. a public API
+// server and an admin/metrics server in the same process.
+//
+// All servers are started together. If any of them fails to start, the
+// others are shut down and RunAll returns the startup error without running
+// cleanups. Otherwise, on SIGINT/SIGTERM or cancellation of parent, all
+// servers are shut down in parallel within the configured timeout before the
+// cleanups run. The returned error joins every startup and shutdown error.
+//
+// A panic in ListenAndServe is recovered and treated like a server error,
+// except that the cleanups still run; the *PanicError carries the stack.
+func RunAll(parent context.Context, srvs []Server, cfg *Config) error {
+	return runAll(parent, srvs, cfg, nil)
+}
+
+// runAll implements RunAll, recording its progress in lc.
+func runAll(parent context.Context, srvs []Server, cfg *Config, lc *lifecycle) error {
 	if cfg == nil {
 		cfg = &Config{}
 	}
 
-	ctx, stop := signal.NotifyContext(parent, syscall.SIGINT, syscall.SIGTERM)
+	ctx, stop := signalx.NotifyContext(parent, cfg.Notifier, cfg.signals()...)
 	defer stop()
 
-	serverErr := make(chan error, 1)
-	go func() {
-		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-			serverErr <- err
+	if len(cfg.Tasks) > 0 {
+		// Tasks are managed as servers; copy so the caller's slice is untouched.
+		srvs = append([]Server(nil), srvs...)
+		for _, task := range cfg.Tasks {
+			srvs = append(srvs, newRunnerServer(context.WithoutCancel(ctx), taskRunner(task)))
 		}
-		close(serverErr)
-	}()
+	}
 
-	select {
-	case err := <-serverErr:
+	if err := warmup(ctx, cfg); err != nil {
+		lc.finish(err)
 		return err
-	case <-ctx.Done():
 	}
 
-	timeout := defaultShutdownTimeout
-	if cfg.ShutdownTimeout > 0 {
-		timeout = cfg.ShutdownTimeout
+	if cfg.OnReload != nil {
+		stopReload := watchReload(ctx, cfg)
+		defer stopReload()
+	}
+
+	conns := trackConns(srvs)
+	var inflight *requestTracker
+	if cfg.TrackRequests {
+		inflight = trackRequests(srvs, cfg.clock())
+	}
+	registerOnShutdown(srvs, cfg.OnShutdownNotify)
+	reqs := newRequestBase(parent)
+	defer reqs.cancel()
+	reqs.install(srvs)
+	stopping := make(chan struct{})
+	serverErr := serveAll(srvs, cfg, stopping)
+	cfg.events().ServerStarted(len(srvs))
+	if cfg.Upgrade {
+		stopUpgrade := watchUpgrade(ctx, cfg)
+		defer stopUpgrade()
+	}
+
+	ready := func() {
+		lc.set(Running)
+		if cfg.OnStart != nil {
+			cfg.OnStart()
+		}
+		if cfg.Upgrade {
+			notifyParent(cfg)
+		}
+	}
+	probed := probeStartup(ctx, cfg)
+	if probed == nil {
+		ready()
+	}
+
+	pending := len(srvs)
+	var startErr error
+	for startErr == nil && pending > 0 && ctx.Err() == nil {
+		select {
+		case startErr = <-serverErr:
+			pending--
+		case err := <-probed:
+			probed = nil
+			if err != nil {
+				startErr = err
+			} else {
+				ready()
+			}
+		case <-ctx.Done():
+		}
+	}
+
+	close(stopping)
+	lc.set(Draining)
+	stats := ShutdownStats{Reason: shutdownReason(ctx, startErr)}
+	lc.trigger(stats.Reason)
+	cfg.events().ShutdownTriggered(stats.Reason)
+	begin := cfg.clock().Now()
+	if stats.Reason.Trigger == TriggerSignal && cfg.OnSignal != nil {
+		cfg.OnSignal(stats.Reason.Signal)
 	}
+
 	// context.WithoutCancel preserves values (trace IDs, loggers) from ctx
-	// while preventing the already-cancelled ctx from short-circuiting shutdown.
-	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	// while preventing the already-cancelled ctx from short-circuiting
+	// shutdown. A forced shutdown cancels base instead.
+	base := context.WithoutCancel(ctx)
+	if cfg.ForceOnSecondSignal {
+		var stopForce func()
+		base, stopForce = watchForce(base, cfg, srvs)
+		defer stopForce()
+	}
+	base, endShutdown := cfg.startSpan(base, "graceful.shutdown")
+
+	reqs.drain()
+	cfg.Readiness.setDraining()
+	if cfg.OnShutdownBegin != nil {
+		cfg.OnShutdownBegin()
+	}
+
+	var errs []error
+	if startErr == nil {
+		preCtx, endPre := cfg.startSpan(base, "graceful.pre_shutdown")
+		preErrs := preShutdown(preCtx, cfg)
+		endPre(errors.Join(preErrs...))
+		errs = append(errs, preErrs...)
+		if cfg.Deregister != nil {
+			deregCtx, endDereg := cfg.startSpan(base, "graceful.deregister")
+			deregErr := deregister(deregCtx, cfg)
+			endDereg(deregErr)
+			errs = append(errs, deregErr)
+		}
+		if cfg.ShutdownDelay > 0 {
+			cfg.log(slog.LevelInfo, "delaying shutdown", slog.Duration("delay", cfg.ShutdownDelay))
+			cfg.sleep(base, cfg.ShutdownDelay)
+		}
+	}
+
+	timeout := cfg.drainTimeout(cfg.since(begin))
+	shutdownCtx, cancel := cfg.withTimeout(base, timeout)
 	defer cancel()
+	reqs.expireWith(shutdownCtx)
 
-	shutdownErr := srv.Shutdown(shutdownCtx)
+	errs = append(errs, startErr)
+	drainBegin := cfg.clock().Now()
+	drainCtx, endDrain := cfg.startSpan(shutdownCtx, "graceful.drain")
+	shutdownErrs := drain(drainCtx, cfg, srvs, conns, inflight)
+	endDrain(errors.Join(shutdownErrs...))
+	stats.DrainDuration = cfg.since(drainBegin)
+	if errors.Is(errors.Join(shutdownErrs...), context.DeadlineExceeded) {
+		stats.TimedOut = true
+		cfg.log(slog.LevelWarn, "shutdown timed out", slog.Duration("timeout", timeout))
+	}
+	errs = append(errs, shutdownErrs...)
 
 	// Drain serverErr: a real ListenAndServe error may have raced with ctx.Done
 	// and been lost when the select chose the ctx.Done branch.
-	srvErr := <-serverErr
+	for ; pending > 0; pending-- {
+		errs = append(errs, <-serverErr)
+	}
 
-	cleanup(cfg.Cleanups)
+	// A server that panicked, or a task that failed, was running, unlike a
+	// server that failed to start, so whatever the cleanups release may
+	// already be in use.
+	var panicked *PanicError
+	var failed *taskError
+	if startErr == nil || errors.As(startErr, &panicked) || errors.As(startErr, &failed) {
+		cleanupBegin := cfg.clock().Now()
+		cleanupCtx, cancelCleanups := cfg.cleanupContext(base, shutdownCtx, cfg.since(begin))
+		defer cancelCleanups()
+		cleanupCtx, endCleanups := cfg.startSpan(cleanupCtx, "graceful.cleanups")
+		cleanupErrs := runCleanups(cleanupCtx, cfg)
+		endCleanups(errors.Join(cleanupErrs...))
+		stats.CleanupDuration = cfg.since(cleanupBegin)
+		for i, err := range cleanupErrs {
+			if err == nil {
+				continue
+			}
+			stats.CleanupFailures++
+			if i < len(cfg.Cleanups) {
+				cfg.events().CleanupFailed("", i, err)
+			} else {
+				i -= len(cfg.Cleanups)
+				cfg.events().CleanupFailed(cfg.namedCleanups[i].name, i, err)
+			}
+		}
+		errs = append(errs, cleanupErrs...)
+	}
+
+	if _, forced := signalx.Received(base); forced {
+		errs = append(errs, ErrForced)
+	}
+
+	err := errors.Join(errs...)
+	endShutdown(err)
+	stats.Duration = cfg.since(begin)
+	cfg.events().ShutdownFinished(err, stats.Duration)
+	if cfg.Metrics != nil {
+		cfg.Metrics.RecordShutdown(stats)
+	}
+	lc.finish(err)
+	if cfg.OnShutdownDone != nil {
+		cfg.OnShutdownDone(err)
+	}
+	return err
+}
+
+// taskError marks an error returned by one of Config.Tasks, which, unlike
+// a server's, does not mean the process failed to start.
+type taskError struct{ err error }
+
+func (e *taskError) Error() string { return e.err.Error() }
+
+func (e *taskError) Unwrap() error { return e.err }
+
+// taskRunner adapts a Config.Tasks entry to a Runner, marking its errors
+// with taskError.
+func taskRunner(task func(context.Context) error) Runner {
+	return RunnerFunc(func(ctx context.Context) error {
+		if err := task(ctx); err != nil {
+			return &taskError{err}
+		}
+		return nil
+	})
+}
+
+// serveAll starts every server in its own goroutine. Each server reports on
+// the returned channel exactly once: nil when it stopped because of
+// Shutdown, otherwise the error that made ListenAndServe return for good
+// (see Config.RestartPolicy). Closing stopping cancels pending restarts.
+func serveAll(srvs []Server, cfg *Config, stopping <-chan struct{}) <-chan error {
+	serverErr := make(chan error, len(srvs))
+	for _, srv := range srvs {
+		go func(srv Server) {
+			serverErr <- serve(srv, cfg, stopping)
+		}(srv)
+	}
+	return serverErr
+}
+
+// preShutdown runs cfg.PreShutdown within cfg.PreShutdownTimeout. ctx must
+// not be cancelled by the shutdown trigger itself.
+func preShutdown(ctx context.Context, cfg *Config) []error {
+	if len(cfg.PreShutdown) == 0 {
+		return nil
+	}
+	timeout := defaultPreShutdownTimeout
+	if cfg.PreShutdownTimeout > 0 {
+		timeout = cfg.PreShutdownTimeout
+	}
+	ctx, cancel := cfg.withTimeout(ctx, timeout)
+	defer cancel()
+	errs := callAll(ctx, cfg.PreShutdown)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		cfg.log(slog.LevelWarn, "pre-shutdown timed out", slog.Duration("timeout", timeout))
+	}
+	return errs
+}
+
+// signals returns the signals that trigger shutdown.
+func (c *Config) signals() []os.Signal {
+	if len(c.Signals) > 0 {
+		return c.Signals
+	}
+	return defaultSignals
+}
+
+// events returns the Events receiving lifecycle events.
+func (c *Config) events() Events {
+	if c.Events != nil {
+		return c.Events
+	}
+	return slogEvents{c.Logger}
+}
 
-	if srvErr != nil {
-		return srvErr
+func (c *Config) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if c.Logger != nil {
+		c.Logger.LogAttrs(context.Background(), level, msg, attrs...)
 	}
-	return shutdownErr
 }
 
-// cleanup calls each fn in order. If one panics, the rest still run;
-// the first panic value is re-raised after all have completed.
-func cleanup(fns []func()) {
-	var panicVal any
-	for _, fn := range fns {
-		func() {
-			defer func() {
-				if r := recover(); r != nil && panicVal == nil {
-					panicVal = r
-				}
-			}()
-			fn()
-		}()
-	}
-	if panicVal != nil {
-		panic(panicVal)
+// shutdownAll calls Shutdown on every server in parallel and returns the
+// errors in server order.
+func shutdownAll(ctx context.Context, srvs []Server) []error {
+	errs := make([]error, len(srvs))
+	var wg sync.WaitGroup
+	for i, srv := range srvs {
+		wg.Add(1)
+		go func(i int, srv Server) {
+			defer wg.Done()
+			errs[i] = srv.Shutdown(ctx)
+		}(i, srv)
 	}
+	wg.Wait()
+	return errs
 }
@@ -1,13 +1,24 @@
 package graceful_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/rin2yh/gouse/net/graceful"
+	"github.com/rin2yh/gouse/net/graceful/gracefultest"
+	"github.com/rin2yh/gouse/signalx"
 )
 
 func TestRun(t *testing.T) {
@@ -40,59 +51,93 @@ func TestRunServerError(t *testing.T) {
 }
 
 func TestRunCleanup(t *testing.T) {
-	var called []string
+	tests := map[string]struct {
+		order graceful.CleanupOrder
+		want  []string
+	}{
+		"FIFO": {graceful.FIFO, []string{"first", "second"}},
+		"LIFO": {graceful.LIFO, []string{"second", "first"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var called []string
+			_, cancel, done := startRun(t, http.DefaultServeMux, &graceful.Config{
+				ShutdownTimeout: testShutdownTimeout,
+				CleanupOrder:    tt.order,
+				Cleanups: []func(context.Context) error{
+					func(context.Context) error { called = append(called, "first"); return nil },
+					func(context.Context) error { called = append(called, "second"); return nil },
+				},
+			})
+			cancel()
+			if err := awaitShutdown(t, done); err != nil {
+				t.Fatalf("expected nil error, got: %v", err)
+			}
+			if !reflect.DeepEqual(called, tt.want) {
+				t.Fatalf("cleanups ran in order %v, want %v", called, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCleanupErrors(t *testing.T) {
+	errFirst := errors.New("flush failed")
+	errSecond := errors.New("close failed")
+	var deadlineSet bool
 	_, cancel, done := startRun(t, http.DefaultServeMux, &graceful.Config{
 		ShutdownTimeout: testShutdownTimeout,
-		Cleanups: []func(){
-			func() { called = append(called, "first") },
-			func() { called = append(called, "second") },
+		Cleanups: []func(context.Context) error{
+			func(ctx context.Context) error {
+				_, deadlineSet = ctx.Deadline()
+				return errFirst
+			},
+			func(context.Context) error { return errSecond },
 		},
 	})
 	cancel()
-	if err := awaitShutdown(t, done); err != nil {
-		t.Fatalf("expected nil error, got: %v", err)
+	err := awaitShutdown(t, done)
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Fatalf("expected joined cleanup errors, got: %v", err)
 	}
-	if len(called) != 2 || called[0] != "first" || called[1] != "second" {
-		t.Fatalf("expected cleanups to run in order, got: %v", called)
+	if !deadlineSet {
+		t.Fatal("expected cleanup context to carry the shutdown deadline")
 	}
 }
 
 func TestRunCleanupPanic(t *testing.T) {
-	srv, addr := newTestServer(t, http.DefaultServeMux)
-	ctx, cancel := context.WithCancel(context.Background())
-	t.Cleanup(cancel)
-
+	errThird := errors.New("third failed")
 	secondRan := false
-	done := make(chan any, 1)
-	go func() {
-		defer func() { done <- recover() }()
-		_ = graceful.Run(ctx, srv, &graceful.Config{
-			ShutdownTimeout: testShutdownTimeout,
-			Cleanups: []func(){
-				func() { panic("cleanup panic") },
-				func() { secondRan = true },
-			},
-		})
-	}()
+	_, cancel, done := startRun(t, http.DefaultServeMux, &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		Cleanups: []func(context.Context) error{
+			func(context.Context) error { panic("cleanup panic") },
+			func(context.Context) error { secondRan = true; return nil },
+			func(context.Context) error { return errThird },
+			func(context.Context) error { panic("another panic") },
+		},
+	})
 
-	if err := waitForServer(addr, testStartTimeout); err != nil {
-		t.Fatal("server did not start in time:", err)
-	}
 	cancel()
+	err := awaitShutdown(t, done)
+	if !secondRan {
+		t.Fatal("second cleanup did not run after first cleanup panicked")
+	}
+	if !errors.Is(err, errThird) {
+		t.Fatalf("expected cleanup error in result, got: %v", err)
+	}
 
-	select {
-	case val := <-done:
-		if val == nil {
-			t.Fatal("expected Run to panic from cleanup, but it returned normally")
-		}
-		if val != "cleanup panic" {
-			t.Fatalf("expected panic value %q, got %v", "cleanup panic", val)
+	var panics []any
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var pe *graceful.PanicError
+		if errors.As(e, &pe) {
+			panics = append(panics, pe.Value)
+			if len(pe.Stack) == 0 {
+				t.Error("expected PanicError to carry a stack")
+			}
 		}
-	case <-time.After(testShutdownTimeout):
-		t.Fatal("Run did not return in time")
 	}
-	if !secondRan {
-		t.Fatal("second cleanup did not run after first cleanup panicked")
+	if len(panics) != 2 || panics[0] != "cleanup panic" || panics[1] != "another panic" {
+		t.Fatalf("expected both panics to be reported, got: %v", panics)
 	}
 }
 
@@ -120,17 +165,25 @@ func TestRunHandlesRequests(t *testing.T) {
 }
 
 func TestRunShutdownError(t *testing.T) {
-	const shortTimeout = 50 * time.Millisecond
+	const timeout = time.Minute
 
 	handlerStarted := make(chan struct{})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
 	mux := http.NewServeMux()
 	mux.HandleFunc("/hang", func(w http.ResponseWriter, r *http.Request) {
 		close(handlerStarted) // signal before blocking so cancel fires while in-flight
-		time.Sleep(2 * shortTimeout)
+		<-release
 		w.WriteHeader(http.StatusOK)
 	})
 
-	addr, cancel, done := startRun(t, mux, &graceful.Config{ShutdownTimeout: shortTimeout})
+	clock := gracefultest.NewFakeClock(time.Now())
+	var stats graceful.ShutdownStats
+	addr, cancel, done := startRun(t, mux, &graceful.Config{
+		ShutdownTimeout: timeout,
+		Clock:           clock,
+		Metrics:         graceful.MetricsFunc(func(s graceful.ShutdownStats) { stats = s }),
+	})
 
 	// Client timeout prevents this goroutine hanging if the server never responds.
 	client := &http.Client{Timeout: testShutdownTimeout}
@@ -150,7 +203,1329 @@ func TestRunShutdownError(t *testing.T) {
 	}
 
 	cancel()
-	if err := awaitShutdown(t, done); err == nil {
-		t.Fatal("expected non-nil error when shutdown times out, got nil")
+	clock.BlockUntil(1) // the shutdown deadline
+	clock.Advance(timeout)
+	err := awaitShutdown(t, done)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded when shutdown times out, got: %v", err)
+	}
+	if !stats.TimedOut {
+		t.Fatal("expected ShutdownStats.TimedOut to be true")
+	}
+	if stats.Duration != timeout {
+		t.Fatalf("ShutdownStats.Duration = %v, want %v", stats.Duration, timeout)
+	}
+}
+
+func TestRunDrainError(t *testing.T) {
+	const timeout = time.Minute
+
+	handlerStarted := make(chan struct{})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hang", func(w http.ResponseWriter, r *http.Request) {
+		close(handlerStarted)
+		<-release
+	})
+
+	clock := gracefultest.NewFakeClock(time.Now())
+	addr, cancel, done := startRun(t, mux, &graceful.Config{
+		ShutdownTimeout: timeout,
+		TrackRequests:   true,
+		Clock:           clock,
+	})
+
+	client := &http.Client{Timeout: testShutdownTimeout}
+	go func() {
+		resp, err := client.Get("http://" + addr + "/hang")
+		if err == nil && resp != nil {
+			resp.Body.Close()
+		}
+	}()
+	select {
+	case <-handlerStarted:
+	case <-time.After(testStartTimeout):
+		t.Fatal("handler did not start in time")
+	}
+
+	cancel()
+	clock.BlockUntil(1)
+	clock.Advance(timeout)
+	err := awaitShutdown(t, done)
+	var derr *graceful.DrainError
+	if !errors.As(err, &derr) {
+		t.Fatalf("expected a *graceful.DrainError, got: %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DrainError to wrap context.DeadlineExceeded, got: %v", err)
+	}
+	if derr.ActiveConns < 1 {
+		t.Errorf("ActiveConns = %d, want at least 1", derr.ActiveConns)
+	}
+	if derr.Path != "/hang" || derr.Elapsed != timeout {
+		t.Errorf("longest request = %q after %v, want %q after %v", derr.Path, derr.Elapsed, "/hang", timeout)
+	}
+}
+
+func TestRunBudget(t *testing.T) {
+	budget := graceful.Budget{Total: 30 * time.Second, MinCleanup: 5 * time.Second}
+
+	handlerStarted := make(chan struct{})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hang", func(w http.ResponseWriter, r *http.Request) {
+		close(handlerStarted)
+		<-release
+	})
+
+	clock := gracefultest.NewFakeClock(time.Now())
+	cleanupStarted := make(chan struct{})
+	var stats graceful.ShutdownStats
+	addr, cancel, done := startRun(t, mux, &graceful.Config{
+		Budget: budget,
+		Clock:  clock,
+		Cleanups: []func(context.Context) error{func(ctx context.Context) error {
+			close(cleanupStarted)
+			<-ctx.Done()
+			return nil
+		}},
+		Metrics: graceful.MetricsFunc(func(s graceful.ShutdownStats) { stats = s }),
+	})
+
+	client := &http.Client{Timeout: testShutdownTimeout}
+	go func() {
+		resp, err := client.Get("http://" + addr + "/hang")
+		if err == nil && resp != nil {
+			resp.Body.Close()
+		}
+	}()
+	select {
+	case <-handlerStarted:
+	case <-time.After(testStartTimeout):
+		t.Fatal("handler did not start in time")
+	}
+
+	cancel()
+	clock.BlockUntil(1) // drain deadline
+	clock.Advance(budget.Total - budget.MinCleanup)
+	select {
+	case <-cleanupStarted:
+	case <-time.After(testShutdownTimeout):
+		t.Fatal("cleanup did not start after the drain deadline")
+	}
+	clock.BlockUntil(1) // cleanup deadline
+	clock.Advance(budget.MinCleanup)
+	if err := awaitShutdown(t, done); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the drain to time out, got: %v", err)
+	}
+	if want := budget.Total - budget.MinCleanup; stats.DrainDuration != want {
+		t.Errorf("DrainDuration = %v, want %v", stats.DrainDuration, want)
+	}
+	if stats.CleanupDuration != budget.MinCleanup {
+		t.Errorf("CleanupDuration = %v, want %v", stats.CleanupDuration, budget.MinCleanup)
+	}
+}
+
+func TestRunServePanic(t *testing.T) {
+	var cleaned bool
+	srv := &controllableServer{listenFunc: func() error { panic("listener exploded") }}
+	err := graceful.Run(context.Background(), srv, &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		Cleanups: []func(context.Context) error{func(context.Context) error {
+			cleaned = true
+			return nil
+		}},
+	})
+	var pe *graceful.PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected a *graceful.PanicError, got: %v", err)
+	}
+	if pe.Value != "listener exploded" || len(pe.Stack) == 0 {
+		t.Fatalf("PanicError = %v with %d bytes of stack", pe.Value, len(pe.Stack))
+	}
+	if !cleaned {
+		t.Fatal("expected cleanups to run after a ListenAndServe panic")
+	}
+}
+
+func TestRunDeregister(t *testing.T) {
+	errUnavailable := errors.New("registry unavailable")
+	tests := []struct {
+		name    string
+		advance []time.Duration // clock steps, each taken while Deregister backs off
+		fails   int
+		wantErr bool
+	}{
+		{"retried until success", []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, 2, false},
+		{"gives up at the deadline", []time.Duration{time.Second}, 100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := gracefultest.NewFakeClock(time.Now())
+			var attempts int
+			_, cancel, done := startRun(t, http.DefaultServeMux, &graceful.Config{
+				ShutdownTimeout:   testShutdownTimeout,
+				DeregisterTimeout: time.Second,
+				Clock:             clock,
+				Deregister: func(context.Context) error {
+					attempts++
+					if attempts <= tt.fails {
+						return errUnavailable
+					}
+					return nil
+				},
+			})
+
+			cancel()
+			for _, d := range tt.advance {
+				clock.BlockUntil(2) // the deregister deadline and the backoff
+				clock.Advance(d)
+			}
+			err := awaitShutdown(t, done)
+			if tt.wantErr != errors.Is(err, errUnavailable) {
+				t.Fatalf("Run() = %v, want error %v: %v", err, tt.wantErr, errUnavailable)
+			}
+			if want := len(tt.advance) + 1; !tt.wantErr && attempts != want {
+				t.Fatalf("Deregister called %d times, want %d", attempts, want)
+			}
+		})
+	}
+}
+
+func TestRunCleanupRemaining(t *testing.T) {
+	const timeout = time.Minute
+	tests := []struct {
+		name  string
+		clock graceful.Clock
+	}{
+		{"system clock", nil},
+		{"fake clock", gracefultest.NewFakeClock(time.Now())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				remaining time.Duration
+				ok        bool
+			)
+			_, cancel, done := startRun(t, http.DefaultServeMux, &graceful.Config{
+				ShutdownTimeout: timeout,
+				Clock:           tt.clock,
+				Cleanups: []func(context.Context) error{func(ctx context.Context) error {
+					remaining, ok = graceful.Remaining(ctx)
+					return nil
+				}},
+			})
+			cancel()
+			if err := awaitShutdown(t, done); err != nil {
+				t.Fatalf("expected nil error, got: %v", err)
+			}
+			if !ok || remaining <= 0 || remaining > timeout {
+				t.Fatalf("Remaining() = %v, %v; want within (0, %v]", remaining, ok, timeout)
+			}
+		})
+	}
+}
+
+func TestRunSignal(t *testing.T) {
+	var (
+		fake signalx.Fake
+		got  os.Signal
+	)
+	_, _, done := startRun(t, http.DefaultServeMux, &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		Notifier:        &fake,
+		OnSignal:        func(sig os.Signal) { got = sig },
+	})
+
+	if !fake.Send(syscall.SIGTERM) {
+		t.Fatal("expected Run to listen for SIGTERM")
+	}
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if got != syscall.SIGTERM {
+		t.Fatalf("OnSignal received %v, want %v", got, syscall.SIGTERM)
+	}
+}
+
+func TestRunSignals(t *testing.T) {
+	var fake signalx.Fake
+	_, _, done := startRun(t, http.DefaultServeMux, &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		Notifier:        &fake,
+		Signals:         []os.Signal{os.Interrupt},
+	})
+
+	if fake.Send(syscall.SIGTERM) {
+		t.Fatal("expected Signals to replace the default SIGTERM")
+	}
+	if !fake.Send(os.Interrupt) {
+		t.Fatal("expected Run to listen for os.Interrupt")
+	}
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+}
+
+func TestRunAll(t *testing.T) {
+	api, apiAddr := newTestServer(t, http.DefaultServeMux)
+	admin, adminAddr := newTestServer(t, http.DefaultServeMux)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cleaned := false
+	done := make(chan error, 1)
+	go func() {
+		done <- graceful.RunAll(ctx, []graceful.Server{api, admin}, &graceful.Config{
+			ShutdownTimeout: testShutdownTimeout,
+			Cleanups:        []func(context.Context) error{func(context.Context) error { cleaned = true; return nil }},
+		})
+	}()
+	for _, addr := range []string{apiAddr, adminAddr} {
+		if err := waitForServer(addr, testStartTimeout); err != nil {
+			t.Fatal("server did not start in time:", err)
+		}
+	}
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if !cleaned {
+		t.Fatal("expected cleanups to run")
+	}
+}
+
+func TestRunAllStartupError(t *testing.T) {
+	want := errors.New("listen tcp: bind: address already in use")
+	failing := &controllableServer{listenFunc: func() error { return want }}
+	healthy := newBenchmarkServer()
+
+	cleaned := false
+	err := graceful.RunAll(context.Background(), []graceful.Server{healthy, failing}, &graceful.Config{
+		Cleanups: []func(context.Context) error{func(context.Context) error { cleaned = true; return nil }},
+	})
+	if !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+	if cleaned {
+		t.Fatal("expected cleanups to be skipped after a startup error")
+	}
+}
+
+func TestRunAllShutdownErrors(t *testing.T) {
+	errA := errors.New("shutdown a")
+	errB := errors.New("shutdown b")
+	newServer := func(shutdownErr error) *controllableServer {
+		srv := newBenchmarkServer()
+		inner := srv.shutdownFunc
+		srv.shutdownFunc = func(ctx context.Context) error {
+			_ = inner(ctx)
+			return shutdownErr
+		}
+		return srv
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := graceful.RunAll(ctx, []graceful.Server{newServer(errA), newServer(errB)}, nil)
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("expected joined shutdown errors, got %v", err)
+	}
+}
+
+func TestRunPreShutdown(t *testing.T) {
+	var addr string
+	var order []string
+	errHook := errors.New("deregister failed")
+	cfg := &graceful.Config{
+		ShutdownTimeout:    testShutdownTimeout,
+		PreShutdownTimeout: time.Second,
+		PreShutdown: []func(context.Context) error{
+			func(ctx context.Context) error {
+				order = append(order, "pre-shutdown")
+				if _, ok := ctx.Deadline(); !ok {
+					t.Error("expected pre-shutdown context to have a deadline")
+				}
+				// The server must still accept requests at this point.
+				resp, err := http.Get("http://" + addr + "/")
+				if err != nil {
+					t.Errorf("server not serving during pre-shutdown: %v", err)
+					return nil
+				}
+				resp.Body.Close()
+				return errHook
+			},
+		},
+		Cleanups: []func(context.Context) error{
+			func(context.Context) error { order = append(order, "cleanup"); return nil },
+		},
+	}
+	addr, cancel, done := startRun(t, http.DefaultServeMux, cfg)
+
+	cancel()
+	if err := awaitShutdown(t, done); !errors.Is(err, errHook) {
+		t.Fatalf("expected pre-shutdown error, got: %v", err)
+	}
+	if len(order) != 2 || order[0] != "pre-shutdown" || order[1] != "cleanup" {
+		t.Fatalf("unexpected call order: %v", order)
+	}
+}
+
+func TestRunLifecycleHooks(t *testing.T) {
+	var events []string
+	errCleanup := errors.New("cleanup failed")
+	var doneErr error
+	_, cancel, done := startRun(t, http.DefaultServeMux, &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		OnStart:         func() { events = append(events, "start") },
+		OnShutdownBegin: func() { events = append(events, "shutdown-begin") },
+		OnShutdownDone: func(err error) {
+			events = append(events, "shutdown-done")
+			doneErr = err
+		},
+		PreShutdown: []func(context.Context) error{
+			func(context.Context) error { events = append(events, "pre-shutdown"); return nil },
+		},
+		Cleanups: []func(context.Context) error{
+			func(context.Context) error { events = append(events, "cleanup"); return errCleanup },
+		},
+	})
+
+	cancel()
+	err := awaitShutdown(t, done)
+	want := []string{"start", "shutdown-begin", "pre-shutdown", "cleanup", "shutdown-done"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	if !errors.Is(doneErr, errCleanup) || doneErr != err {
+		t.Fatalf("OnShutdownDone received %v, Run returned %v", doneErr, err)
+	}
+}
+
+func TestRunLogger(t *testing.T) {
+	var buf bytes.Buffer
+	var fake signalx.Fake
+	errCleanup := errors.New("close failed")
+	cfg := &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		Notifier:        &fake,
+		Logger:          slog.New(slog.NewTextHandler(&buf, nil)),
+		Cleanups: []func(context.Context) error{
+			func(context.Context) error { return errCleanup },
+		},
+	}
+	cfg.AddCleanup("queue", func(context.Context) error { return errCleanup })
+	_, _, done := startRun(t, http.DefaultServeMux, cfg)
+
+	fake.Send(syscall.SIGTERM)
+	_ = awaitShutdown(t, done)
+
+	out := buf.String()
+	for _, want := range []string{
+		`msg="servers started" servers=1`,
+		`msg="shutdown triggered" reason=signal signal=terminated`,
+		`msg="cleanup failed" index=0 error="close failed"`,
+		`msg="cleanup failed" name=queue error="graceful: cleanup \"queue\": close failed"`,
+		`level=ERROR msg="shutdown complete"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunReadiness(t *testing.T) {
+	var ready graceful.Readiness
+	mux := http.NewServeMux()
+	mux.Handle("/readyz", &ready)
+
+	var addr string
+	var duringShutdown int
+	cfg := &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		Readiness:       &ready,
+		PreShutdown: []func(context.Context) error{
+			func(context.Context) error {
+				resp, err := http.Get("http://" + addr + "/readyz")
+				if err != nil {
+					return err
+				}
+				resp.Body.Close()
+				duringShutdown = resp.StatusCode
+				return nil
+			},
+		},
+	}
+	addr, cancel, done := startRun(t, mux, cfg)
+
+	resp, err := http.Get("http://" + addr + "/readyz")
+	if err != nil {
+		t.Fatal("request failed:", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 before shutdown, got %d", resp.StatusCode)
+	}
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if duringShutdown != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 once shutdown began, got %d", duringShutdown)
+	}
+	if !ready.Draining() {
+		t.Fatal("expected Draining() to be true after shutdown")
+	}
+}
+
+func TestRunShutdownDelay(t *testing.T) {
+	const delay = 100 * time.Millisecond
+
+	var shutdownAt time.Time
+	srv := newBenchmarkServer()
+	inner := srv.shutdownFunc
+	srv.shutdownFunc = func(ctx context.Context) error {
+		shutdownAt = time.Now()
+		return inner(ctx)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := graceful.Run(ctx, srv, &graceful.Config{ShutdownDelay: delay}); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if elapsed := shutdownAt.Sub(start); elapsed < delay {
+		t.Fatalf("Shutdown called after %v, want at least %v", elapsed, delay)
+	}
+}
+
+func TestRunListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- graceful.RunListener(ctx, &http.Server{Handler: http.DefaultServeMux}, ln, nil)
+	}()
+	if err := waitForServer(ln.Addr().String(), testStartTimeout); err != nil {
+		t.Fatal("server did not start in time:", err)
+	}
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if _, err := ln.Accept(); err == nil {
+		t.Fatal("expected listener to be closed after shutdown")
+	}
+}
+
+func TestRunParallelCleanups(t *testing.T) {
+	const n = 3
+	errSlow := errors.New("slow cleanup")
+
+	// Each cleanup waits until all have started, which only completes if
+	// they run concurrently.
+	var started sync.WaitGroup
+	started.Add(n)
+	cleanups := make([]func(context.Context) error, n)
+	for i := range cleanups {
+		cleanups[i] = func(ctx context.Context) error {
+			started.Done()
+			started.Wait()
+			return nil
+		}
+	}
+	cleanups = append(cleanups, func(ctx context.Context) error {
+		<-ctx.Done()
+		return errSlow
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	err := graceful.Run(ctx, newBenchmarkServer(), &graceful.Config{
+		ShutdownTimeout:  testShutdownTimeout,
+		ParallelCleanups: true,
+		CleanupTimeout:   50 * time.Millisecond,
+		Cleanups:         cleanups,
+	})
+	if !errors.Is(err, errSlow) {
+		t.Fatalf("expected slow cleanup error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= testShutdownTimeout {
+		t.Fatalf("CleanupTimeout not applied: took %v", elapsed)
+	}
+}
+
+func TestRunMetrics(t *testing.T) {
+	var got []graceful.ShutdownStats
+	_, cancel, done := startRun(t, http.DefaultServeMux, &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		Metrics:         graceful.MetricsFunc(func(s graceful.ShutdownStats) { got = append(got, s) }),
+		Cleanups: []func(context.Context) error{
+			func(context.Context) error { return errors.New("failed") },
+			func(context.Context) error { panic("boom") },
+			func(context.Context) error { return nil },
+		},
+	})
+
+	cancel()
+	_ = awaitShutdown(t, done)
+	if len(got) != 1 {
+		t.Fatalf("RecordShutdown called %d times, want 1", len(got))
+	}
+	s := got[0]
+	if s.CleanupFailures != 2 {
+		t.Errorf("CleanupFailures = %d, want 2", s.CleanupFailures)
+	}
+	if s.TimedOut {
+		t.Error("TimedOut = true, want false")
+	}
+	if s.Duration < s.DrainDuration+s.CleanupDuration {
+		t.Errorf("Duration %v shorter than drain %v + cleanup %v", s.Duration, s.DrainDuration, s.CleanupDuration)
+	}
+}
+
+func TestRunForceCloseAfterTimeout(t *testing.T) {
+	const shortTimeout = 50 * time.Millisecond
+
+	handlerStarted := make(chan struct{})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hang", func(w http.ResponseWriter, r *http.Request) {
+		close(handlerStarted)
+		<-release
+	})
+
+	addr, cancel, done := startRun(t, mux, &graceful.Config{
+		ShutdownTimeout:        shortTimeout,
+		ForceCloseAfterTimeout: true,
+	})
+
+	clientErr := make(chan error, 1)
+	go func() {
+		client := &http.Client{Timeout: testShutdownTimeout}
+		resp, err := client.Get("http://" + addr + "/hang")
+		if err == nil {
+			resp.Body.Close()
+		}
+		clientErr <- err
+	}()
+	<-handlerStarted
+
+	cancel()
+	if err := awaitShutdown(t, done); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	select {
+	case err := <-clientErr:
+		if err == nil {
+			t.Fatal("expected the hanging request to fail after force close")
+		}
+	case <-time.After(testStartTimeout):
+		t.Fatal("connection was not closed after the shutdown timeout")
+	}
+}
+
+func TestRunForceCloseGrace(t *testing.T) {
+	handlerStarted := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(handlerStarted)
+		<-r.Context().Done()
+		time.Sleep(50 * time.Millisecond) // wrapping up after cancellation
+		w.Write([]byte("partial"))
+	})
+
+	addr, cancel, done := startRun(t, mux, &graceful.Config{
+		ShutdownTimeout:        50 * time.Millisecond,
+		ForceCloseAfterTimeout: true,
+		ForceCloseGrace:        testStartTimeout,
+	})
+
+	type result struct {
+		body string
+		err  error
+	}
+	res := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			res <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		res <- result{string(body), err}
+	}()
+	<-handlerStarted
+
+	cancel()
+	if err := awaitShutdown(t, done); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if r := <-res; r.err != nil || r.body != "partial" {
+		t.Fatalf("request during grace = (%q, %v), want it to complete", r.body, r.err)
+	}
+}
+
+func TestRunDrainProgress(t *testing.T) {
+	handlerStarted := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(handlerStarted)
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	var (
+		mu     sync.Mutex
+		counts []int
+	)
+	addr, cancel, done := startRun(t, mux, &graceful.Config{
+		ShutdownTimeout:       testShutdownTimeout,
+		DrainProgressInterval: 10 * time.Millisecond,
+		DrainProgress: func(active int) {
+			mu.Lock()
+			defer mu.Unlock()
+			counts = append(counts, active)
+		},
+	})
+
+	go func() {
+		client := &http.Client{Timeout: testShutdownTimeout}
+		if resp, err := client.Get("http://" + addr + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-handlerStarted
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(counts) < 2 {
+		t.Fatalf("DrainProgress called %d times, want at least 2", len(counts))
+	}
+	if counts[0] < 1 {
+		t.Errorf("first DrainProgress count = %d, want at least 1", counts[0])
+	}
+}
+
+func TestRunShutdownReason(t *testing.T) {
+	errCause := errors.New("deploy")
+	tests := map[string]struct {
+		trigger func(cancel context.CancelCauseFunc, fake *signalx.Fake)
+		want    graceful.ShutdownReason
+	}{
+		"signal": {
+			trigger: func(_ context.CancelCauseFunc, fake *signalx.Fake) { fake.Send(syscall.SIGINT) },
+			want:    graceful.ShutdownReason{Trigger: graceful.TriggerSignal, Signal: syscall.SIGINT},
+		},
+		"context": {
+			trigger: func(cancel context.CancelCauseFunc, _ *signalx.Fake) { cancel(errCause) },
+			want:    graceful.ShutdownReason{Trigger: graceful.TriggerContext, Err: errCause},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var (
+				fake signalx.Fake
+				got  graceful.ShutdownReason
+			)
+			srv, addr := newTestServer(t, http.DefaultServeMux)
+			ctx, cancel := context.WithCancelCause(context.Background())
+			t.Cleanup(func() { cancel(nil) })
+			done := make(chan error, 1)
+			go func() {
+				done <- graceful.Run(ctx, srv, &graceful.Config{
+					Notifier: &fake,
+					Metrics:  graceful.MetricsFunc(func(s graceful.ShutdownStats) { got = s.Reason }),
+				})
+			}()
+			if err := waitForServer(addr, testStartTimeout); err != nil {
+				t.Fatal("server did not start in time:", err)
+			}
+
+			tt.trigger(cancel, &fake)
+			if err := awaitShutdown(t, done); err != nil {
+				t.Fatalf("expected nil error, got: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Reason = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunRestartPolicy(t *testing.T) {
+	errTransient := errors.New("accept: too many open files")
+	tests := map[string]struct {
+		failures  int
+		policy    *graceful.RestartPolicy
+		wantErr   error
+		wantCalls int
+	}{
+		"recovers": {
+			failures:  2,
+			policy:    &graceful.RestartPolicy{Backoff: time.Millisecond},
+			wantCalls: 3,
+		},
+		"gives up after MaxAttempts": {
+			failures:  5,
+			policy:    &graceful.RestartPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+			wantErr:   errTransient,
+			wantCalls: 3,
+		},
+		"not retryable": {
+			failures: 1,
+			policy: &graceful.RestartPolicy{
+				Backoff:   time.Millisecond,
+				Retryable: func(error) bool { return false },
+			},
+			wantErr:   errTransient,
+			wantCalls: 1,
+		},
+		"no policy": {
+			failures:  1,
+			wantErr:   errTransient,
+			wantCalls: 1,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var calls int
+			serving := make(chan struct{})
+			stopped := make(chan struct{})
+			srv := &controllableServer{
+				listenFunc: func() error {
+					calls++
+					if calls <= tt.failures {
+						return errTransient
+					}
+					close(serving)
+					<-stopped
+					return http.ErrServerClosed
+				},
+				shutdownFunc: func(context.Context) error {
+					close(stopped)
+					return nil
+				},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			done := make(chan error, 1)
+			go func() {
+				done <- graceful.Run(ctx, srv, &graceful.Config{RestartPolicy: tt.policy})
+			}()
+			if tt.wantErr == nil {
+				<-serving
+				cancel()
+			}
+
+			err := awaitShutdown(t, done)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Run() = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("ListenAndServe called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRunForceOnSecondSignal(t *testing.T) {
+	handlerStarted := make(chan struct{})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hang", func(w http.ResponseWriter, r *http.Request) {
+		close(handlerStarted)
+		<-release
+	})
+
+	var fake signalx.Fake
+	shutdownBegan := make(chan struct{})
+	var cleanupErr error
+	addr, _, done := startRun(t, mux, &graceful.Config{
+		ShutdownTimeout:     time.Minute,
+		ForceOnSecondSignal: true,
+		Notifier:            &fake,
+		OnShutdownBegin:     func() { close(shutdownBegan) },
+		Cleanups: []func(context.Context) error{
+			func(ctx context.Context) error { cleanupErr = ctx.Err(); return nil },
+		},
+	})
+
+	go func() {
+		client := &http.Client{Timeout: testShutdownTimeout}
+		if resp, err := client.Get("http://" + addr + "/hang"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-handlerStarted
+
+	fake.Send(syscall.SIGTERM)
+	<-shutdownBegan
+	fake.Send(syscall.SIGINT)
+
+	if err := awaitShutdown(t, done); !errors.Is(err, graceful.ErrForced) {
+		t.Fatalf("expected %v, got %v", graceful.ErrForced, err)
+	}
+	if cleanupErr == nil {
+		t.Fatal("expected cleanups to run with a cancelled context after a forced shutdown")
+	}
+}
+
+func TestRunCleanupAbandoned(t *testing.T) {
+	hung := make(chan struct{})
+	t.Cleanup(func() { close(hung) })
+	var secondRan bool
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := graceful.Run(ctx, newBenchmarkServer(), &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		CleanupTimeout:  20 * time.Millisecond,
+		Cleanups: []func(context.Context) error{
+			func(context.Context) error { <-hung; return nil }, // ignores its context
+			func(context.Context) error { secondRan = true; return nil },
+		},
+	})
+	if !errors.Is(err, graceful.ErrCleanupAbandoned) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v wrapping %v, got: %v", graceful.ErrCleanupAbandoned, context.DeadlineExceeded, err)
+	}
+	if !secondRan {
+		t.Fatal("expected the next cleanup to run after the hung one was abandoned")
+	}
+}
+
+func TestRunCleanupTimeoutClock(t *testing.T) {
+	hung := make(chan struct{})
+	t.Cleanup(func() { close(hung) })
+	clock := gracefultest.NewFakeClock(time.Now())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- graceful.Run(ctx, newBenchmarkServer(), &graceful.Config{
+			ShutdownTimeout: time.Hour,
+			CleanupTimeout:  time.Minute,
+			Clock:           clock,
+			Cleanups: []func(context.Context) error{
+				func(context.Context) error { <-hung; return nil }, // ignores its context
+			},
+		})
+	}()
+
+	// The shutdown deadline and the cleanup's timeout, then the shutdown
+	// deadline and the grace period before the cleanup is abandoned.
+	clock.BlockUntil(2)
+	clock.Advance(time.Minute)
+	clock.BlockUntil(2)
+	clock.Advance(time.Second)
+	err := awaitShutdown(t, done)
+	if !errors.Is(err, graceful.ErrCleanupAbandoned) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v wrapping %v, got: %v", graceful.ErrCleanupAbandoned, context.DeadlineExceeded, err)
+	}
+}
+
+func TestRunOnReload(t *testing.T) {
+	var fake signalx.Fake
+	reloaded := make(chan struct{}, 1)
+	_, cancel, done := startRun(t, http.DefaultServeMux, &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		Notifier:        &fake,
+		OnReload: func(context.Context) error {
+			reloaded <- struct{}{}
+			return nil
+		},
+	})
+
+	if !fake.Send(syscall.SIGHUP) {
+		t.Fatal("expected Run to listen for SIGHUP")
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(testStartTimeout):
+		t.Fatal("OnReload was not called")
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned after SIGHUP: %v", err)
+	default:
+	}
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+}
+
+func TestRunRequestContext(t *testing.T) {
+	const shortTimeout = 50 * time.Millisecond
+
+	handlerStarted := make(chan struct{})
+	sawDraining := make(chan struct{})
+	sawCancel := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/long", func(w http.ResponseWriter, r *http.Request) {
+		close(handlerStarted)
+		<-graceful.ShuttingDown(r.Context())
+		close(sawDraining)
+		<-r.Context().Done()
+		close(sawCancel)
+	})
+
+	addr, cancel, done := startRun(t, mux, &graceful.Config{ShutdownTimeout: shortTimeout})
+	go func() {
+		client := &http.Client{Timeout: testShutdownTimeout}
+		if resp, err := client.Get("http://" + addr + "/long"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-handlerStarted
+
+	cancel()
+	for name, ch := range map[string]chan struct{}{"ShuttingDown": sawDraining, "request context": sawCancel} {
+		select {
+		case <-ch:
+		case <-time.After(testStartTimeout):
+			t.Fatalf("%s not closed during shutdown", name)
+		}
+	}
+	_ = awaitShutdown(t, done)
+
+	if graceful.ShuttingDown(context.Background()) != nil {
+		t.Fatal("ShuttingDown(context.Background()) should be nil")
+	}
+}
+
+func TestRunStartupProbe(t *testing.T) {
+	errUnhealthy := errors.New("unhealthy")
+	tests := map[string]struct {
+		healthyAfter int // probe calls that fail first; -1 never succeeds
+		wantErr      error
+	}{
+		"becomes healthy": {healthyAfter: 2},
+		"never healthy":   {healthyAfter: -1, wantErr: graceful.ErrStartupTimeout},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var calls int
+			started := make(chan struct{})
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			done := make(chan error, 1)
+			go func() {
+				done <- graceful.Run(ctx, newBenchmarkServer(), &graceful.Config{
+					StartupTimeout: 300 * time.Millisecond,
+					StartupProbe: func(context.Context) error {
+						calls++
+						if tt.healthyAfter < 0 || calls <= tt.healthyAfter {
+							return errUnhealthy
+						}
+						return nil
+					},
+					OnStart: func() { close(started) },
+				})
+			}()
+
+			if tt.wantErr == nil {
+				select {
+				case <-started:
+				case <-time.After(testStartTimeout):
+					t.Fatal("OnStart not called after the probe succeeded")
+				}
+				cancel()
+			}
+			err := awaitShutdown(t, done)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("expected nil error, got: %v", err)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, errUnhealthy) {
+				t.Fatalf("expected the last probe error in %v", err)
+			}
+		})
+	}
+}
+
+func TestRunOnShutdownNotify(t *testing.T) {
+	streamStarted := make(chan struct{})
+	closeStreams := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		close(streamStarted)
+		<-closeStreams // a long-lived stream ends only when told to
+	})
+
+	addr, cancel, done := startRun(t, mux, &graceful.Config{
+		ShutdownTimeout:  testShutdownTimeout,
+		OnShutdownNotify: []func(){func() { close(closeStreams) }},
+	})
+	go func() {
+		client := &http.Client{Timeout: testShutdownTimeout}
+		if resp, err := client.Get("http://" + addr + "/events"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-streamStarted
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+}
+
+func TestRunEvents(t *testing.T) {
+	var (
+		fake   signalx.Fake
+		events recordingEvents
+	)
+	cfg := &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		Notifier:        &fake,
+		Events:          &events,
+		Cleanups: []func(context.Context) error{
+			func(context.Context) error { return nil },
+			func(context.Context) error { return errors.New("close failed") },
+		},
+	}
+	cfg.AddCleanup("cache", func(context.Context) error { return nil })
+	cfg.AddCleanup("queue", func(context.Context) error { return errors.New("flush failed") })
+	_, _, done := startRun(t, http.DefaultServeMux, cfg)
+
+	fake.Send(syscall.SIGTERM)
+	_ = awaitShutdown(t, done)
+
+	want := []string{
+		"started",
+		"triggered: signal terminated",
+		`cleanup "" #1 failed: close failed`,
+		`cleanup "queue" #1 failed: graceful: cleanup "queue": flush failed`,
+		"finished",
+	}
+	if !reflect.DeepEqual(events.got, want) {
+		t.Fatalf("events = %q, want %q", events.got, want)
+	}
+}
+
+func TestRunTracer(t *testing.T) {
+	var (
+		tracer  recordingTracer
+		cleanup any
+	)
+	_, cancel, done := startRun(t, http.DefaultServeMux, &graceful.Config{
+		ShutdownTimeout: testShutdownTimeout,
+		Tracer:          &tracer,
+		PreShutdown: []func(context.Context) error{
+			func(context.Context) error { return nil },
+		},
+		Cleanups: []func(context.Context) error{
+			func(ctx context.Context) error { cleanup = ctx.Value(spanKey{}); return nil },
+		},
+	})
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	want := []string{"graceful.pre_shutdown", "graceful.drain", "graceful.cleanup.0", "graceful.cleanups", "graceful.shutdown"}
+	if !reflect.DeepEqual(tracer.ended, want) {
+		t.Fatalf("spans = %q, want %q", tracer.ended, want)
+	}
+	if cleanup != "graceful.cleanup.0" {
+		t.Fatalf("cleanup context carries span %v, want %q", cleanup, "graceful.cleanup.0")
+	}
+}
+
+func TestRunWarmups(t *testing.T) {
+	var order []string
+	srv := newBenchmarkServer()
+	listen := srv.listenFunc
+	srv.listenFunc = func() error {
+		order = append(order, "serve")
+		return listen()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := graceful.Run(ctx, srv, &graceful.Config{
+		Warmups: []func(context.Context) error{
+			func(context.Context) error { order = append(order, "cache"); return nil },
+			func(context.Context) error { order = append(order, "pool"); return nil },
+		},
+		OnStart: cancel,
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if want := []string{"cache", "pool", "serve"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+}
+
+func TestRunWarmupError(t *testing.T) {
+	want := errors.New("cache unavailable")
+	served := false
+	srv := &controllableServer{listenFunc: func() error { served = true; return nil }}
+
+	err := graceful.Run(context.Background(), srv, &graceful.Config{
+		Warmups: []func(context.Context) error{
+			func(context.Context) error { return want },
+		},
+	})
+	if !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+	if served {
+		t.Fatal("expected the server not to start after a failed warmup")
+	}
+}
+
+func TestRunTasks(t *testing.T) {
+	t.Run("stopped on shutdown before cleanups", func(t *testing.T) {
+		var order []string
+		taskStarted := make(chan struct{})
+		_, cancel, done := startRun(t, http.DefaultServeMux, &graceful.Config{
+			ShutdownTimeout: testShutdownTimeout,
+			Tasks: []func(context.Context) error{
+				func(ctx context.Context) error {
+					close(taskStarted)
+					<-ctx.Done()
+					order = append(order, "task")
+					return ctx.Err()
+				},
+			},
+			Cleanups: []func(context.Context) error{
+				func(context.Context) error { order = append(order, "cleanup"); return nil },
+			},
+		})
+		<-taskStarted
+
+		cancel()
+		if err := awaitShutdown(t, done); err != nil {
+			t.Fatalf("expected nil error, got: %v", err)
+		}
+		if want := []string{"task", "cleanup"}; !reflect.DeepEqual(order, want) {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	})
+
+	t.Run("error triggers shutdown", func(t *testing.T) {
+		want := errors.New("consumer failed")
+		err := graceful.Run(context.Background(), newBenchmarkServer(), &graceful.Config{
+			Tasks: []func(context.Context) error{
+				func(context.Context) error { return want },
+			},
+		})
+		if !errors.Is(err, want) {
+			t.Fatalf("expected %v, got %v", want, err)
+		}
+	})
+
+	t.Run("error still runs cleanups", func(t *testing.T) {
+		want := errors.New("consumer failed")
+		cleanupRan := false
+		err := graceful.Run(context.Background(), newBenchmarkServer(), &graceful.Config{
+			Tasks: []func(context.Context) error{
+				func(context.Context) error { return want },
+			},
+			Cleanups: []func(context.Context) error{
+				func(context.Context) error { cleanupRan = true; return nil },
+			},
+		})
+		if !errors.Is(err, want) || err.Error() != want.Error() {
+			t.Fatalf("expected %v, got %v", want, err)
+		}
+		if !cleanupRan {
+			t.Fatal("cleanup did not run after a task failed")
+		}
+	})
+}
+
+func TestRunIgnoreServeErrors(t *testing.T) {
+	stopped := make(chan struct{})
+	srv := &controllableServer{
+		listenFunc: func() error {
+			<-stopped
+			return &net.OpError{Op: "accept", Err: net.ErrClosed}
+		},
+		shutdownFunc: func(context.Context) error {
+			close(stopped)
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := graceful.Run(ctx, srv, &graceful.Config{IgnoreServeErrors: []error{net.ErrClosed}})
+	if err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+}
+
+func TestRunPair(t *testing.T) {
+	var order []string
+	publicSrv, publicAddr := newTestServer(t, http.DefaultServeMux)
+	adminSrv, adminAddr := newTestServer(t, http.DefaultServeMux)
+	public := &wrappedServer{Server: publicSrv, onShutdown: func() { order = append(order, "public") }}
+	admin := &wrappedServer{Server: adminSrv, onShutdown: func() { order = append(order, "admin") }}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	done := make(chan error, 1)
+	go func() {
+		done <- graceful.RunPair(ctx, public, admin, &graceful.Config{
+			Cleanups: []func(context.Context) error{
+				func(context.Context) error {
+					// The admin server still answers during cleanups.
+					if err := waitForServer(adminAddr, testStartTimeout); err != nil {
+						return err
+					}
+					order = append(order, "cleanup")
+					return nil
+				},
+			},
+		})
+	}()
+	for _, addr := range []string{publicAddr, adminAddr} {
+		if err := waitForServer(addr, testStartTimeout); err != nil {
+			t.Fatal("server did not start in time:", err)
+		}
+	}
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if want := []string{"public", "cleanup", "admin"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+}
+
+func TestRunPairAdminError(t *testing.T) {
+	want := errors.New("admin: address already in use")
+	admin := &controllableServer{listenFunc: func() error { return want }}
+
+	err := graceful.RunPair(context.Background(), newBenchmarkServer(), admin, nil)
+	if !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
 	}
 }
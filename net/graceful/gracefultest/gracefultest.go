@@ -0,0 +1,134 @@
+// Package gracefultest provides utilities for testing code built on
+// net/graceful.
+//
+//	func TestShutdown(t *testing.T) {
+//	    addr, cancel, done := gracefultest.StartServer(t, mux, &graceful.Config{...})
+//	    resp, err := http.Get("http://" + addr + "/")
+//	    // ...
+//	    cancel()
+//	    if err := gracefultest.AwaitShutdown(t, done); err != nil {
+//	        t.Fatal(err)
+//	    }
+//	}
+package gracefultest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/graceful"
+)
+
+const (
+	// StartTimeout is how long StartServer waits for the server to answer.
+	StartTimeout = 2 * time.Second
+	// ShutdownTimeout is how long AwaitShutdown waits for Run to return.
+	ShutdownTimeout = 5 * time.Second
+)
+
+// ErrStartTimeout is returned by WaitForServer when the server does not
+// answer in time.
+var ErrStartTimeout = errors.New("gracefultest: server failed to start within timeout")
+
+// NewServer returns a graceful.Server serving handler on a free loopback
+// port, and the address it listens on. The listener is closed when the test
+// ends.
+func NewServer(t testing.TB, handler http.Handler) (graceful.Server, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return graceful.OnListener(&http.Server{Handler: handler}, ln), ln.Addr().String()
+}
+
+// StartServer runs handler with graceful.Run in a goroutine and waits until
+// it answers HTTP requests. Cancelling cancel triggers shutdown; done
+// receives Run's result. cancel is also called when the test ends, so a
+// failing test does not leak the server.
+func StartServer(t testing.TB, handler http.Handler, cfg *graceful.Config) (addr string, cancel context.CancelFunc, done <-chan error) {
+	t.Helper()
+	srv, addr := NewServer(t, handler)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	ch := make(chan error, 1)
+	go func() { ch <- graceful.Run(ctx, srv, cfg) }()
+	if err := WaitForServer(addr, StartTimeout); err != nil {
+		t.Fatal("server did not start in time:", err)
+	}
+	return addr, cancel, ch
+}
+
+// WaitForServer polls addr with HTTP requests until one succeeds or timeout
+// elapses.
+func WaitForServer(addr string, timeout time.Duration) error {
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		resp, err := client.Get("http://" + addr + "/")
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return ErrStartTimeout
+}
+
+// AwaitShutdown waits up to ShutdownTimeout for Run's result on done and
+// fails the test if it does not arrive.
+func AwaitShutdown(t testing.TB, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(ShutdownTimeout):
+		t.Fatal("server did not shut down in time")
+		return nil
+	}
+}
+
+// Server is a fake graceful.Server whose behaviour is injected through its
+// fields. The zero value serves until Shutdown is called and then returns
+// http.ErrServerClosed, like an idle *http.Server.
+type Server struct {
+	// ListenFunc, if set, replaces ListenAndServe.
+	ListenFunc func() error
+
+	// ShutdownFunc, if set, replaces Shutdown.
+	ShutdownFunc func(ctx context.Context) error
+
+	once     sync.Once
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func (s *Server) stopped() chan struct{} {
+	s.once.Do(func() { s.done = make(chan struct{}) })
+	return s.done
+}
+
+// ListenAndServe calls ListenFunc, or blocks until Shutdown is called.
+func (s *Server) ListenAndServe() error {
+	if s.ListenFunc != nil {
+		return s.ListenFunc()
+	}
+	<-s.stopped()
+	return http.ErrServerClosed
+}
+
+// Shutdown calls ShutdownFunc, or makes ListenAndServe return.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.ShutdownFunc != nil {
+		return s.ShutdownFunc(ctx)
+	}
+	done := s.stopped()
+	s.stopOnce.Do(func() { close(done) })
+	return nil
+}
@@ -0,0 +1,81 @@
+package gracefultest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/graceful"
+	"github.com/rin2yh/gouse/net/graceful/gracefultest"
+)
+
+func TestStartServer(t *testing.T) {
+	addr, cancel, done := gracefultest.StartServer(t, http.NotFoundHandler(), nil)
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	cancel()
+	if err := gracefultest.AwaitShutdown(t, done); err != nil {
+		t.Fatalf("AwaitShutdown() = %v, want nil", err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	t.Run("zero value serves until shutdown", func(t *testing.T) {
+		var srv gracefultest.Server
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := graceful.Run(ctx, &srv, nil); err != nil {
+			t.Fatalf("Run() = %v, want nil", err)
+		}
+	})
+
+	t.Run("injected errors", func(t *testing.T) {
+		errListen := errors.New("bind failed")
+		srv := &gracefultest.Server{ListenFunc: func() error { return errListen }}
+		if err := graceful.Run(context.Background(), srv, nil); !errors.Is(err, errListen) {
+			t.Fatalf("Run() = %v, want %v", err, errListen)
+		}
+	})
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := gracefultest.NewFakeClock(start)
+	timer := clock.NewTimer(time.Second)
+	stopped := clock.NewTimer(time.Second)
+	stopped.Stop()
+
+	clock.Advance(time.Second - 1)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired before its deadline")
+	default:
+	}
+
+	clock.Advance(1)
+	select {
+	case now := <-timer.C():
+		if want := start.Add(time.Second); !now.Equal(want) {
+			t.Fatalf("timer fired at %v, want %v", now, want)
+		}
+	default:
+		t.Fatal("timer did not fire at its deadline")
+	}
+	select {
+	case <-stopped.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+	if got := clock.Now(); !got.Equal(start.Add(time.Second)) {
+		t.Fatalf("Now() = %v, want %v", got, start.Add(time.Second))
+	}
+}
@@ -0,0 +1,209 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/rin2yh/gouse/signalx"
+)
+
+// Runner is a long-running component that is not an HTTP server, such as a
+// queue consumer or a background scheduler. Run must block until ctx is
+// cancelled and then return; returning context.Canceled at that point is
+// treated as a clean stop.
+type Runner interface {
+	Run(ctx context.Context) error
+}
+
+// RunnerFunc adapts an ordinary function to the Runner interface.
+type RunnerFunc func(ctx context.Context) error
+
+// Run calls f(ctx).
+func (f RunnerFunc) Run(ctx context.Context) error { return f(ctx) }
+
+// Group manages a mix of servers, runners and cleanups as one unit. Start
+// starts every server and runner, and on shutdown tears the components down
+// one at a time in reverse registration order, so a component can rely on
+// everything registered before it until it has stopped.
+//
+//	g := graceful.NewGroup(nil)
+//	g.AddCleanup(func(ctx context.Context) error { return db.Close() })
+//	g.AddRunner(consumer)
+//	g.Add(srv)
+//	if err := g.Start(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// A Group is not safe for concurrent registration and must not be started
+// more than once.
+type Group struct {
+	lc         lifecycle
+	cfg        *Config
+	components []component
+}
+
+// component is one registered unit; exactly one field is set.
+type component struct {
+	srv     Server
+	runner  Runner
+	cleanup func(context.Context) error
+}
+
+// NewGroup returns an empty Group. Of cfg, the ShutdownTimeout,
+// RestartPolicy, IgnoreServeErrors, Readiness, Logger, Events, Notifier,
+// Signals and Clock fields are used; the shutdown timeout bounds the whole
+// teardown. If cfg is nil, the defaults of Run apply.
+func NewGroup(cfg *Config) *Group {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	return &Group{cfg: cfg}
+}
+
+// Add registers a server.
+func (g *Group) Add(srv Server) {
+	g.components = append(g.components, component{srv: srv})
+}
+
+// AddRunner registers a runner. It is stopped by cancelling its context.
+func (g *Group) AddRunner(r Runner) {
+	g.components = append(g.components, component{runner: r})
+}
+
+// AddCleanup registers functions to call during teardown, at their position
+// in the reverse registration order.
+func (g *Group) AddCleanup(fns ...func(context.Context) error) {
+	for _, fn := range fns {
+		g.components = append(g.components, component{cleanup: fn})
+	}
+}
+
+// Start starts every server and runner and blocks until SIGINT/SIGTERM is
+// received, ctx is cancelled or a component stops on its own. It then stops
+// the servers and runners and calls the cleanups in reverse registration
+// order. A failure in one component does not prevent the rest of the
+// teardown. The returned error joins every error encountered.
+func (g *Group) Start(parent context.Context) error {
+	cfg := g.cfg
+	ctx, stop := signalx.NotifyContext(parent, cfg.Notifier, cfg.signals()...)
+	defer stop()
+
+	// Runners get a context of their own, so they keep running until their
+	// turn in the teardown rather than as soon as ctx is cancelled.
+	srvs := make([]Server, len(g.components))
+	var running []Server
+	for i, c := range g.components {
+		switch {
+		case c.srv != nil:
+			srvs[i] = c.srv
+		case c.runner != nil:
+			srvs[i] = newRunnerServer(context.WithoutCancel(ctx), c.runner)
+		default:
+			continue
+		}
+		running = append(running, srvs[i])
+	}
+
+	stopping := make(chan struct{})
+	serverErr := serveAll(running, cfg, stopping)
+	cfg.events().ServerStarted(len(running))
+	g.lc.set(Running)
+
+	pending := len(running)
+	var startErr error
+	for startErr == nil && pending > 0 && ctx.Err() == nil {
+		select {
+		case startErr = <-serverErr:
+			pending--
+		case <-ctx.Done():
+		}
+	}
+	close(stopping)
+	g.lc.set(Draining)
+	cfg.events().ShutdownTriggered(shutdownReason(ctx, startErr))
+	begin := cfg.clock().Now()
+	cfg.Readiness.setDraining()
+
+	timeout := defaultShutdownTimeout
+	if cfg.ShutdownTimeout > 0 {
+		timeout = cfg.ShutdownTimeout
+	}
+	shutdownCtx, cancel := cfg.withTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	errs := []error{startErr}
+	for i := len(g.components) - 1; i >= 0; i-- {
+		var err error
+		if srvs[i] != nil {
+			err = srvs[i].Shutdown(shutdownCtx)
+		} else {
+			err = call(shutdownCtx, g.components[i].cleanup)
+		}
+		if err != nil {
+			cfg.log(slog.LevelError, "component stop failed", slog.Int("index", i), slog.Any("error", err))
+		}
+		errs = append(errs, err)
+	}
+	for ; pending > 0; pending-- {
+		errs = append(errs, <-serverErr)
+	}
+
+	err := errors.Join(errs...)
+	cfg.events().ShutdownFinished(err, cfg.since(begin))
+	g.lc.finish(err)
+	return err
+}
+
+// State returns the current lifecycle state. It is Starting until Start
+// has started every component.
+func (g *Group) State() State {
+	return g.lc.get()
+}
+
+// runnerServer adapts a Runner to the Server interface: ListenAndServe runs
+// it and Shutdown cancels its context and waits for it to return.
+type runnerServer struct {
+	r      Runner
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	done chan struct{} // closed when the current Run returns
+}
+
+func newRunnerServer(ctx context.Context, r Runner) *runnerServer {
+	ctx, cancel := context.WithCancel(ctx)
+	return &runnerServer{r: r, ctx: ctx, cancel: cancel}
+}
+
+func (s *runnerServer) ListenAndServe() error {
+	done := make(chan struct{})
+	defer close(done)
+	s.mu.Lock()
+	s.done = done
+	s.mu.Unlock()
+
+	err := s.r.Run(s.ctx)
+	if s.ctx.Err() != nil && errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+func (s *runnerServer) Shutdown(ctx context.Context) error {
+	s.cancel()
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,111 @@
+package graceful_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/graceful"
+	"github.com/rin2yh/gouse/net/graceful/gracefultest"
+)
+
+func TestGroup(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, s)
+	}
+
+	srv, addr := newTestServer(t, http.DefaultServeMux)
+	started := make(chan struct{})
+	g := graceful.NewGroup(&graceful.Config{ShutdownTimeout: testShutdownTimeout})
+	g.AddCleanup(func(context.Context) error { record("db"); return nil })
+	g.AddRunner(graceful.RunnerFunc(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		record("consumer")
+		return ctx.Err()
+	}))
+	g.Add(&wrappedServer{Server: srv, onShutdown: func() { record("http") }})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	done := make(chan error, 1)
+	go func() { done <- g.Start(ctx) }()
+	<-started
+	if err := waitForServer(addr, testStartTimeout); err != nil {
+		t.Fatal("server did not start in time:", err)
+	}
+	if got := g.State(); got != graceful.Running {
+		t.Fatalf("State() = %v, want %v", got, graceful.Running)
+	}
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if got := g.State(); got != graceful.Stopped {
+		t.Fatalf("State() = %v, want %v", got, graceful.Stopped)
+	}
+	if want := []string{"http", "consumer", "db"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("teardown order = %v, want %v", order, want)
+	}
+}
+
+func TestGroupClock(t *testing.T) {
+	clock := gracefultest.NewFakeClock(time.Now())
+	var took time.Duration
+	g := graceful.NewGroup(&graceful.Config{
+		ShutdownTimeout: time.Hour,
+		Clock:           clock,
+		Events:          finishedEvents{took: &took},
+	})
+	g.AddCleanup(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan error, 1)
+	go func() { done <- g.Start(ctx) }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	if err := awaitShutdown(t, done); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got: %v", context.DeadlineExceeded, err)
+	}
+	if took != time.Hour {
+		t.Fatalf("shutdown took %v on the clock, want %v", took, time.Hour)
+	}
+}
+
+// finishedEvents records the duration passed to ShutdownFinished.
+type finishedEvents struct {
+	graceful.NopEvents
+	took *time.Duration
+}
+
+func (e finishedEvents) ShutdownFinished(_ error, took time.Duration) { *e.took = took }
+
+func TestGroupRunnerError(t *testing.T) {
+	want := errors.New("consumer failed")
+	cleaned := false
+	g := graceful.NewGroup(nil)
+	g.AddCleanup(func(context.Context) error { cleaned = true; return nil })
+	g.AddRunner(graceful.RunnerFunc(func(context.Context) error { return want }))
+
+	if err := g.Start(context.Background()); !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+	if !cleaned {
+		t.Fatal("expected cleanup to run after runner failure")
+	}
+}
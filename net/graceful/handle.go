@@ -0,0 +1,95 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrStopped is the cancellation cause reported in ShutdownStats.Reason
+// when shutdown was triggered by Handle.Stop.
+var ErrStopped = errors.New("graceful: stopped by Handle.Stop")
+
+// Handle controls servers started by Start or StartAll.
+type Handle struct {
+	lc       lifecycle
+	handlers []*swapHandler
+	cancel   context.CancelCauseFunc
+	done     chan struct{}
+	err      error
+}
+
+// Start is like Run but returns immediately with a Handle instead of
+// blocking, for callers that supervise the server lifecycle themselves.
+func Start(parent context.Context, srv Server, cfg *Config) *Handle {
+	return StartAll(parent, []Server{srv}, cfg)
+}
+
+// StartAll is like RunAll but returns immediately with a Handle.
+//
+// The Handler of each *http.Server among srvs is wrapped so that
+// Handle.SwapHandler can replace it.
+func StartAll(parent context.Context, srvs []Server, cfg *Config) *Handle {
+	ctx, cancel := context.WithCancelCause(parent)
+	h := &Handle{cancel: cancel, done: make(chan struct{}), handlers: installSwapHandlers(srvs)}
+	go func() {
+		defer close(h.done)
+		defer cancel(nil)
+		h.err = runAll(ctx, srvs, cfg, &h.lc)
+	}()
+	return h
+}
+
+// Stop triggers shutdown and waits for it to complete or for ctx to be
+// done, whichever happens first. It returns the same error as Wait, or
+// ctx's error if ctx ended first; shutdown then carries on in the
+// background.
+func (h *Handle) Stop(ctx context.Context) error {
+	h.cancel(ErrStopped)
+	select {
+	case <-h.done:
+		return h.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait blocks until shutdown has completed and returns the error that Run
+// would have returned.
+func (h *Handle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// Done returns a channel that is closed once shutdown has completed.
+func (h *Handle) Done() <-chan struct{} {
+	return h.done
+}
+
+// State returns the current lifecycle state.
+func (h *Handle) State() State {
+	return h.lc.get()
+}
+
+// Reason returns what started the shutdown: a signal, cancellation of the
+// parent context (with its cause), Stop (a TriggerContext reason whose Err
+// is ErrStopped), a server error or every server stopping on its own. It
+// reports false until shutdown has been triggered. Use it after Wait to
+// tell why a server that returned nil stopped.
+func (h *Handle) Reason() (ShutdownReason, bool) {
+	return h.lc.shutdownReason()
+}
+
+// SwapHandler replaces the handler of every *http.Server managed by h,
+// without restarting the listeners; requests already being served finish
+// with the old handler. A nil handler means http.DefaultServeMux. It
+// returns ErrNoHTTPServer if h manages no *http.Server.
+func (h *Handle) SwapHandler(handler http.Handler) error {
+	if len(h.handlers) == 0 {
+		return ErrNoHTTPServer
+	}
+	for _, sh := range h.handlers {
+		sh.store(handler)
+	}
+	return nil
+}
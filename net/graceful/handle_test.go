@@ -0,0 +1,214 @@
+package graceful_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/graceful"
+	"github.com/rin2yh/gouse/signalx"
+)
+
+func TestStart(t *testing.T) {
+	srv, addr := newTestServer(t, http.DefaultServeMux)
+	var reason graceful.ShutdownReason
+	h := graceful.Start(context.Background(), srv, &graceful.Config{
+		Metrics: graceful.MetricsFunc(func(s graceful.ShutdownStats) { reason = s.Reason }),
+	})
+	if err := waitForServer(addr, testStartTimeout); err != nil {
+		t.Fatal("server did not start in time:", err)
+	}
+	select {
+	case <-h.Done():
+		t.Fatal("Done closed before Stop")
+	default:
+	}
+	if got := h.State(); got != graceful.Running {
+		t.Fatalf("State() = %v, want %v", got, graceful.Running)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testShutdownTimeout)
+	defer cancel()
+	if err := h.Stop(ctx); err != nil {
+		t.Fatalf("Stop() = %v, want nil", err)
+	}
+	select {
+	case <-h.Done():
+	default:
+		t.Fatal("Done not closed after Stop returned")
+	}
+	if err := h.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+	if got := h.State(); got != graceful.Stopped {
+		t.Fatalf("State() = %v, want %v", got, graceful.Stopped)
+	}
+	if !errors.Is(reason.Err, graceful.ErrStopped) {
+		t.Fatalf("Reason.Err = %v, want %v", reason.Err, graceful.ErrStopped)
+	}
+}
+
+func TestStartServerError(t *testing.T) {
+	want := errors.New("listen tcp: bind: address already in use")
+	h := graceful.Start(context.Background(), &controllableServer{listenFunc: func() error { return want }}, nil)
+
+	select {
+	case <-h.Done():
+	case <-time.After(testShutdownTimeout):
+		t.Fatal("Done not closed after startup failure")
+	}
+	if err := h.Wait(); !errors.Is(err, want) {
+		t.Fatalf("Wait() = %v, want %v", err, want)
+	}
+	if got := h.State(); got != graceful.Failed {
+		t.Fatalf("State() = %v, want %v", got, graceful.Failed)
+	}
+}
+
+func TestHandleSwapHandler(t *testing.T) {
+	respond := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, body) })
+	}
+	srv, addr := newTestServer(t, respond("v1"))
+	h := graceful.Start(context.Background(), srv, nil)
+	t.Cleanup(func() { h.Stop(context.Background()) })
+	if err := waitForServer(addr, testStartTimeout); err != nil {
+		t.Fatal("server did not start in time:", err)
+	}
+
+	get := func() string {
+		t.Helper()
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+	if got := get(); got != "v1" {
+		t.Fatalf("before swap: body = %q, want %q", got, "v1")
+	}
+	if err := h.SwapHandler(respond("v2")); err != nil {
+		t.Fatalf("SwapHandler() error: %v", err)
+	}
+	if got := get(); got != "v2" {
+		t.Fatalf("after swap: body = %q, want %q", got, "v2")
+	}
+
+	other := graceful.Start(context.Background(), newBenchmarkServer(), nil)
+	defer other.Stop(context.Background())
+	if err := other.SwapHandler(respond("v2")); !errors.Is(err, graceful.ErrNoHTTPServer) {
+		t.Fatalf("SwapHandler() without *http.Server = %v, want %v", err, graceful.ErrNoHTTPServer)
+	}
+}
+
+func TestHealthHandlers(t *testing.T) {
+	srv, addr := newTestServer(t, http.DefaultServeMux)
+	draining := make(chan struct{})
+	release := make(chan struct{})
+	h := graceful.Start(context.Background(), srv, &graceful.Config{
+		PreShutdown: []func(context.Context) error{func(context.Context) error {
+			close(draining)
+			<-release
+			return nil
+		}},
+	})
+	livez, readyz := graceful.HealthHandlers(h)
+	probe := func(handler http.Handler) (int, string) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code, rec.Body.String()
+	}
+	check := func(phase string, wantLive, wantReady int) {
+		t.Helper()
+		if code, body := probe(livez); code != wantLive {
+			t.Errorf("%s: livez = %d %q, want %d", phase, code, body, wantLive)
+		}
+		if code, body := probe(readyz); code != wantReady {
+			t.Errorf("%s: readyz = %d %q, want %d", phase, code, body, wantReady)
+		}
+	}
+
+	if err := waitForServer(addr, testStartTimeout); err != nil {
+		t.Fatal("server did not start in time:", err)
+	}
+	check("running", http.StatusOK, http.StatusOK)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- h.Stop(context.Background()) }()
+	<-draining
+	check("draining", http.StatusOK, http.StatusServiceUnavailable)
+
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Stop() = %v, want nil", err)
+	}
+	check("stopped", http.StatusOK, http.StatusServiceUnavailable)
+}
+
+func TestHandleReason(t *testing.T) {
+	errCause := errors.New("deploy")
+	errListen := errors.New("listen tcp: bind: address already in use")
+	tests := map[string]struct {
+		// srv, if set, replaces a test server that runs until shutdown.
+		srv     graceful.Server
+		trigger func(h *graceful.Handle, cancel context.CancelCauseFunc, fake *signalx.Fake)
+		want    graceful.ShutdownReason
+	}{
+		"signal": {
+			trigger: func(_ *graceful.Handle, _ context.CancelCauseFunc, fake *signalx.Fake) { fake.Send(syscall.SIGTERM) },
+			want:    graceful.ShutdownReason{Trigger: graceful.TriggerSignal, Signal: syscall.SIGTERM},
+		},
+		"context": {
+			trigger: func(_ *graceful.Handle, cancel context.CancelCauseFunc, _ *signalx.Fake) { cancel(errCause) },
+			want:    graceful.ShutdownReason{Trigger: graceful.TriggerContext, Err: errCause},
+		},
+		"stop": {
+			trigger: func(h *graceful.Handle, _ context.CancelCauseFunc, _ *signalx.Fake) { h.Stop(context.Background()) },
+			want:    graceful.ShutdownReason{Trigger: graceful.TriggerContext, Err: graceful.ErrStopped},
+		},
+		"server error": {
+			srv:  &controllableServer{listenFunc: func() error { return errListen }},
+			want: graceful.ShutdownReason{Trigger: graceful.TriggerServerError, Err: errListen},
+		},
+		"servers stopped": {
+			srv:  &controllableServer{listenFunc: func() error { return http.ErrServerClosed }},
+			want: graceful.ShutdownReason{Trigger: graceful.TriggerServersStopped},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var fake signalx.Fake
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+			cfg := &graceful.Config{ShutdownTimeout: testShutdownTimeout, Notifier: &fake}
+
+			var h *graceful.Handle
+			if tt.srv != nil {
+				h = graceful.Start(ctx, tt.srv, cfg)
+			} else {
+				srv, addr := newTestServer(t, http.DefaultServeMux)
+				h = graceful.Start(ctx, srv, cfg)
+				if err := waitForServer(addr, testStartTimeout); err != nil {
+					t.Fatal("server did not start in time:", err)
+				}
+				if r, ok := h.Reason(); ok {
+					t.Fatalf("Reason() before shutdown = %v, true; want false", r)
+				}
+				tt.trigger(h, cancel, &fake)
+			}
+			h.Wait()
+
+			got, ok := h.Reason()
+			if !ok || got.Trigger != tt.want.Trigger || got.Signal != tt.want.Signal || !errors.Is(got.Err, tt.want.Err) {
+				t.Fatalf("Reason() = %v, %v; want %v, true", got, ok, tt.want)
+			}
+		})
+	}
+}
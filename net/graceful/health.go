@@ -0,0 +1,40 @@
+package graceful
+
+import "net/http"
+
+// HealthHandlers returns liveness and readiness probe handlers driven by the
+// lifecycle state of h:
+//
+//   - livez responds 200 OK in every state but Failed, so an orchestrator
+//     does not kill the process while it drains.
+//   - readyz responds 200 OK only while h is Running and 503 Service
+//     Unavailable while starting, draining and after shutdown, so load
+//     balancers stop routing new traffic as soon as shutdown begins.
+//
+// Both write the current state as the response body. An http.ServeMux
+// accepts registrations while serving, so the handlers can be mounted on
+// the server h manages:
+//
+//	h := graceful.Start(ctx, srv, nil)
+//	livez, readyz := graceful.HealthHandlers(h)
+//	mux.Handle("/livez", livez)
+//	mux.Handle("/readyz", readyz)
+func HealthHandlers(h *Handle) (livez, readyz http.Handler) {
+	livez = stateHandler(h, func(s State) bool { return s != Failed })
+	readyz = stateHandler(h, func(s State) bool { return s == Running })
+	return livez, readyz
+}
+
+// stateHandler responds 200 OK when healthy reports true for h's state and
+// 503 Service Unavailable otherwise.
+func stateHandler(h *Handle, healthy func(State) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		s := h.State()
+		if !healthy(s) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write([]byte(s.String() + "\n"))
+	})
+}
@@ -0,0 +1,46 @@
+package graceful
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// listenerServer serves an *http.Server on a pre-bound listener, over TLS
+// if tls is set.
+type listenerServer struct {
+	srv               *http.Server
+	ln                net.Listener
+	tls               bool
+	certFile, keyFile string
+}
+
+func (s *listenerServer) ListenAndServe() error {
+	if s.tls {
+		return s.srv.ServeTLS(s.ln, s.certFile, s.keyFile)
+	}
+	return s.srv.Serve(s.ln)
+}
+
+func (s *listenerServer) Shutdown(ctx context.Context) error { return s.srv.Shutdown(ctx) }
+func (s *listenerServer) Close() error                       { return s.srv.Close() }
+
+// OnListener returns a Server that serves srv on ln instead of listening on
+// srv.Addr. Binding the listener up front avoids the race between picking a
+// free port and listening on it, and supports listeners obtained elsewhere
+// (e.g. socket activation). The listener is closed by Shutdown.
+func OnListener(srv *http.Server, ln net.Listener) Server {
+	return &listenerServer{srv: srv, ln: ln}
+}
+
+// OnListenerTLS is like OnListener but serves HTTPS, as
+// srv.ServeTLS(ln, certFile, keyFile) does (including HTTP/2). certFile and
+// keyFile may be empty if srv.TLSConfig provides the certificate.
+func OnListenerTLS(srv *http.Server, ln net.Listener, certFile, keyFile string) Server {
+	return &listenerServer{srv: srv, ln: ln, tls: true, certFile: certFile, keyFile: keyFile}
+}
+
+// RunListener is like Run but serves srv on ln. See OnListener.
+func RunListener(parent context.Context, srv *http.Server, ln net.Listener, cfg *Config) error {
+	return Run(parent, OnListener(srv, ln), cfg)
+}
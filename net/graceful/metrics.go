@@ -0,0 +1,40 @@
+package graceful
+
+import "time"
+
+// ShutdownStats summarises a completed shutdown.
+type ShutdownStats struct {
+	// Reason is what started the shutdown.
+	Reason ShutdownReason
+
+	// Duration is the total time from the shutdown trigger until the
+	// cleanups finished.
+	Duration time.Duration
+
+	// DrainDuration is the time spent in Shutdown waiting for in-flight
+	// requests.
+	DrainDuration time.Duration
+
+	// TimedOut reports whether Shutdown hit the shutdown deadline.
+	TimedOut bool
+
+	// CleanupDuration is the time spent running the cleanups.
+	CleanupDuration time.Duration
+
+	// CleanupFailures is the number of cleanups that returned an error or
+	// panicked.
+	CleanupFailures int
+}
+
+// Metrics receives shutdown measurements, so they can be exported to
+// Prometheus, OpenTelemetry or similar without this package depending on
+// them.
+type Metrics interface {
+	RecordShutdown(ShutdownStats)
+}
+
+// MetricsFunc adapts an ordinary function to the Metrics interface.
+type MetricsFunc func(ShutdownStats)
+
+// RecordShutdown calls f(s).
+func (f MetricsFunc) RecordShutdown(s ShutdownStats) { f(s) }
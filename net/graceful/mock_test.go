@@ -2,23 +2,16 @@ package graceful_test
 
 import (
 	"context"
-	"net"
+	"fmt"
 	"net/http"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/rin2yh/gouse/net/graceful"
+	"github.com/rin2yh/gouse/net/graceful/gracefultest"
 )
 
-// listenerServer wraps *http.Server with a pre-bound listener to avoid the
-// TOCTOU race between acquiring a free port and calling ListenAndServe.
-type listenerServer struct {
-	srv *http.Server
-	ln  net.Listener
-}
-
-func (s *listenerServer) ListenAndServe() error              { return s.srv.Serve(s.ln) }
-func (s *listenerServer) Shutdown(ctx context.Context) error { return s.srv.Shutdown(ctx) }
-
 // controllableServer injects arbitrary ListenAndServe / Shutdown behaviour.
 type controllableServer struct {
 	listenFunc   func() error
@@ -35,10 +28,62 @@ func (s *controllableServer) Shutdown(ctx context.Context) error {
 
 func newTestServer(t *testing.T, handler http.Handler) (graceful.Server, string) {
 	t.Helper()
-	ln, err := net.Listen("tcp", "127.0.0.1:0")
-	if err != nil {
-		t.Fatal(err)
-	}
-	t.Cleanup(func() { ln.Close() })
-	return &listenerServer{srv: &http.Server{Handler: handler}, ln: ln}, ln.Addr().String()
+	return gracefultest.NewServer(t, handler)
+}
+
+// wrappedServer calls onShutdown before delegating Shutdown.
+type wrappedServer struct {
+	graceful.Server
+	onShutdown func()
+}
+
+func (s *wrappedServer) Shutdown(ctx context.Context) error {
+	s.onShutdown()
+	return s.Server.Shutdown(ctx)
+}
+
+// recordingEvents records the events it receives.
+type recordingEvents struct {
+	graceful.NopEvents
+	got []string
+}
+
+func (e *recordingEvents) ServerStarted(n int) {
+	e.got = append(e.got, "started")
+}
+
+func (e *recordingEvents) ShutdownTriggered(r graceful.ShutdownReason) {
+	e.got = append(e.got, "triggered: "+r.String())
+}
+
+func (e *recordingEvents) CleanupFailed(name string, i int, err error) {
+	e.got = append(e.got, fmt.Sprintf("cleanup %q #%d failed: %v", name, i, err))
+}
+
+func (e *recordingEvents) ShutdownFinished(err error, _ time.Duration) {
+	e.got = append(e.got, "finished")
+}
+
+// recordingTracer records the names of the spans it starts, in the order
+// they end.
+type recordingTracer struct {
+	mu    sync.Mutex
+	ended []string
+}
+
+type spanKey struct{}
+
+type recordingSpan struct {
+	t    *recordingTracer
+	name string
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, graceful.Span) {
+	return context.WithValue(ctx, spanKey{}, name), &recordingSpan{t, name}
+}
+
+func (s *recordingSpan) End(error) {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+	s.t.ended = append(s.t.ended, s.name)
 }
@@ -0,0 +1,43 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// RunPair is like Run for a public server with an admin server (metrics,
+// health checks) next to it. Both servers start together, without waiting
+// for either to be listening; the admin server is shut down last, after the
+// public server has drained and the cleanups have run, so /metrics and
+// /healthz stay reachable for the whole shutdown. If the admin
+// server fails, the public one is shut down and the error is returned.
+//
+// The admin server gets its own ShutdownTimeout once Run has returned.
+func RunPair(parent context.Context, public, admin Server, cfg *Config) error {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	ctx, cancel := context.WithCancelCause(parent)
+	defer cancel(nil)
+
+	adminErr := make(chan error, 1)
+	go func() {
+		err := serve(admin, cfg, ctx.Done())
+		if err != nil {
+			err = fmt.Errorf("graceful: admin server: %w", err)
+			cancel(err)
+		}
+		adminErr <- err
+	}()
+
+	err := Run(ctx, public, cfg)
+
+	timeout := defaultShutdownTimeout
+	if cfg.ShutdownTimeout > 0 {
+		timeout = cfg.ShutdownTimeout
+	}
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.WithoutCancel(parent), timeout)
+	defer cancelShutdown()
+	return errors.Join(err, admin.Shutdown(shutdownCtx), <-adminErr)
+}
@@ -0,0 +1,155 @@
+// Package prometheus exposes net/graceful lifecycle metrics in the
+// Prometheus text exposition format, without depending on the Prometheus
+// client library.
+//
+//	c := prometheus.NewCollector("myapp")
+//	cfg := c.Attach(&graceful.Config{})
+//	mux.Handle("/metrics/graceful", c)
+//	err := graceful.Run(ctx, srv, cfg)
+package prometheus
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rin2yh/gouse/internal/promtext"
+	"github.com/rin2yh/gouse/net/graceful"
+)
+
+// states lists every lifecycle state, in exposition order.
+var states = []graceful.State{graceful.Starting, graceful.Running, graceful.Draining, graceful.Stopped, graceful.Failed}
+
+// Collector tracks the lifecycle of a graceful.Run call and serves it as
+// Prometheus metrics:
+//
+//	<ns>_graceful_state{state="..."}                gauge, 1 for the current state
+//	<ns>_graceful_start_time_seconds               gauge, Unix time the servers started
+//	<ns>_graceful_uptime_seconds                   gauge, time since the servers started
+//	<ns>_graceful_shutdown_duration_seconds        gauge, duration of the last shutdown
+//	<ns>_graceful_drain_duration_seconds           gauge, time the last shutdown spent draining
+//	<ns>_graceful_shutdowns_total                  counter
+//	<ns>_graceful_shutdown_timeouts_total          counter
+//	<ns>_graceful_cleanup_failures_total           counter
+//
+// Collector implements graceful.Metrics and http.Handler.
+type Collector struct {
+	prefix string
+	now    func() time.Time
+
+	mu               sync.Mutex
+	state            graceful.State
+	started          time.Time
+	shutdownDuration time.Duration
+	drainDuration    time.Duration
+	shutdowns        uint64
+	timeouts         uint64
+	cleanupFailures  uint64
+}
+
+// NewCollector returns a Collector whose metric names are prefixed with
+// namespace and an underscore; an empty namespace means no prefix.
+func NewCollector(namespace string) *Collector {
+	prefix := ""
+	if namespace != "" {
+		prefix = namespace + "_"
+	}
+	return &Collector{prefix: prefix, now: time.Now}
+}
+
+// Attach sets c as cfg.Metrics and chains onto cfg.OnStart,
+// cfg.OnShutdownBegin and cfg.OnShutdownDone to follow the lifecycle
+// state. It returns cfg; a nil cfg is replaced by a new Config.
+func (c *Collector) Attach(cfg *graceful.Config) *graceful.Config {
+	if cfg == nil {
+		cfg = &graceful.Config{}
+	}
+	cfg.Metrics = c
+
+	onStart, onBegin, onDone := cfg.OnStart, cfg.OnShutdownBegin, cfg.OnShutdownDone
+	cfg.OnStart = func() {
+		c.mu.Lock()
+		c.state = graceful.Running
+		c.started = c.now()
+		c.mu.Unlock()
+		if onStart != nil {
+			onStart()
+		}
+	}
+	cfg.OnShutdownBegin = func() {
+		c.setState(graceful.Draining)
+		if onBegin != nil {
+			onBegin()
+		}
+	}
+	cfg.OnShutdownDone = func(err error) {
+		if err != nil {
+			c.setState(graceful.Failed)
+		} else {
+			c.setState(graceful.Stopped)
+		}
+		if onDone != nil {
+			onDone(err)
+		}
+	}
+	return cfg
+}
+
+func (c *Collector) setState(s graceful.State) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = s
+}
+
+// RecordShutdown implements graceful.Metrics.
+func (c *Collector) RecordShutdown(s graceful.ShutdownStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shutdowns++
+	c.shutdownDuration = s.Duration
+	c.drainDuration = s.DrainDuration
+	if s.TimedOut {
+		c.timeouts++
+	}
+	c.cleanupFailures += uint64(s.CleanupFailures)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", promtext.ContentType)
+	_, _ = c.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format, for
+// appending to an existing metrics endpoint.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pw := promtext.NewWriter(w)
+	name := c.prefix + "graceful_state"
+	pw.Metric(name, "gauge", "Current lifecycle state.")
+	for _, s := range states {
+		v := 0
+		if s == c.state {
+			v = 1
+		}
+		pw.Printf("%s{%s} %d\n", name, promtext.Label("state", s.String()), v)
+	}
+
+	var start, uptime float64
+	if !c.started.IsZero() {
+		start = float64(c.started.UnixNano()) / 1e9
+		uptime = c.now().Sub(c.started).Seconds()
+	}
+	pw.Gauge(c.prefix+"graceful_start_time_seconds", "Unix time the servers started.", start)
+	pw.Gauge(c.prefix+"graceful_uptime_seconds", "Seconds since the servers started.", uptime)
+	pw.Gauge(c.prefix+"graceful_shutdown_duration_seconds", "Duration of the last shutdown.", c.shutdownDuration.Seconds())
+	pw.Gauge(c.prefix+"graceful_drain_duration_seconds", "Time the last shutdown spent draining connections.", c.drainDuration.Seconds())
+	pw.Counter(c.prefix+"graceful_shutdowns_total", "Completed shutdowns.", c.shutdowns)
+	pw.Counter(c.prefix+"graceful_shutdown_timeouts_total", "Shutdowns that exceeded the shutdown timeout.", c.timeouts)
+	pw.Counter(c.prefix+"graceful_cleanup_failures_total", "Cleanups that returned an error or panicked.", c.cleanupFailures)
+
+	return pw.Flush()
+}
@@ -0,0 +1,75 @@
+package prometheus_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/graceful"
+	"github.com/rin2yh/gouse/net/graceful/prometheus"
+)
+
+// stubServer serves until Shutdown is called.
+type stubServer struct{ done chan struct{} }
+
+func (s *stubServer) ListenAndServe() error {
+	<-s.done
+	return http.ErrServerClosed
+}
+
+func (s *stubServer) Shutdown(context.Context) error {
+	close(s.done)
+	return nil
+}
+
+func TestCollector(t *testing.T) {
+	c := prometheus.NewCollector("myapp")
+	var doneCalled bool
+	cfg := c.Attach(&graceful.Config{
+		OnShutdownDone: func(error) { doneCalled = true },
+		Cleanups: []func(context.Context) error{
+			func(context.Context) error { return errors.New("close failed") },
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg.OnStart = chain(cfg.OnStart, func() {
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if !strings.Contains(rec.Body.String(), `myapp_graceful_state{state="running"} 1`) {
+			t.Errorf("state while serving not running:\n%s", rec.Body)
+		}
+		cancel()
+	})
+	_ = graceful.Run(ctx, &stubServer{done: make(chan struct{})}, cfg)
+
+	if !doneCalled {
+		t.Error("Attach dropped the existing OnShutdownDone hook")
+	}
+	var b strings.Builder
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`myapp_graceful_state{state="failed"} 1`,
+		`myapp_graceful_state{state="running"} 0`,
+		"# TYPE myapp_graceful_shutdowns_total counter\nmyapp_graceful_shutdowns_total 1\n",
+		"myapp_graceful_cleanup_failures_total 1\n",
+		"myapp_graceful_shutdown_timeouts_total 0\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func chain(a, b func()) func() {
+	return func() {
+		a()
+		b()
+	}
+}
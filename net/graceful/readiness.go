@@ -0,0 +1,43 @@
+package graceful
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Readiness is an http.Handler for readiness probes. It responds 200 OK
+// until Run begins shutting down and 503 Service Unavailable afterwards, so
+// load balancers (e.g. Kubernetes during a rolling deploy) stop routing new
+// traffic while in-flight requests drain.
+//
+// The zero value is ready. Pass it to Run via Config.Readiness:
+//
+//	var ready graceful.Readiness
+//	mux.Handle("/readyz", &ready)
+//	err := graceful.Run(ctx, srv, &graceful.Config{Readiness: &ready})
+type Readiness struct {
+	draining atomic.Bool
+}
+
+// Draining reports whether shutdown has begun.
+func (r *Readiness) Draining() bool {
+	return r.draining.Load()
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Draining() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("shutting down\n"))
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+func (r *Readiness) setDraining() {
+	if r != nil {
+		r.draining.Store(true)
+	}
+}
@@ -0,0 +1,73 @@
+package graceful
+
+import (
+	"context"
+	"os"
+
+	"github.com/rin2yh/gouse/signalx"
+)
+
+// Trigger identifies what started a shutdown.
+type Trigger int
+
+const (
+	// TriggerServersStopped means every server returned on its own.
+	TriggerServersStopped Trigger = iota
+	// TriggerSignal means one of Config.Signals was received.
+	TriggerSignal
+	// TriggerContext means the parent context was cancelled.
+	TriggerContext
+	// TriggerServerError means a server failed to start or stopped with an
+	// error.
+	TriggerServerError
+)
+
+func (t Trigger) String() string {
+	switch t {
+	case TriggerSignal:
+		return "signal"
+	case TriggerContext:
+		return "context cancelled"
+	case TriggerServerError:
+		return "server failed"
+	default:
+		return "servers stopped"
+	}
+}
+
+// ShutdownReason describes what started a shutdown.
+type ShutdownReason struct {
+	Trigger Trigger
+
+	// Signal is the signal received, for TriggerSignal.
+	Signal os.Signal
+
+	// Err is the server error for TriggerServerError, and the parent's
+	// context.Cause for TriggerContext.
+	Err error
+}
+
+func (r ShutdownReason) String() string {
+	switch r.Trigger {
+	case TriggerSignal:
+		return "signal " + r.Signal.String()
+	case TriggerServerError, TriggerContext:
+		return r.Trigger.String() + ": " + r.Err.Error()
+	default:
+		return r.Trigger.String()
+	}
+}
+
+// shutdownReason determines why the wait in RunAll ended.
+func shutdownReason(ctx context.Context, startErr error) ShutdownReason {
+	if startErr != nil {
+		return ShutdownReason{Trigger: TriggerServerError, Err: startErr}
+	}
+	if sig, ok := signalx.Received(ctx); ok {
+		return ShutdownReason{Trigger: TriggerSignal, Signal: sig}
+	}
+	if ctx.Err() != nil {
+		return ShutdownReason{Trigger: TriggerContext, Err: context.Cause(ctx)}
+	}
+	return ShutdownReason{Trigger: TriggerServersStopped}
+}
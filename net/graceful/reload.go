@@ -0,0 +1,24 @@
+package graceful
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/rin2yh/gouse/signalx"
+)
+
+// watchReload calls cfg.OnReload each time SIGHUP arrives, until ctx is
+// done or stop is called. It does nothing on platforms without SIGHUP.
+func watchReload(ctx context.Context, cfg *Config) (stop func()) {
+	if reloadSignal == nil {
+		cfg.log(slog.LevelWarn, "OnReload is not supported on this platform")
+		return func() {}
+	}
+	return signalx.OnSignal(ctx, cfg.Notifier, reloadSignal, func(os.Signal) {
+		cfg.log(slog.LevelInfo, "reloading")
+		if err := cfg.OnReload(ctx); err != nil {
+			cfg.log(slog.LevelError, "reload failed", slog.Any("error", err))
+		}
+	})
+}
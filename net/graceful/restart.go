@@ -0,0 +1,96 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultRestartAttempts   = 3
+	defaultRestartBackoff    = 100 * time.Millisecond
+	defaultRestartMaxBackoff = 10 * time.Second
+)
+
+// RestartPolicy controls restarting a server whose ListenAndServe fails
+// with a transient error (e.g. EMFILE) instead of shutting everything down.
+// The zero value is valid.
+type RestartPolicy struct {
+	// MaxAttempts is the maximum number of restarts per server over the
+	// lifetime of Run. Defaults to 3 if zero.
+	MaxAttempts int
+
+	// Backoff is the delay before the first restart; it doubles with each
+	// further attempt. Defaults to 100ms if zero.
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between restarts. Defaults to 10s if zero.
+	MaxBackoff time.Duration
+
+	// Retryable reports whether err is worth a restart. Defaults to
+	// restarting on every error.
+	Retryable func(err error) bool
+}
+
+// delay returns the backoff before restart attempt n (starting at 1).
+func (p *RestartPolicy) delay(n int) time.Duration {
+	d, limit := defaultRestartBackoff, defaultRestartMaxBackoff
+	if p.Backoff > 0 {
+		d = p.Backoff
+	}
+	if p.MaxBackoff > 0 {
+		limit = p.MaxBackoff
+	}
+	for i := 1; i < n && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+// ignored reports whether err matches one of cfg.IgnoreServeErrors.
+func (c *Config) ignored(err error) bool {
+	for _, target := range c.IgnoreServeErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// serve runs srv.ListenAndServe, restarting it according to
+// cfg.RestartPolicy until it stops cleanly, the policy gives up or stopping
+// is closed. It returns nil when srv stopped because of Shutdown. A panic in
+// ListenAndServe is returned as a *PanicError and never restarted.
+func serve(srv Server, cfg *Config, stopping <-chan struct{}) error {
+	p := cfg.RestartPolicy
+	listen := func(context.Context) error { return srv.ListenAndServe() }
+	for attempt := 1; ; attempt++ {
+		err := call(context.Background(), listen)
+		if err == nil || errors.Is(err, http.ErrServerClosed) || cfg.ignored(err) {
+			return nil
+		}
+		var pe *PanicError
+		if p == nil || errors.As(err, &pe) || (p.Retryable != nil && !p.Retryable(err)) {
+			return err
+		}
+		attempts := defaultRestartAttempts
+		if p.MaxAttempts > 0 {
+			attempts = p.MaxAttempts
+		}
+		if attempt > attempts {
+			return err
+		}
+
+		d := p.delay(attempt)
+		cfg.log(slog.LevelWarn, "server restarting", slog.Int("attempt", attempt), slog.Duration("delay", d), slog.Any("error", err))
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-stopping:
+			timer.Stop()
+			return err
+		}
+	}
+}
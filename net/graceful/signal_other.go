@@ -0,0 +1,12 @@
+//go:build !unix && !windows
+
+package graceful
+
+import "os"
+
+// defaultSignals trigger shutdown unless Config.Signals is set. os.Interrupt
+// is the only signal guaranteed to exist on every platform.
+var defaultSignals = []os.Signal{os.Interrupt}
+
+// reloadSignal is nil: Config.OnReload is not supported on this platform.
+var reloadSignal os.Signal
@@ -0,0 +1,14 @@
+//go:build unix
+
+package graceful
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultSignals trigger shutdown unless Config.Signals is set.
+var defaultSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// reloadSignal triggers Config.OnReload.
+var reloadSignal os.Signal = syscall.SIGHUP
@@ -0,0 +1,17 @@
+//go:build windows
+
+package graceful
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultSignals trigger shutdown unless Config.Signals is set. The Go
+// runtime delivers Ctrl+C and Ctrl+Break as os.Interrupt, and console
+// close, logoff and system shutdown events as SIGTERM.
+var defaultSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// reloadSignal is nil: Windows has no SIGHUP, so Config.OnReload is never
+// called.
+var reloadSignal os.Signal
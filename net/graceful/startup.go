@@ -0,0 +1,71 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultWarmupTimeout        = 30 * time.Second
+	defaultStartupTimeout       = 30 * time.Second
+	defaultStartupProbeInterval = 100 * time.Millisecond
+)
+
+// ErrStartupTimeout is returned (wrapping the probe's last error) when
+// Config.StartupProbe does not succeed within Config.StartupTimeout.
+var ErrStartupTimeout = errors.New("graceful: startup probe did not succeed in time")
+
+// warmup runs cfg.Warmups in order within cfg.WarmupTimeout, stopping at
+// the first failure.
+func warmup(ctx context.Context, cfg *Config) error {
+	if len(cfg.Warmups) == 0 {
+		return nil
+	}
+	timeout := defaultWarmupTimeout
+	if cfg.WarmupTimeout > 0 {
+		timeout = cfg.WarmupTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for i, fn := range cfg.Warmups {
+		if err := call(ctx, fn); err != nil {
+			return fmt.Errorf("graceful: warmup %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// probeStartup calls cfg.StartupProbe until it succeeds, reporting the
+// outcome on the returned channel. It returns nil if no probe is set.
+func probeStartup(ctx context.Context, cfg *Config) <-chan error {
+	if cfg.StartupProbe == nil {
+		return nil
+	}
+	timeout := defaultStartupTimeout
+	if cfg.StartupTimeout > 0 {
+		timeout = cfg.StartupTimeout
+	}
+	result := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		ticker := time.NewTicker(defaultStartupProbeInterval)
+		defer ticker.Stop()
+		for {
+			err := cfg.StartupProbe(ctx)
+			if err == nil {
+				result <- nil
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				result <- fmt.Errorf("%w after %v: %w", ErrStartupTimeout, timeout, err)
+				return
+			}
+		}
+	}()
+	return result
+}
@@ -0,0 +1,87 @@
+package graceful
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// State is a lifecycle stage of a Handle or Group.
+type State int32
+
+const (
+	// Starting means the servers are being started.
+	Starting State = iota
+	// Running means the servers have been started and shutdown has not
+	// been triggered.
+	Running
+	// Draining means shutdown has been triggered and is in progress.
+	Draining
+	// Stopped means shutdown completed without error.
+	Stopped
+	// Failed means shutdown completed with an error, including a server
+	// that failed to start.
+	Failed
+)
+
+func (s State) String() string {
+	switch s {
+	case Starting:
+		return "starting"
+	case Running:
+		return "running"
+	case Draining:
+		return "draining"
+	case Stopped:
+		return "stopped"
+	case Failed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// lifecycle records the current State. A nil *lifecycle ignores updates,
+// for plain Run calls that nobody observes.
+type lifecycle struct {
+	state atomic.Int32
+
+	mu        sync.Mutex
+	reason    ShutdownReason
+	triggered bool
+}
+
+func (l *lifecycle) set(s State) {
+	if l != nil {
+		l.state.Store(int32(s))
+	}
+}
+
+func (l *lifecycle) get() State {
+	return State(l.state.Load())
+}
+
+// trigger records why shutdown began.
+func (l *lifecycle) trigger(r ShutdownReason) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.reason, l.triggered = r, true
+}
+
+// shutdownReason returns the reason recorded by trigger, if any.
+func (l *lifecycle) shutdownReason() (ShutdownReason, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.reason, l.triggered
+}
+
+// finish records the final state for the error Run returns.
+func (l *lifecycle) finish(err error) {
+	if err != nil {
+		l.set(Failed)
+	} else {
+		l.set(Stopped)
+	}
+}
@@ -0,0 +1,44 @@
+package graceful
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrNoHTTPServer is returned by Handle.SwapHandler when the Handle manages
+// no *http.Server.
+var ErrNoHTTPServer = errors.New("graceful: no *http.Server to swap the handler of")
+
+// swapHandler is an http.Handler whose target can be replaced at runtime.
+type swapHandler struct {
+	h atomic.Pointer[http.Handler]
+}
+
+func (s *swapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.h.Load()).ServeHTTP(w, r)
+}
+
+func (s *swapHandler) store(h http.Handler) {
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+	s.h.Store(&h)
+}
+
+// installSwapHandlers replaces the Handler of every *http.Server among srvs
+// with a swapHandler serving the original one.
+func installSwapHandlers(srvs []Server) []*swapHandler {
+	var hs []*swapHandler
+	for _, srv := range srvs {
+		s, ok := httpServer(srv)
+		if !ok {
+			continue
+		}
+		sh := &swapHandler{}
+		sh.store(s.Handler)
+		s.Handler = sh
+		hs = append(hs, sh)
+	}
+	return hs
+}
@@ -0,0 +1,166 @@
+// Package tcp provides graceful shutdown for raw TCP (or any net.Listener)
+// servers, reusing net/graceful's signal handling and shutdown sequence.
+//
+//	ln, err := net.Listen("tcp", ":9000")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	err = tcp.Serve(ctx, ln, func(ctx context.Context, conn net.Conn) {
+//	    // Handle conn; wrap up when ctx is done.
+//	}, nil)
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rin2yh/gouse/net/graceful"
+)
+
+// ErrServerClosed is returned by ListenAndServe after Shutdown or Close.
+// It is http.ErrServerClosed, which graceful treats as a clean stop.
+var ErrServerClosed = http.ErrServerClosed
+
+// Handler serves one connection. ctx is cancelled as soon as shutdown
+// begins, so long-lived handlers can finish the current exchange and
+// return. The connection is closed after the handler returns.
+type Handler func(ctx context.Context, conn net.Conn)
+
+// Server accepts connections on a listener and runs a Handler for each.
+// It implements graceful.Server; its Close method lets
+// graceful.Config.ForceCloseAfterTimeout drop remaining connections.
+type Server struct {
+	ln      net.Listener
+	handler Handler
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// NewServer returns a Server that serves handler on ln.
+func NewServer(ln net.Listener, handler Handler) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		ln:      ln,
+		handler: handler,
+		ctx:     ctx,
+		cancel:  cancel,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// Serve serves handler on ln with graceful.Run: it blocks until
+// SIGINT/SIGTERM or cancellation of parent, then stops accepting and waits
+// up to the configured timeout for the handlers to return.
+func Serve(parent context.Context, ln net.Listener, handler Handler, cfg *graceful.Config) error {
+	return graceful.Run(parent, NewServer(ln, handler), cfg)
+}
+
+// ListenAndServe accepts connections until Shutdown or Close is called,
+// then returns ErrServerClosed. Temporary accept errors, such as running
+// out of file descriptors (EMFILE), are retried with backoff, as
+// http.Server does.
+func (s *Server) ListenAndServe() error {
+	var delay time.Duration
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Temporary() { //nolint:staticcheck // as http.Server does, to back off on EMFILE
+				delay = max(5*time.Millisecond, min(2*delay, time.Second))
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+		if !s.track(conn) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer s.untrack(conn)
+	defer conn.Close()
+	s.handler(s.ctx, conn)
+}
+
+// track registers conn, unless shutdown has begun.
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// Shutdown stops accepting connections, cancels the handlers' context and
+// waits for them to return or for ctx to be done, whichever happens first.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.stop()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting connections and closes every live connection
+// immediately.
+func (s *Server) Close() error {
+	err := s.stop()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	return err
+}
+
+// ActiveConns returns the number of connections being handled.
+func (s *Server) ActiveConns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// stop marks the server as shutting down and closes the listener.
+func (s *Server) stop() error {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+	if err := s.ln.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package tcp_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/graceful"
+	"github.com/rin2yh/gouse/net/graceful/tcp"
+)
+
+const testTimeout = 2 * time.Second
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ln
+}
+
+// echo echoes lines until the client disconnects or shutdown begins.
+func echo(ctx context.Context, conn net.Conn) {
+	go func() {
+		<-ctx.Done()
+		conn.SetReadDeadline(time.Now())
+	}()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write([]byte(line)); err != nil {
+			return
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	ln := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- tcp.Serve(ctx, ln, echo, nil) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || got != "hello\n" {
+		t.Fatalf("echo = %q, %v, want %q", got, err, "hello\n")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Serve() = %v, want nil", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Serve did not return after cancellation")
+	}
+	if _, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		t.Fatal("expected the listener to be closed after shutdown")
+	}
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	ln := listen(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	hang := func(ctx context.Context, conn net.Conn) {
+		close(started)
+		<-release // ignores ctx
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- tcp.Serve(ctx, ln, hang, &graceful.Config{ShutdownTimeout: 50 * time.Millisecond})
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	<-started
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Serve() = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Serve did not return after the shutdown timeout")
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	ln := listen(t)
+	started := make(chan struct{})
+	srv := tcp.NewServer(ln, func(ctx context.Context, conn net.Conn) {
+		close(started)
+		conn.Read(make([]byte, 1)) // returns once Close closes conn
+	})
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe() }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	<-started
+	if n := srv.ActiveConns(); n != 1 {
+		t.Fatalf("ActiveConns() = %d, want 1", n)
+	}
+
+	if err := srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !errors.Is(err, tcp.ErrServerClosed) {
+		t.Fatalf("ListenAndServe() = %v, want %v", err, tcp.ErrServerClosed)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() after Close = %v, want nil", err)
+	}
+}
+
+// flakyListener fails its first Accept with EMFILE, like a process out of
+// file descriptors.
+type flakyListener struct {
+	net.Listener
+	failed bool
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	if !l.failed {
+		l.failed = true
+		return nil, &net.OpError{Op: "accept", Net: "tcp", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+	}
+	return l.Listener.Accept()
+}
+
+func TestServeRetriesTemporaryErrors(t *testing.T) {
+	ln := &flakyListener{Listener: listen(t)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- tcp.Serve(ctx, ln, echo, nil) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("hello\n"))
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+	if got, err := bufio.NewReader(conn).ReadString('\n'); err != nil || got != "hello\n" {
+		t.Fatalf("echo after EMFILE = %q, %v; want the server to keep accepting", got, err)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Serve() = %v, want nil", err)
+	}
+}
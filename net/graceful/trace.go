@@ -0,0 +1,58 @@
+package graceful
+
+import (
+	"context"
+	"strconv"
+)
+
+// Tracer starts spans around the phases of shutdown, so drain latency shows
+// up in traces next to the requests it delays. It is small enough to adapt
+// an OpenTelemetry trace.Tracer (or any other tracer) in a few lines
+// without this package depending on it; see the README.
+type Tracer interface {
+	// Start starts a span named name as a child of any span in ctx and
+	// returns a context carrying it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// End ends the span, recording err if it is non-nil.
+	End(err error)
+}
+
+// startSpan starts a span with cfg.Tracer, if set. The returned function
+// ends it.
+func (c *Config) startSpan(ctx context.Context, name string) (context.Context, func(error)) {
+	if c.Tracer == nil {
+		return ctx, func(error) {}
+	}
+	ctx, span := c.Tracer.Start(ctx, name)
+	return ctx, span.End
+}
+
+// traced returns a copy of fns with each cleanup wrapped in its own span.
+func traced(cfg *Config, fns []func(context.Context) error) []func(context.Context) error {
+	wrapped := make([]func(context.Context) error, len(fns))
+	if cfg.Tracer == nil {
+		copy(wrapped, fns)
+		return wrapped
+	}
+	for i, fn := range fns {
+		wrapped[i] = tracedFn(cfg, "graceful.cleanup."+strconv.Itoa(i), fn)
+	}
+	return wrapped
+}
+
+// tracedFn wraps fn in a span named name, if cfg.Tracer is set.
+func tracedFn(cfg *Config, name string, fn func(context.Context) error) func(context.Context) error {
+	if cfg.Tracer == nil {
+		return fn
+	}
+	return func(ctx context.Context) error {
+		ctx, end := cfg.startSpan(ctx, name)
+		err := call(ctx, fn)
+		end(err)
+		return err
+	}
+}
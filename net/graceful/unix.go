@@ -0,0 +1,68 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+)
+
+// ErrSocketInUse is returned by ListenUnix when another process is already
+// accepting connections on the socket.
+var ErrSocketInUse = errors.New("graceful: unix socket is in use")
+
+// ListenUnix listens on the unix domain socket at path. A stale socket file
+// left behind by a process that exited without removing it is removed
+// first; a socket that still accepts connections yields ErrSocketInUse, and
+// a path that is not a socket is left alone and reported as an error.
+func ListenUnix(path string) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
+
+// removeStaleSocket removes the socket file at path unless something is
+// listening on it.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("graceful: %s exists and is not a unix socket", path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%w: %s", ErrSocketInUse, path)
+	}
+	return os.Remove(path)
+}
+
+// RunUnix is like Run but serves srv on the unix domain socket at path (see
+// ListenUnix). The socket file is removed by a cleanup appended to
+// cfg.Cleanups, once the server has shut down.
+func RunUnix(parent context.Context, srv *http.Server, path string, cfg *Config) error {
+	ln, err := ListenUnix(path)
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	c := *cfg
+	c.Cleanups = append(slices.Clip(cfg.Cleanups), func(context.Context) error {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	})
+	return Run(parent, OnListener(srv, ln), &c)
+}
@@ -0,0 +1,89 @@
+package graceful_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/graceful"
+)
+
+// socketPath returns a socket path short enough for sun_path limits.
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "graceful")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "s.sock")
+}
+
+func TestRunUnix(t *testing.T) {
+	path := socketPath(t)
+
+	// Leave a stale socket file behind, as a crashed process would.
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Skip("unix sockets unavailable:", err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	done := make(chan error, 1)
+	go func() { done <- graceful.RunUnix(ctx, &http.Server{Handler: http.NotFoundHandler()}, path, nil) }()
+
+	client := &http.Client{
+		Timeout: 100 * time.Millisecond,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+	deadline := time.Now().Add(testStartTimeout)
+	for {
+		resp, err := client.Get("http://unix/")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("server did not start in time:", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := graceful.ListenUnix(path); !errors.Is(err, graceful.ErrSocketInUse) {
+		t.Fatalf("ListenUnix() on a served socket = %v, want %v", err, graceful.ErrSocketInUse)
+	}
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if _, err := os.Lstat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("socket file still exists after shutdown: %v", err)
+	}
+}
+
+func TestListenUnixNotSocket(t *testing.T) {
+	path := socketPath(t)
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := graceful.ListenUnix(path); err == nil {
+		t.Fatal("expected an error for a path that is not a socket")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("regular file was removed: %v", err)
+	}
+}
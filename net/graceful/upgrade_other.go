@@ -0,0 +1,18 @@
+//go:build !unix
+
+package graceful
+
+import (
+	"context"
+	"net"
+)
+
+// Listen is net.Listen; inheriting listeners across upgrades is only
+// supported on Unix.
+func Listen(network, addr string) (net.Listener, error) {
+	return net.Listen(network, addr)
+}
+
+func watchUpgrade(context.Context, *Config) (stop func()) { return func() {} }
+
+func notifyParent(*Config) {}
@@ -0,0 +1,171 @@
+//go:build unix
+
+package graceful
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/rin2yh/gouse/signalx"
+)
+
+// envInheritedListeners tells a process started by an upgrade how many
+// listeners it inherited, as "<parent pid>:<count>". They occupy consecutive
+// descriptors starting at 3, in the order the parent obtained them from
+// Listen. The child unsets it once read, and ignores it if its parent is not
+// the process that set it, so processes it starts in turn never take
+// unrelated descriptors for listeners or signal the wrong parent.
+const envInheritedListeners = "GRACEFUL_INHERITED_LISTENERS"
+
+const firstInheritedFD = 3
+
+// inheritance holds the listeners handed over by the parent process and
+// those obtained through Listen, which are passed on by the next upgrade.
+var inheritance struct {
+	sync.Mutex
+	loaded    bool
+	inherited bool // this process was started by an upgrade
+	files     []*os.File
+	listeners []net.Listener
+}
+
+// loadInheritance reads and unsets envInheritedListeners on first use.
+// inheritance must be locked.
+func loadInheritance() {
+	if inheritance.loaded {
+		return
+	}
+	inheritance.loaded = true
+	v, ok := os.LookupEnv(envInheritedListeners)
+	if !ok {
+		return
+	}
+	os.Unsetenv(envInheritedListeners)
+	ppid, count, _ := strings.Cut(v, ":")
+	if ppid != strconv.Itoa(os.Getppid()) {
+		return
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 0 {
+		return
+	}
+	inheritance.inherited = true
+	for i := 0; i < n; i++ {
+		fd := uintptr(firstInheritedFD + i)
+		inheritance.files = append(inheritance.files, os.NewFile(fd, "inherited-listener-"+strconv.Itoa(i)))
+	}
+}
+
+// Listen is like net.Listen, but in a process started by an upgrade it
+// returns the listener inherited from the parent instead of binding a new
+// one. Inherited listeners are handed out in the order the parent's calls to
+// Listen obtained them, so both processes must call Listen in the same order.
+//
+// Listeners obtained this way are passed on to the new process when
+// Config.Upgrade is set and SIGUSR2 arrives.
+func Listen(network, addr string) (net.Listener, error) {
+	inheritance.Lock()
+	defer inheritance.Unlock()
+
+	loadInheritance()
+
+	var (
+		ln  net.Listener
+		err error
+	)
+	if i := len(inheritance.listeners); i < len(inheritance.files) {
+		f := inheritance.files[i]
+		ln, err = net.FileListener(f)
+		f.Close()
+	} else {
+		ln, err = net.Listen(network, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	inheritance.listeners = append(inheritance.listeners, ln)
+	return ln, nil
+}
+
+// inherited reports whether this process was started by an upgrade.
+func inherited() bool {
+	inheritance.Lock()
+	defer inheritance.Unlock()
+	loadInheritance()
+	return inheritance.inherited
+}
+
+// watchUpgrade starts a new copy of the binary, handing over every listener
+// obtained through Listen, each time SIGUSR2 arrives.
+func watchUpgrade(ctx context.Context, cfg *Config) (stop func()) {
+	return signalx.OnSignal(ctx, cfg.Notifier, syscall.SIGUSR2, func(os.Signal) {
+		pid, err := upgrade()
+		if err != nil {
+			cfg.log(slog.LevelError, "upgrade failed", slog.Any("error", err))
+			return
+		}
+		cfg.log(slog.LevelInfo, "upgrade started", slog.Int("pid", pid))
+	})
+}
+
+// upgrade starts the running executable again with the same arguments and
+// the listeners obtained through Listen as inherited descriptors.
+func upgrade() (pid int, err error) {
+	inheritance.Lock()
+	lns := append([]net.Listener(nil), inheritance.listeners...)
+	inheritance.Unlock()
+
+	files := []*os.File{os.Stdin, os.Stdout, os.Stderr}
+	defer func() {
+		for _, f := range files[firstInheritedFD:] {
+			f.Close()
+		}
+	}()
+	for _, ln := range lns {
+		fl, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return 0, fmt.Errorf("graceful: upgrade: listener %T cannot be inherited", ln)
+		}
+		f, err := fl.File()
+		if err != nil {
+			return 0, fmt.Errorf("graceful: upgrade: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("graceful: upgrade: %w", err)
+	}
+	env := make([]string, 0, len(os.Environ())+1)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, envInheritedListeners+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, envInheritedListeners+"="+strconv.Itoa(os.Getpid())+":"+strconv.Itoa(len(lns)))
+
+	p, err := os.StartProcess(exe, os.Args, &os.ProcAttr{Env: env, Files: files})
+	if err != nil {
+		return 0, fmt.Errorf("graceful: upgrade: %w", err)
+	}
+	return p.Pid, nil
+}
+
+// notifyParent tells the process that started this one by an upgrade that
+// the servers are up, so it can begin draining.
+func notifyParent(cfg *Config) {
+	if !inherited() {
+		return
+	}
+	if err := syscall.Kill(os.Getppid(), syscall.SIGTERM); err != nil {
+		cfg.log(slog.LevelWarn, "notifying parent failed", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,88 @@
+//go:build unix
+
+package graceful_test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/graceful"
+)
+
+func TestListenInherited(t *testing.T) {
+	switch os.Getenv("GRACEFUL_TEST_CHILD") {
+	case "1":
+		ln, err := graceful.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Printf("addr=%s\n", ln.Addr())
+		// Processes started by the upgraded one must not inherit the variable.
+		cmd := exec.Command(os.Args[0], "-test.run=^TestListenInherited$")
+		cmd.Env = append(os.Environ(), "GRACEFUL_TEST_CHILD=2")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("grandchild failed: %v\n%s", err, out)
+		}
+		os.Stdout.Write(out)
+		return
+	case "2":
+		fmt.Printf("grandchild env=%q\n", os.Getenv("GRACEFUL_INHERITED_LISTENERS"))
+		return
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := map[string]struct {
+		env      string
+		wantSame bool
+	}{
+		"from parent":      {strconv.Itoa(os.Getpid()) + ":1", true},
+		"from another pid": {"1:1", false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestListenInherited$")
+			cmd.Env = append(os.Environ(), "GRACEFUL_TEST_CHILD=1", "GRACEFUL_INHERITED_LISTENERS="+tt.env)
+			cmd.ExtraFiles = []*os.File{f}
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("child failed: %v\n%s", err, out)
+			}
+			want := "addr=" + ln.Addr().String() + "\n"
+			if got := strings.Contains(string(out), want); got != tt.wantSame {
+				t.Fatalf("child output = %q, contains %q = %v, want %v", out, want, got, tt.wantSame)
+			}
+			if want := `grandchild env=""`; !strings.Contains(string(out), want) {
+				t.Fatalf("child output = %q, want it to contain %q", out, want)
+			}
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	ln, err := graceful.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial(%s) error: %v", ln.Addr(), err)
+	}
+	conn.Close()
+}
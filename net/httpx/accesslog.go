@@ -0,0 +1,48 @@
+package httpx
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// AccessLog returns middleware that logs one record per request to logger
+// once the response is written: method, path, status, bytes, duration,
+// remote address and, under RequestIDMiddleware, the request ID. Responses
+// with a 5xx status are logged at Error level, others at Info. Requests to
+// skipPaths (e.g. "/healthz", "/readyz") are not logged.
+func AccessLog(logger *slog.Logger, skipPaths ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(skipPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			begin := time.Now()
+			rw := NewResponseWriter(w)
+			next.ServeHTTP(rw, r)
+
+			status := rw.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", rw.BytesWritten()),
+				slog.Duration("duration", time.Since(begin)),
+				slog.String("remote_addr", r.RemoteAddr),
+			}
+			if id := RequestID(r.Context()); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+			logger.LogAttrs(r.Context(), level, "request", attrs...)
+		})
+	}
+}
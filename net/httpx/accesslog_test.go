@@ -0,0 +1,71 @@
+package httpx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+	h := httpx.RequestIDMiddleware(httpx.AccessLog(logger, "/healthz")(mux))
+
+	tests := []struct {
+		path       string
+		wantLogged bool
+		wantLevel  string
+		wantStatus float64
+		wantBytes  float64
+	}{
+		{"/items", true, "INFO", 201, 5},
+		{"/fail", true, "ERROR", 500, 5},
+		{"/healthz", false, "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.Header.Set(httpx.RequestIDHeader, "req-1")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if !tt.wantLogged {
+				if buf.Len() != 0 {
+					t.Fatalf("skipped path logged: %s", buf.String())
+				}
+				return
+			}
+			var rec map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+				t.Fatalf("decoding log record %q: %v", buf.String(), err)
+			}
+			want := map[string]any{
+				"level": tt.wantLevel, "method": "POST", "path": tt.path,
+				"status": tt.wantStatus, "bytes": tt.wantBytes, "request_id": "req-1",
+				"remote_addr": "192.0.2.1:1234",
+			}
+			for k, v := range want {
+				if rec[k] != v {
+					t.Errorf("%s = %v, want %v", k, rec[k], v)
+				}
+			}
+			if _, ok := rec["duration"]; !ok {
+				t.Error("duration missing")
+			}
+		})
+	}
+}
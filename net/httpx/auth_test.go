@@ -0,0 +1,110 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+type user struct{ name string }
+
+func whoami[P any]() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := httpx.Principal[P](r.Context())
+		fmt.Fprintf(w, "%v %v", p, ok)
+	})
+}
+
+func TestBasicAuth(t *testing.T) {
+	h := httpx.BasicAuth(func(u, p string) bool { return u == "alice" && p == "secret" })(whoami[string]())
+	tests := []struct {
+		name       string
+		user, pass string
+		noAuth     bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid", "alice", "secret", false, http.StatusOK, "alice true"},
+		{"wrong password", "alice", "guess", false, http.StatusUnauthorized, ""},
+		{"missing", "", "", true, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if !tt.noAuth {
+				r.SetBasicAuth(tt.user, tt.pass)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if rec.Body.String() != tt.wantBody {
+					t.Errorf("body = %q, want %q", rec.Body, tt.wantBody)
+				}
+			} else if rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	h := httpx.BearerAuth(func(ctx context.Context, token string) (*user, error) {
+		switch token {
+		case "t-alice":
+			return &user{"alice"}, nil
+		case "t-guest":
+			return nil, fmt.Errorf("guest: %w", httpx.ErrForbidden)
+		}
+		return nil, errors.New("unknown token")
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, ok := httpx.Principal[*user](r.Context())
+		if !ok {
+			t.Error("no principal in context")
+			return
+		}
+		fmt.Fprint(w, u.name)
+	}))
+	tests := []struct {
+		name, header string
+		wantStatus   int
+		wantBody     string
+		wantAuth     string
+	}{
+		{"valid", "Bearer t-alice", http.StatusOK, "alice", ""},
+		{"scheme case-insensitive", "bearer t-alice", http.StatusOK, "alice", ""},
+		{"forbidden", "Bearer t-guest", http.StatusForbidden, "", ""},
+		{"invalid", "Bearer nope", http.StatusUnauthorized, "", `Bearer error="invalid_token"`},
+		{"missing", "", http.StatusUnauthorized, "", "Bearer"},
+		{"other scheme", "Basic YTpi", http.StatusUnauthorized, "", "Bearer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body, tt.wantBody)
+			}
+			if tt.wantStatus != http.StatusOK && rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("Content-Type = %q, want application/problem+json", rec.Header().Get("Content-Type"))
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.wantAuth {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantAuth)
+			}
+		})
+	}
+}
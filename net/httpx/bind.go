@@ -0,0 +1,268 @@
+package httpx
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rin2yh/gouse/parsex"
+)
+
+const bindMaxMemory = 32 << 20
+
+// ErrRequired is reported in a BindError for a required value that is
+// missing or empty.
+var ErrRequired = errors.New("value is required")
+
+// BindError reports a request value Bind could not store. All BindErrors
+// are the client's fault, so respond 400 Bad Request.
+type BindError struct {
+	Source string // "query", "form" or "path"
+	Name   string // the parameter name from the struct tag
+	Err    error  // ErrRequired, a parsex error, or the TextUnmarshaler's
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("httpx: %s parameter %q: %v", e.Source, e.Name, e.Err)
+}
+
+func (e *BindError) Unwrap() error { return e.Err }
+
+// bindSources are the struct tags Bind reads, in order of precedence.
+var bindSources = []string{"path", "query", "form"}
+
+// Bind sets the fields of the struct dst points to from the request's path
+// values (Go 1.22 ServeMux patterns), query parameters and form body,
+// according to struct tags:
+//
+//	type listParams struct {
+//	    Org    string        `path:"org"`
+//	    Page   int           `query:"page"`
+//	    Tags   []string      `query:"tag"`
+//	    Since  time.Time     `query:"since"`
+//	    Token  string        `form:"token,required"`
+//	}
+//
+// Fields may be strings, booleans (parsex.ParseBool syntax), integers,
+// floats, time.Duration (parsex.ParseDuration syntax), time.Time
+// (RFC 3339), types implementing encoding.TextUnmarshaler, pointers to
+// these, and slices of these, which take every value of a repeated
+// parameter. A field may have several tags; the first non-empty value in
+// the order path, query, form wins. Absent values leave fields unchanged,
+// so defaults can be set beforehand, unless the tag says ",required".
+// Anonymous struct fields are bound recursively.
+//
+// Bind returns the errors for all fields joined, each a *BindError, or
+// another error if dst is not a pointer to a struct or the form cannot be
+// read.
+func Bind(r *http.Request, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("httpx: Bind needs a non-nil pointer to a struct, got %T", dst)
+	}
+	b := &binder{r: r}
+	if err := b.bindStruct(v.Elem()); err != nil {
+		return err
+	}
+	return errors.Join(b.errs...)
+}
+
+type binder struct {
+	r     *http.Request
+	query url.Values
+	form  url.Values
+	errs  []error
+}
+
+func (b *binder) bindStruct(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := b.bindStruct(v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if err := b.bindField(v.Field(i), f.Tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindField sets one field from the first of its tagged sources that has
+// a value.
+func (b *binder) bindField(field reflect.Value, tag reflect.StructTag) error {
+	var src, name string
+	var required bool
+	for _, source := range bindSources {
+		spec, ok := tag.Lookup(source)
+		if !ok {
+			continue
+		}
+		n, opt, _ := strings.Cut(spec, ",")
+		if n == "" || n == "-" {
+			continue
+		}
+		required = required || opt == "required"
+		if src == "" {
+			src, name = source, n
+		}
+		values, err := b.values(source, n)
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 || values[0] == "" {
+			continue
+		}
+		if err := setValues(field, values); err != nil {
+			b.errs = append(b.errs, &BindError{Source: source, Name: n, Err: err})
+		}
+		return nil
+	}
+	if required && src != "" {
+		b.errs = append(b.errs, &BindError{Source: src, Name: name, Err: ErrRequired})
+	}
+	return nil
+}
+
+// values returns the values of the named parameter in source.
+func (b *binder) values(source, name string) ([]string, error) {
+	switch source {
+	case "path":
+		if v := pathValue(b.r, name); v != "" {
+			return []string{v}, nil
+		}
+		return nil, nil
+	case "query":
+		if b.query == nil {
+			b.query = b.r.URL.Query()
+		}
+		return b.query[name], nil
+	}
+	if b.form == nil {
+		if err := b.parseForm(); err != nil {
+			return nil, err
+		}
+	}
+	return b.form[name], nil
+}
+
+// parseForm reads the urlencoded or multipart request body.
+func (b *binder) parseForm() error {
+	mt, _, _ := mime.ParseMediaType(b.r.Header.Get("Content-Type"))
+	var err error
+	if mt == "multipart/form-data" {
+		err = b.r.ParseMultipartForm(bindMaxMemory)
+	} else {
+		err = b.r.ParseForm()
+	}
+	if err != nil {
+		return fmt.Errorf("httpx: reading form: %w", err)
+	}
+	b.form = b.r.PostForm
+	if b.form == nil {
+		b.form = url.Values{}
+	}
+	return nil
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setValues stores values in field: all of them for a slice, else the
+// first.
+func setValues(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && !reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setValue(s.Index(i), v); err != nil {
+				return err
+			}
+		}
+		field.Set(s)
+		return nil
+	}
+	return setValue(field, values[0])
+}
+
+func setValue(field reflect.Value, s string) error {
+	if field.Kind() == reflect.Pointer {
+		p := reflect.New(field.Type().Elem())
+		if err := setValue(p.Elem(), s); err != nil {
+			return err
+		}
+		field.Set(p)
+		return nil
+	}
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch field.Type() {
+	case durationType:
+		d, err := parsex.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		v, err := parsex.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := parsex.ParseInt[int64](s)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(v) {
+			return &parsex.Error{Func: "ParseInt", Input: s, Err: parsex.ErrRange}
+		}
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := parsex.ParseInt[uint64](s)
+		if err != nil {
+			return err
+		}
+		if field.OverflowUint(v) {
+			return &parsex.Error{Func: "ParseInt", Input: s, Err: parsex.ErrRange}
+		}
+		field.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(v)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build go1.22
+
+package httpx
+
+import "net/http"
+
+// pathValue returns the value of the named wildcard in the ServeMux
+// pattern that matched r.
+func pathValue(r *http.Request, name string) string {
+	return r.PathValue(name)
+}
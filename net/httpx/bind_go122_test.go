@@ -0,0 +1,27 @@
+//go:build go1.22
+
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestBindPath(t *testing.T) {
+	var got struct {
+		Org  string `path:"org" query:"org"`
+		Repo int64  `path:"id,required"`
+	}
+	r := httptest.NewRequest(http.MethodGet, "/?org=ignored", nil)
+	r.SetPathValue("org", "acme")
+	r.SetPathValue("id", "42")
+	if err := httpx.Bind(r, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Org != "acme" || got.Repo != 42 {
+		t.Fatalf("Bind() set %+v, want path values", got)
+	}
+}
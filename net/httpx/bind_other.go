@@ -0,0 +1,10 @@
+//go:build !go1.22
+
+package httpx
+
+import "net/http"
+
+// pathValue returns "": path values need Go 1.22's ServeMux patterns.
+func pathValue(*http.Request, string) string {
+	return ""
+}
@@ -0,0 +1,118 @@
+package httpx_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+	"github.com/rin2yh/gouse/parsex"
+)
+
+type paging struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit"`
+}
+
+type searchParams struct {
+	paging
+	Q       string        `query:"q,required"`
+	Tags    []string      `query:"tag"`
+	Exact   bool          `query:"exact"`
+	Within  time.Duration `query:"within"`
+	Since   time.Time     `query:"since"`
+	Score   *float64      `query:"score"`
+	IP      net.IP        `query:"ip"`
+	Token   string        `form:"token" query:"token"`
+	Level   uint8         `query:"level"`
+	ignored string        `query:"ignored"`
+}
+
+func TestBind(t *testing.T) {
+	score := 0.5
+	tests := []struct {
+		name    string
+		target  string
+		form    string
+		want    searchParams
+		wantErr []httpx.BindError // Source, Name and Err compared with errors.Is
+	}{
+		{
+			name:   "all types",
+			target: "/?q=go&tag=a&tag=b&exact=yes&within=1d&since=2024-01-02T03:04:05Z&score=0.5&ip=192.0.2.1&page=2&level=7",
+			want: searchParams{
+				paging: paging{Page: 2, Limit: 20},
+				Q:      "go", Tags: []string{"a", "b"}, Exact: true, Within: 24 * time.Hour,
+				Since: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Score: &score, IP: net.IPv4(192, 0, 2, 1), Level: 7,
+			},
+		},
+		{
+			name:   "query wins over form",
+			target: "/?q=go&token=from-query",
+			form:   "token=from-form",
+			want:   searchParams{paging: paging{Limit: 20}, Q: "go", Token: "from-query"},
+		},
+		{
+			name:   "form",
+			target: "/?q=go",
+			form:   "token=from-form",
+			want:   searchParams{paging: paging{Limit: 20}, Q: "go", Token: "from-form"},
+		},
+		{
+			name:   "errors",
+			target: "/?page=x&level=300&exact=maybe",
+			want:   searchParams{paging: paging{Limit: 20}},
+			wantErr: []httpx.BindError{
+				{Source: "query", Name: "page", Err: parsex.ErrSyntax},
+				{Source: "query", Name: "q", Err: httpx.ErrRequired},
+				{Source: "query", Name: "exact", Err: parsex.ErrSyntax},
+				{Source: "query", Name: "level", Err: parsex.ErrRange},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r *http.Request
+			if tt.form != "" {
+				r = httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.form))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				r = httptest.NewRequest(http.MethodGet, tt.target, nil)
+			}
+			got := searchParams{paging: paging{Limit: 20}}
+			err := httpx.Bind(r, &got)
+
+			var errs []error
+			if err != nil {
+				errs = err.(interface{ Unwrap() []error }).Unwrap()
+			}
+			if len(errs) != len(tt.wantErr) {
+				t.Fatalf("Bind() = %v, want %d errors", err, len(tt.wantErr))
+			}
+			for i, want := range tt.wantErr {
+				var be *httpx.BindError
+				if !errors.As(errs[i], &be) || be.Source != want.Source || be.Name != want.Name || !errors.Is(be, want.Err) {
+					t.Errorf("error %d = %v, want %s %q: %v", i, errs[i], want.Source, want.Name, want.Err)
+				}
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bind() set %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindInvalidTarget(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	var n int
+	for _, dst := range []any{nil, searchParams{}, &n} {
+		if err := httpx.Bind(r, dst); err == nil {
+			t.Errorf("Bind(%T) = nil, want an error", dst)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned by Client.Do without sending the request while
+// its CircuitBreaker is open.
+var ErrCircuitOpen = errors.New("httpx: circuit breaker open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets requests through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects requests with ErrCircuitOpen.
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe request through; its outcome
+	// closes or reopens the breaker.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops a Client from sending requests to a failing server:
+// after Threshold consecutive failures (transport errors or 5xx responses)
+// it opens for Cooldown, then lets one probe through to decide whether to
+// close again. The zero value is a closed breaker with the defaults. It
+// must not be copied after first use.
+type CircuitBreaker struct {
+	// Threshold is the number of consecutive failures that opens the
+	// breaker. Defaults to 5 if zero.
+	Threshold int
+
+	// Cooldown is how long the breaker stays open. Defaults to 30 seconds
+	// if zero.
+	Cooldown time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// State returns the current state.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// advance moves an open breaker whose cooldown has passed to half-open.
+func (b *CircuitBreaker) advance() {
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= orDefault(b.Cooldown, defaultBreakerCooldown) {
+		b.state = BreakerHalfOpen
+		b.probing = false
+	}
+}
+
+// allow reports whether a request may be sent. A nil breaker allows all.
+func (b *CircuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	switch b.state {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// record reports the outcome of a request let through by allow. Requests
+// abandoned because the caller's ctx ended say nothing about the server.
+func (b *CircuitBreaker) record(ctx context.Context, resp *http.Response, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if ctx.Err() != nil {
+		b.probing = false
+		return
+	}
+	if err == nil && resp.StatusCode < 500 {
+		b.state, b.failures, b.probing = BreakerClosed, 0, false
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= orDefault(b.Threshold, defaultBreakerThreshold) {
+		b.state, b.openedAt, b.probing = BreakerOpen, time.Now(), false
+	}
+}
@@ -0,0 +1,308 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 10 * time.Second
+)
+
+// RetryPolicy controls how a Client retries failed requests.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first.
+	// Defaults to 3 if zero; 1 disables retries.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry. It doubles for each
+	// further retry up to MaxBackoff, and is jittered by up to half of it.
+	// Defaults to 100ms if zero.
+	Backoff time.Duration
+
+	// MaxBackoff caps the computed delay. A longer Retry-After response
+	// header is still honoured. Defaults to 10 seconds if zero.
+	MaxBackoff time.Duration
+
+	// Retryable reports whether an attempt that returned resp and err
+	// should be retried. Defaults to DefaultRetryable if nil.
+	Retryable func(resp *http.Response, err error) bool
+}
+
+// DefaultRetryable retries transport errors, including attempts that
+// exceeded Client.Timeout, and the 429, 502, 503 and 504 statuses, but not
+// a cancelled context. Do stops anyway once its own context is done.
+func DefaultRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Client sends HTTP requests, retrying them according to Retry. The zero
+// value uses http.DefaultClient and retries up to 3 attempts; NewClient
+// builds one from options.
+//
+// Only requests that are safe to repeat are retried: those with an
+// idempotent method (GET, HEAD, OPTIONS, TRACE, PUT, DELETE) or an
+// Idempotency-Key header, and whose body, if any, can be rewound with
+// GetBody (as for bodies given to http.NewRequest as *bytes.Buffer,
+// *bytes.Reader or *strings.Reader).
+type Client struct {
+	// HTTPClient sends each attempt. Defaults to http.DefaultClient if nil.
+	HTTPClient *http.Client
+
+	// Retry is the retry policy.
+	Retry RetryPolicy
+
+	// Timeout bounds each attempt, including reading the response body.
+	// Zero means no limit beyond the context passed to Do.
+	Timeout time.Duration
+
+	// Breaker, if set, rejects requests with ErrCircuitOpen while the
+	// server keeps failing.
+	Breaker *CircuitBreaker
+
+	// OnRequest, if set, is called with each attempt before it is sent,
+	// e.g. to add headers.
+	OnRequest func(req *http.Request)
+
+	// OnResponse, if set, is called after each attempt with its outcome and
+	// how long it took to get the response headers, e.g. to log it.
+	OnResponse func(req *http.Request, resp *http.Response, err error, took time.Duration)
+}
+
+// ClientOption configures a Client built by NewClient.
+type ClientOption func(*Client)
+
+// WithHTTPClient sends requests with hc instead of an http.Client using
+// NewTransport(nil).
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) { c.HTTPClient = hc }
+}
+
+// WithRoundTripper wraps the transport of the client's http.Client in
+// wrap, e.g. to trace requests and propagate trace context with
+// OpenTelemetry's otelhttp without this package depending on it:
+//
+//	httpx.WithRoundTripper(func(rt http.RoundTripper) http.RoundTripper {
+//	    return otelhttp.NewTransport(rt)
+//	})
+//
+// Options apply in order, so give it after WithHTTPClient. The http.Client
+// is copied, not modified.
+func WithRoundTripper(wrap func(http.RoundTripper) http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		hc := http.Client{}
+		if c.HTTPClient != nil {
+			hc = *c.HTTPClient
+		}
+		rt := hc.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		hc.Transport = wrap(rt)
+		c.HTTPClient = &hc
+	}
+}
+
+// WithRetry sets the retry policy.
+func WithRetry(p RetryPolicy) ClientOption {
+	return func(c *Client) { c.Retry = p }
+}
+
+// WithRequestTimeout bounds each attempt to d.
+func WithRequestTimeout(d time.Duration) ClientOption {
+	return func(c *Client) { c.Timeout = d }
+}
+
+// WithCircuitBreaker guards the client with b, which may be shared by
+// clients calling the same server.
+func WithCircuitBreaker(b *CircuitBreaker) ClientOption {
+	return func(c *Client) { c.Breaker = b }
+}
+
+// WithRequestHook sets Client.OnRequest.
+func WithRequestHook(fn func(req *http.Request)) ClientOption {
+	return func(c *Client) { c.OnRequest = fn }
+}
+
+// WithResponseHook sets Client.OnResponse.
+func WithResponseHook(fn func(req *http.Request, resp *http.Response, err error, took time.Duration)) ClientOption {
+	return func(c *Client) { c.OnResponse = fn }
+}
+
+// NewClient returns a Client configured by opts, sending requests over
+// NewTransport(nil) unless WithHTTPClient says otherwise:
+//
+//	c := httpx.NewClient(
+//	    httpx.WithRequestTimeout(2*time.Second),
+//	    httpx.WithRetry(httpx.RetryPolicy{MaxAttempts: 4}),
+//	    httpx.WithCircuitBreaker(&httpx.CircuitBreaker{}),
+//	)
+//	resp, err := c.Do(ctx, req)
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{HTTPClient: &http.Client{Transport: NewTransport(nil)}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Do sends req with ctx, retrying as described on Client. Waits between
+// attempts honour a Retry-After response header and end early if ctx is
+// done. Once the attempts are exhausted, Do returns the last response or
+// error, like http.Client.Do. If the Breaker opens, Do stops with
+// ErrCircuitOpen.
+func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	p := &c.Retry
+	attempts := defaultRetryAttempts
+	if p.MaxAttempts > 0 {
+		attempts = p.MaxAttempts
+	}
+	if !replayable(req) {
+		attempts = 1
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+
+	for attempt := 1; ; attempt++ {
+		if !c.Breaker.allow() {
+			return nil, ErrCircuitOpen
+		}
+		var body io.ReadCloser
+		if attempt > 1 && req.GetBody != nil {
+			var err error
+			if body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := c.send(ctx, hc, req, body)
+		c.Breaker.record(ctx, resp, err)
+		if attempt >= attempts || ctx.Err() != nil || !retryable(resp, err) {
+			return resp, err
+		}
+
+		d := p.delay(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// send makes one attempt at req with ctx, replacing its body with body if
+// not nil and applying Timeout and the hooks.
+func (c *Client) send(ctx context.Context, hc *http.Client, req *http.Request, body io.ReadCloser) (*http.Response, error) {
+	var cancel context.CancelFunc
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+	}
+	r := req.Clone(ctx)
+	if body != nil {
+		r.Body = body
+	}
+	if c.OnRequest != nil {
+		c.OnRequest(r)
+	}
+	begin := time.Now()
+	resp, err := hc.Do(r)
+	if c.OnResponse != nil {
+		c.OnResponse(r, resp, err, time.Since(begin))
+	}
+	if cancel == nil {
+		return resp, err
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases the attempt's timeout once the body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// replayable reports whether req may be sent more than once.
+func replayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// delay returns the wait before retry n (n >= 1): the jittered exponential
+// backoff, or the response's Retry-After if that is longer.
+func (p *RetryPolicy) delay(n int, resp *http.Response) time.Duration {
+	d, limit := defaultRetryBackoff, defaultRetryMaxBackoff
+	if p.Backoff > 0 {
+		d = p.Backoff
+	}
+	if p.MaxBackoff > 0 {
+		limit = p.MaxBackoff
+	}
+	for i := 1; i < n && d < limit; i++ {
+		d *= 2
+	}
+	d = min(d, limit)
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	if resp != nil {
+		if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok && after > d {
+			d = after
+		}
+	}
+	return d
+}
+
+// retryAfter parses a Retry-After header value, given either in seconds or
+// as an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
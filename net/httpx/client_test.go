@@ -0,0 +1,233 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+// flakyServer answers with statuses (and header) in turn, then 200 OK
+// echoing the request body.
+func flakyServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestClientDo(t *testing.T) {
+	fast := httpx.RetryPolicy{Backoff: time.Millisecond}
+	tests := []struct {
+		name       string
+		method     string
+		reqHeader  http.Header
+		respHeader http.Header // sent with the failing statuses
+		statuses   []int
+		policy     httpx.RetryPolicy
+		wantCalls  int32
+		wantStatus int
+	}{
+		{"success", http.MethodGet, nil, nil, nil, fast, 1, http.StatusOK},
+		{"retried until success", http.MethodPut, nil, nil, []int{503, 502}, fast, 3, http.StatusOK},
+		{"attempts exhausted", http.MethodGet, nil, nil, []int{503, 503, 503}, fast, 3, http.StatusServiceUnavailable},
+		{"not retryable status", http.MethodGet, nil, nil, []int{500}, fast, 1, http.StatusInternalServerError},
+		{"POST not retried", http.MethodPost, nil, nil, []int{503}, fast, 1, http.StatusServiceUnavailable},
+		{"POST with Idempotency-Key", http.MethodPost, http.Header{"Idempotency-Key": {"k1"}}, nil, []int{503}, fast, 2, http.StatusOK},
+		{"Retry-After honoured", http.MethodGet, nil, http.Header{"Retry-After": {"0"}}, []int{429}, fast, 2, http.StatusOK},
+		{"MaxAttempts 1 disables retries", http.MethodGet, nil, nil, []int{503}, httpx.RetryPolicy{MaxAttempts: 1}, 1, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := flakyServer(t, tt.respHeader, tt.statuses...)
+			req, err := http.NewRequest(tt.method, srv.URL, strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.reqHeader {
+				req.Header[k] = v
+			}
+
+			c := &httpx.Client{Retry: tt.policy}
+			resp, err := c.Do(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("server called %d times, want %d", got, tt.wantCalls)
+			}
+			if resp.StatusCode == http.StatusOK && string(body) != "payload" {
+				t.Errorf("body = %q, want the request body replayed", body)
+			}
+		})
+	}
+}
+
+func TestClientDoContext(t *testing.T) {
+	srv, calls := flakyServer(t, http.Header{"Retry-After": {"3600"}}, 503)
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var c httpx.Client
+	if _, err := c.Do(ctx, req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Do() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("server called %d times, want 1", got)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	slow := make(chan struct{})
+	t.Cleanup(func() { close(slow) })
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			select { // first attempt hangs past the timeout
+			case <-slow:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(srv.Close)
+
+	var attempts []string
+	c := httpx.NewClient(
+		httpx.WithRequestTimeout(100*time.Millisecond),
+		httpx.WithRetry(httpx.RetryPolicy{Backoff: time.Millisecond}),
+		httpx.WithRequestHook(func(r *http.Request) { r.Header.Set("Authorization", "Bearer t") }),
+		httpx.WithResponseHook(func(r *http.Request, resp *http.Response, err error, took time.Duration) {
+			if err != nil {
+				attempts = append(attempts, "error")
+			} else {
+				attempts = append(attempts, resp.Status)
+			}
+		}),
+	)
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "Bearer t" {
+		t.Fatalf("body = %q, %v; want the hook's header echoed", body, err)
+	}
+	if want := []string{"error", "200 OK"}; strings.Join(attempts, ",") != strings.Join(want, ",") {
+		t.Fatalf("attempts = %v, want %v", attempts, want)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	srv, calls := flakyServer(t, nil, 500, 500, 500)
+	b := &httpx.CircuitBreaker{Threshold: 2, Cooldown: 50 * time.Millisecond}
+	c := httpx.NewClient(httpx.WithCircuitBreaker(b), httpx.WithRetry(httpx.RetryPolicy{MaxAttempts: 1}))
+	get := func() (int, error) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := c.Do(context.Background(), req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if status, err := get(); err != nil || status != 500 {
+			t.Fatalf("request %d = %d, %v", i, status, err)
+		}
+	}
+	if _, err := get(); !errors.Is(err, httpx.ErrCircuitOpen) || b.State() != httpx.BreakerOpen {
+		t.Fatalf("request after %d failures = %v (state %v), want %v", 2, err, b.State(), httpx.ErrCircuitOpen)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("server called %d times, want 2", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if b.State() != httpx.BreakerHalfOpen {
+		t.Fatalf("state after cooldown = %v, want %v", b.State(), httpx.BreakerHalfOpen)
+	}
+	if status, _ := get(); status != 500 || b.State() != httpx.BreakerOpen {
+		t.Fatalf("failed probe: status %d, state %v; want 500 and open", status, b.State())
+	}
+	time.Sleep(60 * time.Millisecond)
+	if status, _ := get(); status != 200 || b.State() != httpx.BreakerClosed {
+		t.Fatalf("successful probe: status %d, state %v; want 200 and closed", status, b.State())
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestWithRoundTripper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Traceparent")))
+	}))
+	t.Cleanup(srv.Close)
+	hc := &http.Client{}
+	c := httpx.NewClient(
+		httpx.WithHTTPClient(hc),
+		httpx.WithRoundTripper(func(rt http.RoundTripper) http.RoundTripper {
+			return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				r = r.Clone(r.Context())
+				r.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+				return rt.RoundTrip(r)
+			})
+		}),
+	)
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasPrefix(string(body), "00-4bf92f") {
+		t.Errorf("server saw Traceparent %q, want the wrapper's", body)
+	}
+	if hc.Transport != nil {
+		t.Error("WithRoundTripper modified the http.Client given to WithHTTPClient")
+	}
+}
@@ -0,0 +1,280 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rin2yh/gouse/bufpool"
+)
+
+const defaultCompressMinSize = 1024
+
+// defaultCompressTypes are the media types Compress compresses by default.
+var defaultCompressTypes = []string{
+	"text/*",
+	"application/json",
+	"application/*+json",
+	"application/javascript",
+	"application/xml",
+	"application/*+xml",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// CompressOptions controls Compress.
+type CompressOptions struct {
+	// MinSize is the smallest response body that is compressed; smaller
+	// ones are not worth the overhead. A response that is flushed before
+	// reaching it is compressed regardless, as it is being streamed.
+	// Defaults to 1024 bytes if zero.
+	MinSize int
+
+	// ContentTypes lists the media types to compress, as "type/subtype"
+	// with "*" wildcards (e.g. "text/*", "application/*+json"). Defaults
+	// to common text formats (HTML, CSS, JavaScript, JSON, XML, SVG, Wasm)
+	// if empty. Responses without a Content-Type are sniffed first.
+	ContentTypes []string
+
+	// Level is the compression level, from flate.BestSpeed (1) to
+	// flate.BestCompression (9), or flate.HuffmanOnly (-2). Defaults to
+	// flate.DefaultCompression if zero. Compress panics for other values.
+	Level int
+}
+
+// Compress returns middleware compressing responses with gzip or deflate,
+// as accepted by the client, and adding "Vary: Accept-Encoding". Responses
+// that already have a Content-Encoding, are partial (206) or have no body
+// are left alone, as are those excluded by opts. Compressors are pooled.
+// The response writer passed to the handler still supports Flush, which
+// flushes the compressor first, and Hijack. opts may be nil for the
+// defaults.
+func Compress(opts *CompressOptions) func(http.Handler) http.Handler {
+	if opts == nil {
+		opts = &CompressOptions{}
+	}
+	c := &compressor{minSize: defaultCompressMinSize, types: defaultCompressTypes, level: flate.DefaultCompression}
+	if opts.MinSize > 0 {
+		c.minSize = opts.MinSize
+	}
+	if len(opts.ContentTypes) > 0 {
+		c.types = opts.ContentTypes
+	}
+	if opts.Level != 0 {
+		c.level = opts.Level
+	}
+	// gzip and flate accept the same levels, so one check covers both
+	// pools, which then cannot fail.
+	if _, err := flate.NewWriter(io.Discard, c.level); err != nil {
+		panic("httpx: invalid compression level " + strconv.Itoa(c.level))
+	}
+	c.gzip.New = func() any {
+		zw, err := gzip.NewWriterLevel(io.Discard, c.level)
+		if err != nil {
+			panic(err)
+		}
+		return zw
+	}
+	c.flate.New = func() any {
+		zw, err := flate.NewWriter(io.Discard, c.level)
+		if err != nil {
+			panic(err)
+		}
+		return zw
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cw := &compressWriter{ResponseWriter: w, c: c, buf: bufpool.Get()}
+			switch ae := r.Header.Get("Accept-Encoding"); {
+			case acceptsEncoding(ae, "gzip"):
+				cw.encoding = "gzip"
+			case acceptsEncoding(ae, "deflate"):
+				cw.encoding = "deflate"
+			}
+			defer cw.release()
+			next.ServeHTTP(cw, r)
+			cw.finish()
+		})
+	}
+}
+
+type compressor struct {
+	minSize int
+	types   []string
+	level   int
+	gzip    sync.Pool // *gzip.Writer
+	flate   sync.Pool // *flate.Writer
+}
+
+// compressible reports whether responses of contentType are compressed.
+func (c *compressor) compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range c.types {
+		if mediaTypeMatch(pattern, mt) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaTypeMatch matches a media type against a pattern where "*" stands
+// for any run of characters other than "/".
+func mediaTypeMatch(pattern, mt string) bool {
+	pt, ps, _ := strings.Cut(pattern, "/")
+	t, s, _ := strings.Cut(mt, "/")
+	return globMatch(pt, t) && globMatch(ps, s)
+}
+
+// globMatch matches s against a pattern containing at most one "*".
+func globMatch(pattern, s string) bool {
+	prefix, suffix, ok := strings.Cut(pattern, "*")
+	if !ok {
+		return pattern == s
+	}
+	return len(s) >= len(prefix)+len(suffix) && strings.HasPrefix(s, prefix) && strings.HasSuffix(s, suffix)
+}
+
+// compressWriter buffers the start of the body until it knows whether to
+// compress: once MinSize bytes are written, on Flush, or when the handler
+// returns.
+type compressWriter struct {
+	http.ResponseWriter
+	c        *compressor
+	encoding string // accepted by the client; "" for none
+
+	status  int
+	buf     *bytes.Buffer
+	decided bool
+	zw      interface {
+		io.WriteCloser
+		Flush() error
+	}
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.status = code
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.decided {
+		if cw.zw != nil {
+			return cw.zw.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+	cw.buf.Write(b)
+	if cw.buf.Len() < cw.c.minSize {
+		return len(b), nil
+	}
+	return len(b), cw.decide(false)
+}
+
+// decide writes the header, compressing the body if it qualifies, and the
+// buffered body. final means the handler has returned.
+func (cw *compressWriter) decide(final bool) error {
+	cw.decided = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	h := cw.ResponseWriter.Header()
+	if h.Get("Content-Type") == "" && cw.buf.Len() > 0 && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Type", http.DetectContentType(cw.buf.Bytes()))
+	}
+	eligible := h.Get("Content-Encoding") == "" && cw.status >= 200 &&
+		cw.status != http.StatusNoContent && cw.status != http.StatusPartialContent &&
+		cw.status != http.StatusNotModified && cw.c.compressible(h.Get("Content-Type"))
+	if eligible {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if eligible && cw.encoding != "" && (!final || cw.buf.Len() >= cw.c.minSize) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+		if cw.encoding == "gzip" {
+			zw := cw.c.gzip.Get().(*gzip.Writer)
+			zw.Reset(cw.ResponseWriter)
+			cw.zw = zw
+		} else {
+			zw := cw.c.flate.Get().(*flate.Writer)
+			zw.Reset(cw.ResponseWriter)
+			cw.zw = zw
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if cw.zw != nil {
+		_, err = cw.zw.Write(cw.buf.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf.Bytes())
+	}
+	cw.buf.Reset()
+	return err
+}
+
+// Flush implements http.Flusher, flushing the compressor first.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(false)
+	}
+	if cw.zw != nil {
+		cw.zw.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker. Anything buffered is discarded.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(cw.ResponseWriter).Hijack()
+	if err == nil {
+		cw.decided = true
+		cw.buf.Reset()
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter { return cw.ResponseWriter }
+
+// finish completes the response once the handler has returned.
+func (cw *compressWriter) finish() {
+	if !cw.decided && (cw.status != 0 || cw.buf.Len() > 0) {
+		cw.decide(true)
+	}
+	if cw.zw != nil {
+		cw.zw.Close()
+	}
+}
+
+// release returns the buffer and compressor to their pools. It also runs
+// if the handler panicked, in which case the response is left unfinished.
+func (cw *compressWriter) release() {
+	switch zw := cw.zw.(type) {
+	case *gzip.Writer:
+		zw.Reset(io.Discard)
+		cw.c.gzip.Put(zw)
+	case *flate.Writer:
+		zw.Reset(io.Discard)
+		cw.c.flate.Put(zw)
+	}
+	bufpool.Put(cw.buf)
+}
@@ -0,0 +1,131 @@
+package httpx_test
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"k":"v"}`, 200)
+	respond := func(status int, ctype, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if ctype != "" {
+				w.Header().Set("Content-Type", ctype)
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}
+	}
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		acceptEncoding string
+		wantEncoding   string
+		wantVary       bool
+		wantBody       string
+	}{
+		{"gzip", respond(200, "application/json", large), "gzip, deflate", "gzip", true, large},
+		{"deflate", respond(200, "application/json", large), "deflate", "deflate", true, large},
+		{"below MinSize", respond(200, "application/json", `{}`), "gzip", "", true, `{}`},
+		{"not accepted", respond(200, "application/json", large), "", "", true, large},
+		{"gzip refused", respond(200, "application/json", large), "gzip;q=0", "", true, large},
+		{"sniffed type", respond(200, "", "<html>"+large), "gzip", "gzip", true, "<html>" + large},
+		{"excluded type", respond(200, "image/png", large), "gzip", "", false, large},
+		{"already encoded", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "br")
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(large))
+		}, "gzip", "br", false, large},
+		{"partial content", respond(http.StatusPartialContent, "text/plain", large), "gzip", "", false, large},
+		{"no body", respond(http.StatusNoContent, "", ""), "gzip", "", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			httpx.Compress(nil)(tt.handler).ServeHTTP(rec, req)
+
+			enc := rec.Header().Get("Content-Encoding")
+			if enc != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", enc, tt.wantEncoding)
+			}
+			if got := rec.Header().Get("Vary") == "Accept-Encoding"; got != tt.wantVary {
+				t.Errorf("Vary: Accept-Encoding set = %v, want %v", got, tt.wantVary)
+			}
+			if body := decodeBody(t, enc, rec.Body); body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func decodeBody(t *testing.T, enc string, r io.Reader) string {
+	t.Helper()
+	switch enc {
+	case "gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case "deflate":
+		r = flate.NewReader(r)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCompressStreaming(t *testing.T) {
+	rec := httptest.NewRecorder()
+	chunks := make(chan int, 2)
+	h := httpx.Compress(&httpx.CompressOptions{MinSize: 1 << 20})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		chunks <- rec.Body.Len()
+		if _, _, err := http.NewResponseController(w).Hijack(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Hijack() = %v, want %v", err, http.ErrNotSupported)
+		}
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(rec, req)
+
+	if n := <-chunks; n == 0 || !rec.Flushed {
+		t.Fatalf("nothing reached the client on Flush (%d bytes, flushed %v)", n, rec.Flushed)
+	}
+	if enc := rec.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("flushed stream Content-Encoding = %q, want gzip", enc)
+	}
+	if body := decodeBody(t, "gzip", rec.Body); body != "data: 1\n\n" {
+		t.Fatalf("body = %q", body)
+	}
+}
+
+func TestCompressInvalidLevel(t *testing.T) {
+	for _, level := range []int{-3, 12} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Compress with Level %d did not panic", level)
+				}
+			}()
+			httpx.Compress(&httpx.CompressOptions{Level: level})
+		}()
+	}
+	httpx.Compress(&httpx.CompressOptions{Level: flate.BestCompression}) // valid
+}
@@ -0,0 +1,92 @@
+// Package debugserver serves pprof, expvar, a liveness probe and
+// optionally metrics on a loopback-only admin server, run alongside an
+// httpx server:
+//
+//	m := httpx.NewMetrics("myapp", nil)
+//	err := httpx.Run(":8080", m.Middleware(mux),
+//	    httpx.WithServer(debugserver.New("127.0.0.1:6060", m)),
+//	)
+//
+// It is a separate package because importing net/http/pprof and expvar
+// registers their handlers on http.DefaultServeMux, which httpx itself must
+// not do to programs serving that mux publicly. Importing debugserver does
+// the same: a program that imports it must not serve http.DefaultServeMux
+// (or a nil handler) on a public address, or it exposes /debug/pprof/ and
+// /debug/vars there.
+package debugserver
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/rin2yh/gouse/net/graceful"
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+// ErrNotLoopback is returned by the ListenAndServe method of a server from
+// New whose address is not a loopback one.
+var ErrNotLoopback = errors.New("debugserver: address is not a loopback address")
+
+// Handler returns a handler serving:
+//
+//	/debug/pprof/...   net/http/pprof profiles
+//	/debug/vars        expvar variables
+//	/healthz           httpx.Healthz
+//	/metrics           metrics, if not nil
+func Handler(metrics http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle(httpx.HealthzPath, httpx.Healthz())
+	if metrics != nil {
+		mux.Handle("/metrics", metrics)
+	}
+	return mux
+}
+
+// New returns a server serving Handler(metrics) on addr, for
+// httpx.WithServer or graceful.RunAll. Profiles and variables disclose
+// internals, so addr must be a loopback address such as "127.0.0.1:6060",
+// "[::1]:6060" or "localhost:6060"; ListenAndServe returns ErrNotLoopback
+// otherwise. It has no write timeout, so CPU profiles and traces can run
+// for as long as requested.
+func New(addr string, metrics http.Handler) graceful.Server {
+	return &server{srv: httpx.NewServer(addr, Handler(metrics), httpx.WithWriteTimeout(0))}
+}
+
+type server struct {
+	srv *http.Server
+}
+
+func (s *server) ListenAndServe() error {
+	if err := checkLoopback(s.srv.Addr); err != nil {
+		return err
+	}
+	return s.srv.ListenAndServe()
+}
+
+func (s *server) Shutdown(ctx context.Context) error { return s.srv.Shutdown(ctx) }
+
+// checkLoopback reports ErrNotLoopback unless addr's host is "localhost"
+// or a loopback IP.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return ErrNotLoopback
+}
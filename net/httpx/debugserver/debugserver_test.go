@@ -0,0 +1,72 @@
+package debugserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx/debugserver"
+)
+
+func TestHandler(t *testing.T) {
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("up 1\n")) })
+	tests := []struct {
+		name    string
+		metrics http.Handler
+		path    string
+		want    int
+	}{
+		{"pprof index", nil, "/debug/pprof/", http.StatusOK},
+		{"pprof profile", nil, "/debug/pprof/goroutine?debug=1", http.StatusOK},
+		{"expvar", nil, "/debug/vars", http.StatusOK},
+		{"healthz", nil, "/healthz", http.StatusOK},
+		{"metrics", metrics, "/metrics", http.StatusOK},
+		{"no metrics", nil, "/metrics", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			debugserver.Handler(tt.metrics).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("GET %s = %d, want %d", tt.path, rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestVars(t *testing.T) {
+	expvar.NewInt("debugserver_test_requests").Set(3)
+	rec := httptest.NewRecorder()
+	debugserver.Handler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("/debug/vars body is not JSON: %v", err)
+	}
+	for _, name := range []string{"cmdline", "memstats", "debugserver_test_requests"} {
+		if _, ok := vars[name]; !ok {
+			t.Errorf("/debug/vars lacks %q", name)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, addr := range []string{":6060", "0.0.0.0:6060", "192.0.2.1:6060", "example.com:6060"} {
+		if err := debugserver.New(addr, nil).ListenAndServe(); !errors.Is(err, debugserver.ErrNotLoopback) {
+			t.Errorf("ListenAndServe() on %q = %v, want %v", addr, err, debugserver.ErrNotLoopback)
+		}
+	}
+
+	srv := debugserver.New("127.0.0.1:0", nil)
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe() }()
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("ListenAndServe() = %v, want %v", err, http.ErrServerClosed)
+	}
+}
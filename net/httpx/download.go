@@ -0,0 +1,289 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned by Download when the downloaded file
+// does not match DownloadOptions.Checksum.
+var ErrChecksumMismatch = errors.New("httpx: checksum mismatch")
+
+// DownloadOptions controls Download.
+type DownloadOptions struct {
+	// Checksum, if set, is the expected digest of the file, computed with
+	// Hash. A file that does not match is deleted.
+	Checksum []byte
+
+	// Hash computes Checksum. Defaults to sha256.New if nil.
+	Hash func() hash.Hash
+
+	// Progress, if set, is called as the file is written with the bytes
+	// written so far, resumed ones included, and the file's size, or -1
+	// if the server does not say.
+	Progress func(done, total int64)
+}
+
+// Download fetches url with c into the file dst, replacing it. The data
+// is written to dst+".part" first and renamed once complete, so dst is
+// never left partial. If a download fails, calling Download again resumes
+// it with a Range request; servers that ignore the range send the whole
+// file again. Requests are retried as c's RetryPolicy says; a nil c is the
+// zero Client. opts may be nil for no checksum or progress.
+//
+//	err := httpx.Download(ctx, c, "https://example.com/big.iso", "big.iso", &httpx.DownloadOptions{
+//	    Checksum: want,
+//	    Progress: func(done, total int64) { bar.Set(done, total) },
+//	})
+func Download(ctx context.Context, c *Client, url, dst string, opts *DownloadOptions) error {
+	if c == nil {
+		c = &Client{}
+	}
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	var h hash.Hash
+	if opts.Checksum != nil {
+		h = sha256.New()
+		if opts.Hash != nil {
+			h = opts.Hash()
+		}
+	}
+
+	part := dst + ".part"
+	f, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	offset, err := resumeFrom(f, h)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return fmt.Errorf("httpx: downloading %s: unexpected Content-Range %q", req.URL.Redacted(), resp.Header.Get("Content-Range"))
+		}
+		total = size
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The part may already be complete; otherwise start over next time.
+		if _, size, ok := parseContentRange(resp.Header.Get("Content-Range")); !ok || size != offset {
+			f.Truncate(0)
+			return fmt.Errorf("httpx: downloading %s: %s", req.URL.Redacted(), resp.Status)
+		}
+		total = offset
+	case resp.StatusCode == http.StatusOK:
+		if offset, err = restart(f, h); err != nil {
+			return err
+		}
+		total = resp.ContentLength
+	default:
+		return fmt.Errorf("httpx: downloading %s: %s", req.URL.Redacted(), resp.Status)
+	}
+
+	var w io.Writer = f
+	if h != nil {
+		w = io.MultiWriter(f, h)
+	}
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		body := &progressReader{r: resp.Body, n: offset, total: total, progress: opts.Progress}
+		if _, err := io.Copy(w, body); err != nil {
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if h != nil {
+		if sum := h.Sum(nil); !bytes.Equal(sum, opts.Checksum) {
+			os.Remove(part)
+			return fmt.Errorf("%w: got %s, want %s", ErrChecksumMismatch, hex.EncodeToString(sum), hex.EncodeToString(opts.Checksum))
+		}
+	}
+	return os.Rename(part, dst)
+}
+
+// resumeFrom returns the size of the partial file f, leaving its offset
+// at the end, and feeds its contents to h if not nil.
+func resumeFrom(f *os.File, h hash.Hash) (int64, error) {
+	if h != nil {
+		return io.Copy(h, f)
+	}
+	return f.Seek(0, io.SeekEnd)
+}
+
+// restart empties the partial file f and resets h, for a server sending
+// the whole file.
+func restart(f *os.File, h hash.Hash) (int64, error) {
+	if h != nil {
+		h.Reset()
+	}
+	if err := f.Truncate(0); err != nil {
+		return 0, err
+	}
+	return f.Seek(0, io.SeekStart)
+}
+
+// parseContentRange parses "bytes start-end/size" or "bytes */size",
+// returning -1 for an unknown start or size.
+func parseContentRange(v string) (start, size int64, ok bool) {
+	rng, ok := strings.CutPrefix(v, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rng, sz, ok := strings.Cut(rng, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	size, start = -1, -1
+	var err error
+	if sz != "*" {
+		if size, err = strconv.ParseInt(sz, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if rng != "*" {
+		first, _, _ := strings.Cut(rng, "-")
+		if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, size, true
+}
+
+// progressReader reports the bytes read through it, counting from n.
+type progressReader struct {
+	r        io.Reader
+	n, total int64
+	progress func(done, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	if n > 0 && p.progress != nil {
+		p.progress(p.n, p.total)
+	}
+	return n, err
+}
+
+// UploadFile is a file part of a multipart upload.
+type UploadFile struct {
+	// Field is the form field name.
+	Field string
+
+	// Name is the file name sent to the server.
+	Name string
+
+	// Content is read as the upload progresses. If it is an io.Closer, it
+	// is closed once read.
+	Content io.Reader
+}
+
+// UploadOptions controls Upload.
+type UploadOptions struct {
+	// Method is the request method. Defaults to POST if empty.
+	Method string
+
+	// Fields are form fields sent before the files, in key order.
+	Fields map[string]string
+
+	// Progress, if set, is called as the body is sent with the bytes of
+	// file content sent so far.
+	Progress func(sent int64)
+}
+
+// Upload sends files to url with c as a multipart/form-data request,
+// streaming the body as it is sent instead of buffering it, so files of
+// any size use constant memory. As the body cannot be replayed, the
+// request is not retried. The caller must close the response body. A nil c
+// is the zero Client; opts may be nil for a POST without fields.
+func Upload(ctx context.Context, c *Client, url string, files []UploadFile, opts *UploadOptions) (*http.Response, error) {
+	if c == nil {
+		c = &Client{}
+	}
+	if opts == nil {
+		opts = &UploadOptions{}
+	}
+	method := opts.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	req, err := http.NewRequest(method, url, pr)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	go func() { pw.CloseWithError(writeMultipart(mw, files, opts)) }()
+
+	resp, err := c.Do(ctx, req)
+	pr.CloseWithError(errors.New("httpx: upload ended")) // stop the writer if the body was not fully sent
+	return resp, err
+}
+
+// writeMultipart writes the fields and files of an upload to mw.
+func writeMultipart(mw *multipart.Writer, files []UploadFile, opts *UploadOptions) error {
+	keys := make([]string, 0, len(opts.Fields))
+	for k := range opts.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := mw.WriteField(k, opts.Fields[k]); err != nil {
+			return err
+		}
+	}
+	var sent int64
+	for _, file := range files {
+		part, err := mw.CreateFormFile(file.Field, file.Name)
+		if err != nil {
+			return err
+		}
+		var p func(int64, int64)
+		if opts.Progress != nil {
+			p = func(done, _ int64) { opts.Progress(done) }
+		}
+		r := &progressReader{r: file.Content, n: sent, progress: p}
+		_, err = io.Copy(part, r)
+		sent = r.n
+		if rc, ok := file.Content.(io.Closer); ok {
+			rc.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
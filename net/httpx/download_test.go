@@ -0,0 +1,125 @@
+package httpx_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	sum := sha256.Sum256(content)
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name      string
+		partial   []byte // left by an earlier attempt
+		wantRange string
+	}{
+		{"fresh", nil, ""},
+		{"resumed", content[:4000], "bytes=4000-"},
+		{"already complete", content, "bytes=10000-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges = nil
+			dst := filepath.Join(t.TempDir(), "file")
+			if tt.partial != nil {
+				if err := os.WriteFile(dst+".part", tt.partial, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var last, total int64
+			err := httpx.Download(context.Background(), nil, srv.URL, dst, &httpx.DownloadOptions{
+				Checksum: sum[:],
+				Progress: func(done, size int64) { last, total = done, size },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(dst)
+			if err != nil || !bytes.Equal(got, content) {
+				t.Fatalf("downloaded %d bytes (%v), want the content", len(got), err)
+			}
+			if _, err := os.Stat(dst + ".part"); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("partial file left behind: %v", err)
+			}
+			if len(ranges) != 1 || ranges[0] != tt.wantRange {
+				t.Errorf("requests with Range %q, want one with %q", ranges, tt.wantRange)
+			}
+			if tt.partial == nil || len(tt.partial) < len(content) {
+				if last != int64(len(content)) || total != int64(len(content)) {
+					t.Errorf("last progress = %d/%d, want %d/%d", last, total, len(content), len(content))
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tampered"))
+	}))
+	t.Cleanup(srv.Close)
+	dst := filepath.Join(t.TempDir(), "file")
+	err := httpx.Download(context.Background(), nil, srv.URL, dst, &httpx.DownloadOptions{Checksum: make([]byte, sha256.Size)})
+	if !errors.Is(err, httpx.ErrChecksumMismatch) {
+		t.Fatalf("Download() = %v, want %v", err, httpx.ErrChecksumMismatch)
+	}
+	for _, name := range []string{dst, dst + ".part"} {
+		if _, err := os.Stat(name); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s exists after a checksum mismatch", name)
+		}
+	}
+}
+
+func TestUpload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, hdr, err := r.FormFile("doc")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		body, _ := io.ReadAll(f)
+		w.Write([]byte(r.Method + " " + r.FormValue("title") + " " + hdr.Filename + " " + string(body)))
+	}))
+	t.Cleanup(srv.Close)
+
+	var sent int64
+	resp, err := httpx.Upload(context.Background(), nil, srv.URL,
+		[]httpx.UploadFile{{Field: "doc", Name: "a.txt", Content: strings.NewReader("hello")}},
+		&httpx.UploadOptions{Method: http.MethodPut, Fields: map[string]string{"title": "greeting"}, Progress: func(n int64) { sent = n }},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if want := "PUT greeting a.txt hello"; string(body) != want {
+		t.Fatalf("server got %q, want %q", body, want)
+	}
+	if sent != 5 {
+		t.Errorf("progress = %d, want 5", sent)
+	}
+}
@@ -0,0 +1,187 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rin2yh/gouse/bufpool"
+)
+
+const defaultETagMaxSize = 1 << 20
+
+// ETagOptions controls ETag.
+type ETagOptions struct {
+	// Weak makes computed ETags weak (W/"..."), for responses that are
+	// semantically but not byte-for-byte stable, e.g. re-encoded JSON.
+	Weak bool
+
+	// MaxSize is the largest body buffered to compute an ETag; larger or
+	// flushed responses are passed through unchanged. Defaults to 1 MiB if
+	// zero.
+	MaxSize int
+}
+
+// ETag returns middleware handling conditional GET and HEAD requests. It
+// buffers 200 OK responses, sets an ETag from a hash of the body unless
+// the handler set one, and answers 304 Not Modified without the body when
+// If-None-Match matches it or, without If-None-Match, when If-Modified-Since
+// is not before the handler's Last-Modified header. opts may be nil for the
+// defaults.
+func ETag(opts *ETagOptions) func(http.Handler) http.Handler {
+	if opts == nil {
+		opts = &ETagOptions{}
+	}
+	weak, maxSize := opts.Weak, opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultETagMaxSize
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ew := &etagWriter{ResponseWriter: w, buf: bufpool.Get(), maxSize: maxSize}
+			defer bufpool.Put(ew.buf)
+			next.ServeHTTP(ew, r)
+			ew.finish(r, weak)
+		})
+	}
+}
+
+// etagWriter buffers a response until the handler returns, or passes it
+// through once it is flushed or outgrows maxSize.
+type etagWriter struct {
+	http.ResponseWriter
+	buf         *bytes.Buffer
+	maxSize     int
+	status      int
+	passthrough bool
+}
+
+func (ew *etagWriter) WriteHeader(code int) {
+	switch {
+	case ew.passthrough:
+		ew.ResponseWriter.WriteHeader(code)
+	case code >= 100 && code < 200 && code != http.StatusSwitchingProtocols:
+		ew.ResponseWriter.WriteHeader(code)
+	case ew.status == 0:
+		ew.status = code
+	}
+}
+
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if ew.passthrough {
+		return ew.ResponseWriter.Write(b)
+	}
+	if ew.status == 0 {
+		ew.status = http.StatusOK
+	}
+	if ew.buf.Len()+len(b) > ew.maxSize {
+		if err := ew.pass(); err != nil {
+			return 0, err
+		}
+		return ew.ResponseWriter.Write(b)
+	}
+	return ew.buf.Write(b)
+}
+
+// pass switches to passing the response through, writing what was held.
+func (ew *etagWriter) pass() error {
+	ew.passthrough = true
+	if ew.status != 0 {
+		ew.ResponseWriter.WriteHeader(ew.status)
+	}
+	if ew.buf.Len() == 0 {
+		return nil
+	}
+	_, err := ew.ResponseWriter.Write(ew.buf.Bytes())
+	ew.buf.Reset()
+	return err
+}
+
+// Flush implements http.Flusher; a flushed response gets no ETag.
+func (ew *etagWriter) Flush() {
+	if !ew.passthrough {
+		ew.pass()
+	}
+	http.NewResponseController(ew.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker.
+func (ew *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(ew.ResponseWriter).Hijack()
+	if err == nil {
+		ew.passthrough = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (ew *etagWriter) Unwrap() http.ResponseWriter { return ew.ResponseWriter }
+
+// finish sends the buffered response, or 304 if the request's conditions
+// say the client has it already.
+func (ew *etagWriter) finish(r *http.Request, weak bool) {
+	if ew.passthrough {
+		return
+	}
+	if ew.status == 0 {
+		ew.status = http.StatusOK
+	}
+	h := ew.Header()
+	if ew.status == http.StatusOK {
+		if h.Get("ETag") == "" && r.Method == http.MethodGet {
+			sum := sha256.Sum256(ew.buf.Bytes())
+			h.Set("ETag", formatETag(sum[:], weak))
+		}
+		if notModified(r, h) {
+			for _, k := range []string{"Content-Type", "Content-Length", "Content-Encoding"} {
+				h.Del(k)
+			}
+			ew.ResponseWriter.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	ew.ResponseWriter.WriteHeader(ew.status)
+	ew.ResponseWriter.Write(ew.buf.Bytes())
+}
+
+// formatETag returns an ETag built from a content hash.
+func formatETag(sum []byte, weak bool) string {
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:12]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// notModified evaluates If-None-Match, or else If-Modified-Since, against
+// the response header h.
+func notModified(r *http.Request, h http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := h.Get("ETag")
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lm, err := http.ParseTime(h.Get("Last-Modified"))
+	return err == nil && !lm.Truncate(time.Second).After(ims)
+}
@@ -0,0 +1,72 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestETag(t *testing.T) {
+	body := `{"items":[1,2,3]}`
+	const lastModified = "Wed, 21 Oct 2015 07:28:00 GMT"
+	plain := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(body)) }
+
+	// Learn the computed ETag.
+	rec := httptest.NewRecorder()
+	httpx.ETag(nil)(http.HandlerFunc(plain)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || rec.Body.String() != body {
+		t.Fatalf("first response = %q with ETag %q", rec.Body.String(), etag)
+	}
+
+	tests := []struct {
+		name       string
+		opts       *httpx.ETagOptions
+		handler    http.HandlerFunc
+		method     string
+		header     map[string]string
+		wantStatus int
+		wantBody   string
+		wantETag   string
+	}{
+		{"If-None-Match matches", nil, plain, "GET", map[string]string{"If-None-Match": `"x", ` + etag}, 304, "", etag},
+		{"If-None-Match differs", nil, plain, "GET", map[string]string{"If-None-Match": `"x"`}, 200, body, etag},
+		{"weak ETag", &httpx.ETagOptions{Weak: true}, plain, "GET", map[string]string{"If-None-Match": etag}, 304, "", "W/" + etag},
+		{"handler ETag", nil, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v7"`)
+			plain(w, r)
+		}, "HEAD", map[string]string{"If-None-Match": `W/"v7"`}, 304, "", `"v7"`},
+		{"If-Modified-Since", nil, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Last-Modified", lastModified)
+			plain(w, r)
+		}, "GET", map[string]string{"If-Modified-Since": lastModified}, 304, "", etag},
+		{"error not cached", nil, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", http.StatusInternalServerError)
+		}, "GET", map[string]string{"If-None-Match": "*"}, 500, "nope\n", ""},
+		{"too large", &httpx.ETagOptions{MaxSize: 4}, plain, "GET", nil, 200, body, ""},
+		{"flushed", nil, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+			w.(http.Flusher).Flush()
+		}, "GET", map[string]string{"If-None-Match": "*"}, 200, body, ""},
+		{"POST ignored", nil, plain, "POST", map[string]string{"If-None-Match": "*"}, 200, body, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			httpx.ETag(tt.opts)(tt.handler).ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus || rec.Body.String() != tt.wantBody {
+				t.Fatalf("response = %d %q, want %d %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			if got := rec.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package httpx
+
+import "errors"
+
+// ErrH2CUnsupported is returned by Run when WithH2C is used with a Go
+// toolchain older than 1.24, whose net/http cannot serve HTTP/2 without TLS.
+var ErrH2CUnsupported = errors.New("httpx: h2c requires Go 1.24 or later")
+
+// WithH2C serves HTTP/2 without TLS (h2c, with prior knowledge) alongside
+// HTTP/1.1, as gRPC clients and load balancers such as AWS ALB speak to
+// backends. It is ignored with WithTLS or WithTLSConfig, which already
+// negotiate HTTP/2.
+//
+// Streams are limited to 250 per connection, and idle connections are
+// pinged after 15s and closed if the ping is not answered within 15s.
+func WithH2C() Option {
+	return func(o *options) { o.h2c = true }
+}
@@ -0,0 +1,25 @@
+//go:build go1.24
+
+package httpx
+
+import (
+	"net/http"
+	"time"
+)
+
+// configureH2C enables unencrypted HTTP/2 on srv if WithH2C was given.
+func (o *options) configureH2C(srv *http.Server) error {
+	if !o.h2c || o.tls {
+		return nil
+	}
+	var p http.Protocols
+	p.SetHTTP1(true)
+	p.SetUnencryptedHTTP2(true)
+	srv.Protocols = &p
+	srv.HTTP2 = &http.HTTP2Config{
+		MaxConcurrentStreams: 250,
+		SendPingTimeout:      15 * time.Second,
+		PingTimeout:          15 * time.Second,
+	}
+	return nil
+}
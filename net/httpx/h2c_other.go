@@ -0,0 +1,13 @@
+//go:build !go1.24
+
+package httpx
+
+import "net/http"
+
+// configureH2C reports ErrH2CUnsupported if WithH2C was given.
+func (o *options) configureH2C(*http.Server) error {
+	if !o.h2c || o.tls {
+		return nil
+	}
+	return ErrH2CUnsupported
+}
@@ -0,0 +1,43 @@
+//go:build go1.24
+
+package httpx_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestRunWithContextH2C(t *testing.T) {
+	var p http.Protocols
+	p.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &p}}
+
+	protos := make(chan string, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case protos <- r.Proto:
+		default:
+		}
+	})
+	ln, addr := listen(t)
+	cancel, done := startRun(t, client, "http://"+addr+"/", func(ctx context.Context) error {
+		return httpx.RunWithContext(ctx, "", handler, httpx.WithListener(ln), httpx.WithH2C())
+	})
+	if proto := <-protos; proto != "HTTP/2.0" {
+		t.Errorf("request served over %s, want HTTP/2.0", proto)
+	}
+	// HTTP/1.1 clients are still served.
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("RunWithContext() = %v, want nil", err)
+	}
+}
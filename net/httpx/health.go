@@ -0,0 +1,90 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/rin2yh/gouse/net/graceful"
+)
+
+// Paths at which WithHealthEndpoints mounts Healthz and Readyz.
+const (
+	HealthzPath = "/healthz"
+	ReadyzPath  = "/readyz"
+)
+
+// Healthz returns a liveness probe handler: it responds 200 OK as long as
+// the process can serve requests at all, including while draining.
+func Healthz() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeProbe(w, http.StatusOK, "ok\n")
+	})
+}
+
+// Readyz returns a readiness probe handler that calls checks in order with
+// the request context (e.g. pinging a database) and responds 200 OK if all
+// pass, or 503 Service Unavailable listing the errors otherwise.
+func Readyz(checks ...func(context.Context) error) http.Handler {
+	return readyz(nil, checks)
+}
+
+// WithHealthEndpoints serves Healthz at /healthz and Readyz(checks...) at
+// /readyz in front of the handler. Once shutdown begins, /readyz responds
+// 503 without running the checks, so load balancers stop routing new
+// traffic while in-flight requests drain.
+func WithHealthEndpoints(checks ...func(context.Context) error) Option {
+	return func(o *options) {
+		o.health = true
+		o.healthChecks = append(o.healthChecks, checks...)
+	}
+}
+
+// withHealth routes the health paths to their handlers and the rest to
+// next, reporting draining through ready.
+func (o *options) withHealth(next http.Handler) http.Handler {
+	if !o.health {
+		return next
+	}
+	ready := &graceful.Readiness{}
+	o.cfg.Readiness = ready
+	healthz, readyz := Healthz(), readyz(ready, o.healthChecks)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case HealthzPath:
+			healthz.ServeHTTP(w, r)
+		case ReadyzPath:
+			readyz.ServeHTTP(w, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// readyz is Readyz that also fails once ready, if not nil, is draining.
+func readyz(ready *graceful.Readiness, checks []func(context.Context) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ready != nil && ready.Draining() {
+			writeProbe(w, http.StatusServiceUnavailable, "shutting down\n")
+			return
+		}
+		var errs []error
+		for _, check := range checks {
+			if err := check(r.Context()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			writeProbe(w, http.StatusServiceUnavailable, err.Error()+"\n")
+			return
+		}
+		writeProbe(w, http.StatusOK, "ok\n")
+	})
+}
+
+func writeProbe(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
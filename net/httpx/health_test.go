@@ -0,0 +1,81 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestHealthHandlers(t *testing.T) {
+	errDB := errors.New("db down")
+	ok := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errDB }
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		wantStatus int
+		wantBody   string
+	}{
+		{"Healthz", httpx.Healthz(), http.StatusOK, "ok\n"},
+		{"Readyz without checks", httpx.Readyz(), http.StatusOK, "ok\n"},
+		{"Readyz checks pass", httpx.Readyz(ok, ok), http.StatusOK, "ok\n"},
+		{"Readyz check fails", httpx.Readyz(ok, fail), http.StatusServiceUnavailable, "db down\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.wantStatus || rec.Body.String() != tt.wantBody {
+				t.Fatalf("response = %d %q, want %d %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWithHealthEndpoints(t *testing.T) {
+	ln, addr := listen(t)
+	base := "http://" + addr
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	cancel, done := startRun(t, client, base+"/", func(ctx context.Context) error {
+		return httpx.RunWithContext(ctx, "", http.NotFoundHandler(),
+			httpx.WithListener(ln),
+			httpx.WithHealthEndpoints(),
+			httpx.WithShutdownDelay(time.Second),
+		)
+	})
+	get := func(path string) int {
+		resp, err := client.Get(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for path, want := range map[string]int{"/healthz": 200, "/readyz": 200, "/other": 404} {
+		if got := get(path); got != want {
+			t.Errorf("GET %s = %d, want %d", path, got, want)
+		}
+	}
+
+	cancel()
+	deadline := time.Now().Add(testStartTimeout)
+	for get("/readyz") != http.StatusServiceUnavailable {
+		if time.Now().After(deadline) {
+			t.Fatal("/readyz did not fail once shutdown began")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := get("/healthz"); got != http.StatusOK {
+		t.Errorf("GET /healthz while draining = %d, want 200", got)
+	}
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("RunWithContext() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,158 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// WithTrackHijacked makes shutdown wait for hijacked connections, such as
+// WebSockets, which http.Server.Shutdown ignores. Connections taken over
+// with Hijack are counted until closed; once shutdown begins the context
+// returned by ShutdownContext is cancelled so handlers can say goodbye to
+// their peers, and Run waits for the connections to close within the
+// shutdown timeout, before the WithCleanup functions run. Connections
+// still open then are reported in Run's error, and closed if WithForceClose
+// is given.
+func WithTrackHijacked() Option {
+	return func(o *options) { o.trackHijacked = true }
+}
+
+type shutdownCtxKey struct{}
+
+// ShutdownContext returns a context cancelled once shutdown begins, for
+// handlers serving hijacked connections under WithTrackHijacked; ctx is a
+// request context. Without WithTrackHijacked it is never cancelled.
+func ShutdownContext(ctx context.Context) context.Context {
+	if sctx, ok := ctx.Value(shutdownCtxKey{}).(context.Context); ok {
+		return sctx
+	}
+	return context.Background()
+}
+
+// withHijackTracking wraps next so its hijacked connections are tracked,
+// and hooks the registry into the shutdown sequence.
+func (o *options) withHijackTracking(next http.Handler) http.Handler {
+	if !o.trackHijacked {
+		return next
+	}
+	reg := newHijackRegistry()
+	prev := o.cfg.OnShutdownBegin
+	o.cfg.OnShutdownBegin = func() {
+		reg.cancel()
+		if prev != nil {
+			prev()
+		}
+	}
+	force := o.cfg.ForceCloseAfterTimeout
+	o.cfg.Cleanups = append([]func(context.Context) error{func(ctx context.Context) error {
+		return reg.wait(ctx, force)
+	}}, o.cfg.Cleanups...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), shutdownCtxKey{}, reg.ctx)
+		next.ServeHTTP(&hijackWriter{ResponseWriter: w, reg: reg}, r.WithContext(ctx))
+	})
+}
+
+// hijackRegistry tracks the open hijacked connections.
+type hijackRegistry struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	conns   map[*trackedConn]struct{}
+	changed chan struct{} // closed and replaced whenever a conn closes
+}
+
+func newHijackRegistry() *hijackRegistry {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &hijackRegistry{ctx: ctx, cancel: cancel, conns: make(map[*trackedConn]struct{}), changed: make(chan struct{})}
+}
+
+func (reg *hijackRegistry) add(c *trackedConn) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.conns[c] = struct{}{}
+}
+
+func (reg *hijackRegistry) remove(c *trackedConn) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	delete(reg.conns, c)
+	close(reg.changed)
+	reg.changed = make(chan struct{})
+}
+
+// wait blocks until every tracked connection has closed or ctx is done,
+// closing the remaining ones in the latter case if force is set.
+func (reg *hijackRegistry) wait(ctx context.Context, force bool) error {
+	for {
+		reg.mu.Lock()
+		n, changed := len(reg.conns), reg.changed
+		reg.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			if force {
+				reg.closeAll()
+			}
+			return fmt.Errorf("httpx: %d hijacked connections still open: %w", n, ctx.Err())
+		}
+	}
+}
+
+func (reg *hijackRegistry) closeAll() {
+	reg.mu.Lock()
+	conns := make([]*trackedConn, 0, len(reg.conns))
+	for c := range reg.conns {
+		conns = append(conns, c)
+	}
+	reg.mu.Unlock()
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// hijackWriter registers the connections its handler hijacks.
+type hijackWriter struct {
+	http.ResponseWriter
+	reg *hijackRegistry
+}
+
+// Hijack implements http.Hijacker.
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	c := &trackedConn{Conn: conn, reg: w.reg}
+	w.reg.add(c)
+	return c, rw, nil
+}
+
+// Flush implements http.Flusher.
+func (w *hijackWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *hijackWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// trackedConn unregisters itself when closed.
+type trackedConn struct {
+	net.Conn
+	reg  *hijackRegistry
+	once sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() { c.reg.remove(c) })
+	return err
+}
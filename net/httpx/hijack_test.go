@@ -0,0 +1,114 @@
+package httpx_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+// dialHijack sends a request to the hijacking handler at addr and returns
+// the connection once the handler has taken it over.
+func dialHijack(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: x\r\n\r\n"))
+	br := bufio.NewReader(conn)
+	if line, err := br.ReadString('\n'); err != nil || line != "hello\n" {
+		t.Fatalf("greeting = %q, %v", line, err)
+	}
+	return conn, br
+}
+
+func TestWithTrackHijacked(t *testing.T) {
+	var closed atomic.Bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ws" {
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		rw.WriteString("hello\n")
+		rw.Flush()
+		<-httpx.ShutdownContext(r.Context()).Done()
+		time.Sleep(50 * time.Millisecond) // closing handshake
+		rw.WriteString("bye\n")
+		rw.Flush()
+		closed.Store(true)
+		conn.Close()
+	})
+	ln, addr := listen(t)
+	var closedBeforeCleanup bool
+	cancel, done := startRun(t, http.DefaultClient, "http://"+addr+"/", func(ctx context.Context) error {
+		return httpx.RunWithContext(ctx, "", handler,
+			httpx.WithListener(ln),
+			httpx.WithTrackHijacked(),
+			httpx.WithCleanup(func(context.Context) error {
+				closedBeforeCleanup = closed.Load()
+				return nil
+			}),
+		)
+	})
+	_, br := dialHijack(t, addr)
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("RunWithContext() = %v, want nil", err)
+	}
+	if rest, _ := io.ReadAll(br); string(rest) != "bye\n" {
+		t.Errorf("after shutdown the peer read %q, want bye", rest)
+	}
+	if !closedBeforeCleanup {
+		t.Error("cleanups ran before the hijacked connection closed")
+	}
+}
+
+func TestWithTrackHijackedTimeout(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ws" {
+			return
+		}
+		_, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		rw.WriteString("hello\n")
+		rw.Flush() // never closed
+	})
+	ln, addr := listen(t)
+	cancel, done := startRun(t, http.DefaultClient, "http://"+addr+"/", func(ctx context.Context) error {
+		return httpx.RunWithContext(ctx, "", handler,
+			httpx.WithListener(ln),
+			httpx.WithTrackHijacked(),
+			httpx.WithShutdownTimeout(100*time.Millisecond),
+			httpx.WithForceClose(0),
+		)
+	})
+	conn, br := dialHijack(t, addr)
+
+	cancel()
+	err := awaitShutdown(t, done)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "1 hijacked connections still open") {
+		t.Fatalf("RunWithContext() = %v, want the open hijacked connection reported", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(testShutdownTimeout))
+	if _, err := br.ReadByte(); err != io.EOF {
+		t.Fatalf("read after forced shutdown = %v, want EOF", err)
+	}
+}
@@ -0,0 +1,82 @@
+package httpx
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Hosts returns a handler routing requests by the host name they were sent
+// to, so one server can serve several domains:
+//
+//	h := httpx.Hosts(map[string]http.Handler{
+//	    "example.com":       site,
+//	    "api.example.com":   api,
+//	    "*.tenants.example": tenants,
+//	}, nil)
+//
+// Patterns are host names without a port, matched case-insensitively. A
+// pattern starting with "*." matches any subdomain, at any depth, of the
+// rest but not the rest itself. Exact names take precedence over wildcards,
+// and longer wildcards over shorter ones. Requests for other hosts go to
+// fallback, or get 404 Not Found if it is nil.
+//
+// Hosts panics if a pattern is empty or has a "*" anywhere but at its
+// start, as http.ServeMux does for invalid patterns.
+func Hosts(hosts map[string]http.Handler, fallback http.Handler) http.Handler {
+	exact := make(map[string]http.Handler)
+	var wildcards []hostWildcard
+	for pattern, h := range hosts {
+		p := normalizeHost(pattern)
+		if suffix, ok := strings.CutPrefix(p, "*"); ok && strings.HasPrefix(suffix, ".") && len(suffix) > 1 && !strings.Contains(suffix, "*") {
+			wildcards = append(wildcards, hostWildcard{suffix, h})
+			continue
+		}
+		if p == "" || strings.Contains(p, "*") {
+			panic("httpx: invalid host pattern " + pattern)
+		}
+		exact[p] = h
+	}
+	sort.Slice(wildcards, func(i, j int) bool { return len(wildcards[i].suffix) > len(wildcards[j].suffix) })
+	if fallback == nil {
+		fallback = http.NotFoundHandler()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := requestHost(r)
+		if h, ok := exact[host]; ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		for _, wc := range wildcards {
+			if len(host) > len(wc.suffix) && strings.HasSuffix(host, wc.suffix) {
+				wc.handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		fallback.ServeHTTP(w, r)
+	})
+}
+
+// hostWildcard is a "*." pattern of Hosts; suffix keeps the leading dot.
+type hostWildcard struct {
+	suffix  string
+	handler http.Handler
+}
+
+// requestHost returns the normalized host name r was sent to.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return normalizeHost(host)
+}
+
+// normalizeHost lowercases host and drops a trailing dot and IPv6
+// brackets, so "Example.COM." and "example.com" match, as do "[::1]" and
+// "::1".
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+}
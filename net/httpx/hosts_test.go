@@ -0,0 +1,72 @@
+package httpx_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func named(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, name) })
+}
+
+func TestHosts(t *testing.T) {
+	h := httpx.Hosts(map[string]http.Handler{
+		"example.com":         named("site"),
+		"api.example.com":     named("api"),
+		"*.example.com":       named("any"),
+		"*.eu.example.com":    named("eu"),
+		"[::1]":               named("ipv6"),
+		"Mixed.Case.Example.": named("mixed"),
+	}, named("fallback"))
+	tests := []struct{ host, want string }{
+		{"example.com", "site"},
+		{"example.com:8080", "site"},
+		{"EXAMPLE.com.", "site"},
+		{"api.example.com", "api"},
+		{"www.example.com", "any"},
+		{"a.b.example.com", "any"},
+		{"x.eu.example.com", "eu"},
+		{"eu.example.com", "any"},
+		{"mixed.case.example", "mixed"},
+		{"[::1]:80", "ipv6"},
+		{"badexample.com", "fallback"},
+		{"other.org", "fallback"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = tt.host
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("Host %q served by %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestHostsNoFallback(t *testing.T) {
+	h := httpx.Hosts(map[string]http.Handler{"example.com": named("site")}, nil)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Host = "other.org"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHostsInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"", "*", "*.", "a.*.com", "*example.com"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Hosts(%q) did not panic", pattern)
+				}
+			}()
+			httpx.Hosts(map[string]http.Handler{pattern: named("x")}, nil)
+		}()
+	}
+}
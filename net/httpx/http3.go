@@ -0,0 +1,63 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrHTTP3WithoutTLS is returned by Run when WithHTTP3 is given without
+// WithTLS or WithTLSConfig: clients only follow Alt-Svc to HTTP/3 from
+// HTTPS responses.
+var ErrHTTP3WithoutTLS = errors.New("httpx: HTTP/3 requires TLS")
+
+// HTTP3Server is an HTTP/3 server run alongside the TCP server by
+// WithHTTP3. quic-go's *http3.Server satisfies it.
+type HTTP3Server interface {
+	// ListenAndServe listens on UDP and serves until Shutdown.
+	ListenAndServe() error
+
+	// Shutdown stops accepting connections and waits for in-flight
+	// requests until ctx is done.
+	Shutdown(ctx context.Context) error
+
+	// SetQUICHeaders adds the Alt-Svc header advertising the server to h.
+	SetQUICHeaders(h http.Header) error
+}
+
+// WithHTTP3 runs srv next to the HTTPS server and advertises it to clients
+// with an Alt-Svc header on every TCP response. srv starts and shuts down
+// with the TCP server, within the same shutdown timeout.
+//
+// The module has no third-party dependencies, so it does not include a
+// QUIC implementation; configure srv, with the same handler and
+// certificates, using one such as quic-go:
+//
+//	h3 := &http3.Server{Addr: ":443", Handler: mux, TLSConfig: http3.ConfigureTLSConfig(tlsCfg)}
+//	err := httpx.Run(":443", mux, httpx.WithTLSConfig(tlsCfg), httpx.WithHTTP3(h3))
+//
+// It requires WithTLS or WithTLSConfig; Run returns ErrHTTP3WithoutTLS
+// otherwise.
+func WithHTTP3(srv HTTP3Server) Option {
+	return func(o *options) { o.h3 = srv }
+}
+
+// withAltSvc advertises the HTTP/3 server on the responses of next.
+func (o *options) withAltSvc(next http.Handler) http.Handler {
+	if o.h3 == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		o.h3.SetQUICHeaders(w.Header())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// configureHTTP3 reports ErrHTTP3WithoutTLS if WithHTTP3 was given without
+// TLS.
+func (o *options) configureHTTP3() error {
+	if o.h3 != nil && !o.tls {
+		return ErrHTTP3WithoutTLS
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/graceful/gracefultest"
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+// fakeHTTP3 is an HTTP3Server that serves nothing until shut down.
+type fakeHTTP3 struct {
+	gracefultest.Server
+	shutdowns atomic.Int32
+}
+
+func (s *fakeHTTP3) Shutdown(ctx context.Context) error {
+	s.shutdowns.Add(1)
+	return s.Server.Shutdown(ctx)
+}
+
+func (s *fakeHTTP3) SetQUICHeaders(h http.Header) error {
+	h.Set("Alt-Svc", `h3=":443"; ma=2592000`)
+	return nil
+}
+
+func TestWithHTTP3(t *testing.T) {
+	certFile, keyFile, client := writeCert(t)
+	h3 := &fakeHTTP3{}
+	ln, addr := listen(t)
+	cancel, done := startRun(t, client, "https://"+addr+"/", func(ctx context.Context) error {
+		return httpx.RunWithContext(ctx, "", http.NotFoundHandler(),
+			httpx.WithListener(ln),
+			httpx.WithTLS(certFile, keyFile),
+			httpx.WithHTTP3(h3),
+		)
+	})
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Alt-Svc"); got == "" {
+		t.Error("response without Alt-Svc")
+	}
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("RunWithContext() = %v, want nil", err)
+	}
+	if got := h3.shutdowns.Load(); got != 1 {
+		t.Fatalf("HTTP/3 server shut down %d times, want 1", got)
+	}
+}
+
+func TestWithHTTP3WithoutTLS(t *testing.T) {
+	err := httpx.RunWithContext(context.Background(), "127.0.0.1:0", http.NotFoundHandler(), httpx.WithHTTP3(&fakeHTTP3{}))
+	if !errors.Is(err, httpx.ErrHTTP3WithoutTLS) {
+		t.Fatalf("RunWithContext() = %v, want %v", err, httpx.ErrHTTP3WithoutTLS)
+	}
+}
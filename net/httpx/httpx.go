@@ -0,0 +1,244 @@
+// Package httpx runs HTTP servers with graceful shutdown through functional
+// options, on top of net/graceful.
+//
+//	err := httpx.Run(":8080", mux,
+//	    httpx.WithShutdownTimeout(10*time.Second),
+//	    httpx.WithTLS("cert.pem", "key.pem"),
+//	)
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rin2yh/gouse/net/graceful"
+)
+
+// Option configures Run and RunWithContext.
+type Option func(*options)
+
+// options collects the settings applied by Options.
+type options struct {
+	cfg graceful.Config
+
+	tls               bool
+	certFile, keyFile string
+	tlsConfig         *tls.Config
+	h2c               bool
+	h3                HTTP3Server
+	servers           []graceful.Server
+
+	listener net.Listener
+	unixPath string
+	unixPerm os.FileMode
+	baseCtx  context.Context
+
+	health       bool
+	healthChecks []func(context.Context) error
+
+	trackHijacked bool
+
+	middleware []func(http.Handler) http.Handler
+}
+
+// WithShutdownTimeout sets how long in-flight requests may take to finish
+// once shutdown begins. Defaults to graceful's 5 seconds.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(o *options) { o.cfg.ShutdownTimeout = d }
+}
+
+// WithForceClose closes the connections still open when the shutdown
+// timeout has passed plus grace, so a slow client cannot hold the process
+// hostage. Request contexts are cancelled at the timeout; grace gives
+// handlers that honour them time to finish their responses, and the wait
+// ends early once every connection has closed. Without it, connections
+// outliving the timeout are left open and Run returns anyway.
+func WithForceClose(grace time.Duration) Option {
+	return func(o *options) {
+		o.cfg.ForceCloseAfterTimeout = true
+		o.cfg.ForceCloseGrace = grace
+	}
+}
+
+// WithShutdownDelay keeps serving for d after shutdown is triggered before
+// draining begins, giving load balancers time to notice a failing /readyz
+// (see WithHealthEndpoints) and stop routing traffic here, like a
+// Kubernetes preStop sleep.
+func WithShutdownDelay(d time.Duration) Option {
+	return func(o *options) { o.cfg.ShutdownDelay = d }
+}
+
+// WithBaseContext makes request contexts carry the values of ctx (loggers,
+// trace IDs) instead of those of the context passed to RunWithContext. As
+// without it, request contexts are cancelled once the shutdown deadline
+// passes, not when ctx is cancelled.
+func WithBaseContext(ctx context.Context) Option {
+	return func(o *options) { o.baseCtx = ctx }
+}
+
+// WithCleanup adds functions called in order after the server has shut
+// down, e.g. to close a database. Each receives the remaining shutdown
+// deadline; their errors are joined into Run's result and do not stop the
+// others. It can be given several times.
+func WithCleanup(fns ...func(context.Context) error) Option {
+	return func(o *options) { o.cfg.Cleanups = append(o.cfg.Cleanups, fns...) }
+}
+
+// WithOnShutdownStart adds fn to be called as soon as shutdown is
+// triggered, before draining begins, e.g. to log or flip a gauge. It can
+// be given several times; the functions are called in order.
+func WithOnShutdownStart(fn func()) Option {
+	return func(o *options) {
+		prev := o.cfg.OnShutdownBegin
+		o.cfg.OnShutdownBegin = func() {
+			if prev != nil {
+				prev()
+			}
+			fn()
+		}
+	}
+}
+
+// WithOnShutdownDone adds fn to be called once shutdown has completed,
+// cleanups included, with the error Run is about to return. It can be
+// given several times; the functions are called in order.
+func WithOnShutdownDone(fn func(error)) Option {
+	return func(o *options) {
+		prev := o.cfg.OnShutdownDone
+		o.cfg.OnShutdownDone = func(err error) {
+			if prev != nil {
+				prev(err)
+			}
+			fn(err)
+		}
+	}
+}
+
+// WithMiddleware wraps the handler passed to Run in mws, the first
+// outermost, e.g. to trace requests with OpenTelemetry's otelhttp without
+// this package depending on it:
+//
+//	httpx.WithMiddleware(func(h http.Handler) http.Handler {
+//	    return otelhttp.NewHandler(h, "server")
+//	})
+//
+// The health endpoints of WithHealthEndpoints are not wrapped. It can be
+// given several times.
+func WithMiddleware(mws ...func(http.Handler) http.Handler) Option {
+	return func(o *options) { o.middleware = append(o.middleware, mws...) }
+}
+
+// WithServer runs srvs alongside the server, such as an admin server from
+// debugserver.New. They start with it, and are shut down with it within
+// the same shutdown timeout; if one fails to start, Run returns its error.
+// It can be given several times.
+func WithServer(srvs ...graceful.Server) Option {
+	return func(o *options) { o.servers = append(o.servers, srvs...) }
+}
+
+// WithListener serves on ln instead of listening on addr, which is then
+// ignored. Binding up front makes ":0" ports safe to use (read the port
+// from ln.Addr()) and supports socket-activated listeners. ln is closed
+// when Run returns.
+func WithListener(ln net.Listener) Option {
+	return func(o *options) { o.listener = ln }
+}
+
+// Run serves handler on addr until SIGINT or SIGTERM is received, then
+// shuts down gracefully. It is RunWithContext with context.Background().
+func Run(addr string, handler http.Handler, opts ...Option) error {
+	return RunWithContext(context.Background(), addr, handler, opts...)
+}
+
+// RunWithContext serves handler on addr until SIGINT or SIGTERM is received
+// or ctx is cancelled, then shuts down gracefully. It returns nil after a
+// clean shutdown, or the error that prevented serving or shutting down.
+//
+// Request contexts carry the values of ctx (see WithBaseContext) and are
+// cancelled once the shutdown deadline passes, so handlers still running
+// then can give up.
+//
+// An empty addr means ":http", or ":https" with WithTLS or WithTLSConfig.
+// addr is ignored with WithListener.
+func RunWithContext(ctx context.Context, addr string, handler http.Handler, opts ...Option) error {
+	o := newOptions(opts)
+	srvs, _, err := o.bind(addr, handler)
+	if err != nil {
+		return err
+	}
+	return graceful.RunAll(ctx, srvs, &o.cfg)
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// bind builds the http.Server for handler and listens on addr, returning
+// the graceful.Servers to run, that one first, and the address it is bound
+// to.
+func (o *options) bind(addr string, handler http.Handler) ([]graceful.Server, net.Addr, error) {
+	srv := &http.Server{Addr: addr, Handler: o.withAltSvc(o.withHealth(o.withHijackTracking(o.wrap(handler))))}
+	if o.baseCtx != nil {
+		base := context.WithoutCancel(o.baseCtx)
+		srv.BaseContext = func(net.Listener) context.Context { return base }
+	}
+	if err := errors.Join(o.configureTLS(srv), o.configureH2C(srv), o.configureHTTP3()); err != nil {
+		if o.listener != nil {
+			o.listener.Close()
+		}
+		return nil, nil, err
+	}
+	ln, err := o.listen(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	srvs := []graceful.Server{o.server(srv, ln)}
+	if o.h3 != nil {
+		srvs = append(srvs, o.h3)
+	}
+	srvs = append(srvs, o.servers...)
+	return srvs, ln.Addr(), nil
+}
+
+// wrap returns handler wrapped in the middleware given by WithMiddleware.
+func (o *options) wrap(handler http.Handler) http.Handler {
+	for i := len(o.middleware) - 1; i >= 0; i-- {
+		handler = o.middleware[i](handler)
+	}
+	return handler
+}
+
+// listen returns the listener given by WithListener, or listens on the
+// socket given by WithUnixSocket or on addr.
+func (o *options) listen(addr string) (net.Listener, error) {
+	if o.listener != nil {
+		return o.listener, nil
+	}
+	if o.unixPath != "" {
+		return o.listenUnix()
+	}
+	if addr == "" {
+		addr = ":http"
+		if o.tls {
+			addr = ":https"
+		}
+	}
+	return net.Listen("tcp", addr)
+}
+
+// server returns the graceful.Server serving srv on ln.
+func (o *options) server(srv *http.Server, ln net.Listener) graceful.Server {
+	if o.tls {
+		return graceful.OnListenerTLS(srv, ln, "", "") // certificates are in srv.TLSConfig
+	}
+	return graceful.OnListener(srv, ln)
+}
@@ -0,0 +1,370 @@
+package httpx_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/graceful/gracefultest"
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+const (
+	testStartTimeout    = 2 * time.Second
+	testShutdownTimeout = 5 * time.Second
+)
+
+// listen returns a listener on a free loopback port and its address. It is
+// closed when the test ends, in case Run never takes ownership of it.
+func listen(t *testing.T) (net.Listener, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return ln, ln.Addr().String()
+}
+
+// startRun runs RunWithContext in a goroutine and waits until url answers.
+func startRun(t *testing.T, client *http.Client, url string, run func(ctx context.Context) error) (cancel context.CancelFunc, done <-chan error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	ch := make(chan error, 1)
+	go func() { ch <- run(ctx) }()
+	deadline := time.Now().Add(testStartTimeout)
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			return cancel, ch
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("server did not start in time:", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func awaitShutdown(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(testShutdownTimeout):
+		t.Fatal("server did not shut down in time")
+		return nil
+	}
+}
+
+func TestRunWithContext(t *testing.T) {
+	ln, addr := listen(t)
+	cancel, done := startRun(t, http.DefaultClient, "http://"+addr+"/", func(ctx context.Context) error {
+		return httpx.RunWithContext(ctx, "", http.NotFoundHandler(),
+			httpx.WithListener(ln),
+			httpx.WithShutdownTimeout(time.Second),
+		)
+	})
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("RunWithContext() = %v, want nil", err)
+	}
+}
+
+// writeCert writes a self-signed certificate for 127.0.0.1 and its key to
+// PEM files, returning their paths and a client trusting the certificate.
+func writeCert(t *testing.T) (certFile, keyFile string, client *http.Client) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client = &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool},
+		ForceAttemptHTTP2: true,
+	}}
+	return certFile, keyFile, client
+}
+
+func TestRunWithContextTLS(t *testing.T) {
+	certFile, keyFile, client := writeCert(t)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		opt  httpx.Option
+	}{
+		{"WithTLS", httpx.WithTLS(certFile, keyFile)},
+		{"WithTLSConfig", httpx.WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, addr := listen(t)
+			protos := make(chan string, 1)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case protos <- r.Proto:
+				default:
+				}
+			})
+			cancel, done := startRun(t, client, "https://"+addr+"/", func(ctx context.Context) error {
+				return httpx.RunWithContext(ctx, "", handler, httpx.WithListener(ln), tt.opt)
+			})
+			if proto := <-protos; proto != "HTTP/2.0" {
+				t.Errorf("request served over %s, want HTTP/2.0", proto)
+			}
+			cancel()
+			if err := awaitShutdown(t, done); err != nil {
+				t.Fatalf("RunWithContext() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestRunWithContextTLSErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  httpx.Option
+		want error
+	}{
+		{"missing files", httpx.WithTLS("missing.pem", "missing.pem"), os.ErrNotExist},
+		{"config without certificate", httpx.WithTLSConfig(&tls.Config{}), httpx.ErrNoCertificate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := httpx.RunWithContext(context.Background(), "127.0.0.1:0", http.NotFoundHandler(), tt.opt)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("RunWithContext() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunWithContextCleanup(t *testing.T) {
+	errClose := errors.New("close failed")
+	var calls []string
+	ln, addr := listen(t)
+	cancel, done := startRun(t, http.DefaultClient, "http://"+addr+"/", func(ctx context.Context) error {
+		return httpx.RunWithContext(ctx, "", http.NotFoundHandler(),
+			httpx.WithListener(ln),
+			httpx.WithCleanup(func(context.Context) error {
+				calls = append(calls, "db")
+				return errClose
+			}),
+			httpx.WithCleanup(func(context.Context) error {
+				calls = append(calls, "cache")
+				return nil
+			}),
+		)
+	})
+	cancel()
+	if err := awaitShutdown(t, done); !errors.Is(err, errClose) {
+		t.Fatalf("RunWithContext() = %v, want %v", err, errClose)
+	}
+	if want := []string{"db", "cache"}; !slices.Equal(calls, want) {
+		t.Fatalf("cleanups called %v, want %v", calls, want)
+	}
+}
+
+type ctxKey struct{}
+
+func TestRunWithContextBaseContext(t *testing.T) {
+	withValue := func(ctx context.Context) context.Context { return context.WithValue(ctx, ctxKey{}, "trace-1") }
+	tests := []struct {
+		name   string
+		runCtx func(context.Context) context.Context
+		opts   []httpx.Option
+	}{
+		{"from RunWithContext", withValue, nil},
+		{"WithBaseContext", func(ctx context.Context) context.Context { return ctx },
+			[]httpx.Option{httpx.WithBaseContext(withValue(context.Background()))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(chan any, 1)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case got <- r.Context().Value(ctxKey{}):
+				default:
+				}
+			})
+			ln, addr := listen(t)
+			cancel, done := startRun(t, http.DefaultClient, "http://"+addr+"/", func(ctx context.Context) error {
+				opts := append([]httpx.Option{httpx.WithListener(ln)}, tt.opts...)
+				return httpx.RunWithContext(tt.runCtx(ctx), "", handler, opts...)
+			})
+			if v := <-got; v != "trace-1" {
+				t.Errorf("request context value = %v, want trace-1", v)
+			}
+			cancel()
+			if err := awaitShutdown(t, done); err != nil {
+				t.Fatalf("RunWithContext() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestRunWithContextShutdownHooks(t *testing.T) {
+	errClose := errors.New("close failed")
+	var calls []string
+	ln, addr := listen(t)
+	cancel, done := startRun(t, http.DefaultClient, "http://"+addr+"/", func(ctx context.Context) error {
+		return httpx.RunWithContext(ctx, "", http.NotFoundHandler(),
+			httpx.WithListener(ln),
+			httpx.WithOnShutdownStart(func() { calls = append(calls, "start 1") }),
+			httpx.WithOnShutdownStart(func() { calls = append(calls, "start 2") }),
+			httpx.WithCleanup(func(context.Context) error {
+				calls = append(calls, "cleanup")
+				return errClose
+			}),
+			httpx.WithOnShutdownDone(func(err error) {
+				if !errors.Is(err, errClose) {
+					t.Errorf("OnShutdownDone got %v, want %v", err, errClose)
+				}
+				calls = append(calls, "done")
+			}),
+		)
+	})
+	cancel()
+	awaitShutdown(t, done)
+	if want := []string{"start 1", "start 2", "cleanup", "done"}; !slices.Equal(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestRunWithContextForceClose(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hang" {
+			close(started)
+			<-release // ignores cancellation
+		}
+	})
+	ln, addr := listen(t)
+	cancel, done := startRun(t, http.DefaultClient, "http://"+addr+"/", func(ctx context.Context) error {
+		return httpx.RunWithContext(ctx, "", handler,
+			httpx.WithListener(ln),
+			httpx.WithShutdownTimeout(50*time.Millisecond),
+			httpx.WithForceClose(50*time.Millisecond),
+		)
+	})
+	clientErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/hang")
+		if err == nil {
+			resp.Body.Close()
+		}
+		clientErr <- err
+	}()
+	<-started
+
+	cancel()
+	if err := awaitShutdown(t, done); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunWithContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := <-clientErr; err == nil {
+		t.Fatal("hanging request succeeded, want its connection closed")
+	}
+}
+
+func TestRunWithContextMiddleware(t *testing.T) {
+	tag := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Order", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	srv, err := httpx.Start(context.Background(), "127.0.0.1:0", http.NotFoundHandler(),
+		httpx.WithMiddleware(tag("a"), tag("b")),
+		httpx.WithMiddleware(tag("c")),
+		httpx.WithHealthEndpoints(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	for path, want := range map[string][]string{"/": {"a", "b", "c"}, httpx.HealthzPath: nil} {
+		resp, err := client.Get("http://" + srv.Addr().String() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Values("X-Order"); !slices.Equal(got, want) {
+			t.Errorf("%s: middleware order = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestRunWithContextServer(t *testing.T) {
+	errBind := errors.New("bind failed")
+	var extra fakeHTTP3
+	ln, addr := listen(t)
+	cancel, done := startRun(t, http.DefaultClient, "http://"+addr+"/", func(ctx context.Context) error {
+		return httpx.RunWithContext(ctx, "", http.NotFoundHandler(), httpx.WithListener(ln), httpx.WithServer(&extra))
+	})
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("RunWithContext() = %v, want nil", err)
+	}
+	if got := extra.shutdowns.Load(); got != 1 {
+		t.Fatalf("extra server shut down %d times, want 1", got)
+	}
+
+	failing := &gracefultest.Server{ListenFunc: func() error { return errBind }}
+	err := httpx.RunWithContext(context.Background(), "127.0.0.1:0", http.NotFoundHandler(), httpx.WithServer(failing))
+	if !errors.Is(err, errBind) {
+		t.Fatalf("RunWithContext() with a failing server = %v, want %v", err, errBind)
+	}
+}
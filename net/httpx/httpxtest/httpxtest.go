@@ -0,0 +1,63 @@
+// Package httpxtest provides utilities for testing code served with
+// net/httpx.
+//
+//	func TestAPI(t *testing.T) {
+//	    url, shutdown := httpxtest.StartServer(t, mux, httpx.WithHealthEndpoints())
+//	    resp, err := http.Get(url + "/items")
+//	    // ...
+//	    if err := shutdown(); err != nil {
+//	        t.Fatal(err)
+//	    }
+//	}
+package httpxtest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/graceful/gracefultest"
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+// StartServer serves handler with httpx on a free loopback port, as
+// RunWithContext would with opts, and waits until it answers HTTP
+// requests. It returns the server's base URL, such as
+// "http://127.0.0.1:54321", and a function that shuts the server down
+// gracefully and returns RunWithContext's result. Failing to start fails
+// the test.
+//
+// The shutdown function is also registered with t.Cleanup, so a test that
+// does not care about the result need not call it; calling it again
+// returns the first result. Shutdown waits up to
+// gracefultest.ShutdownTimeout.
+//
+// The URL always uses http; replace the scheme when opts include WithTLS
+// or WithTLSConfig. Options choosing the listener (WithListener,
+// WithUnixSocket) defeat the free port and should not be given.
+func StartServer(t testing.TB, handler http.Handler, opts ...httpx.Option) (url string, shutdown func() error) {
+	t.Helper()
+	srv, err := httpx.Start(context.Background(), "127.0.0.1:0", handler, opts...)
+	if err != nil {
+		t.Fatal("httpxtest: starting server:", err)
+	}
+
+	var once sync.Once
+	var shutdownErr error
+	shutdown = func() error {
+		once.Do(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), gracefultest.ShutdownTimeout)
+			defer cancel()
+			shutdownErr = srv.Shutdown(ctx)
+		})
+		return shutdownErr
+	}
+	t.Cleanup(func() { shutdown() })
+
+	addr := srv.Addr().String()
+	if err := gracefultest.WaitForServer(addr, gracefultest.StartTimeout); err != nil {
+		t.Fatal("httpxtest: server did not start in time:", err)
+	}
+	return "http://" + addr, shutdown
+}
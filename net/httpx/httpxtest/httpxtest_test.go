@@ -0,0 +1,47 @@
+package httpxtest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+	"github.com/rin2yh/gouse/net/httpx/httpxtest"
+)
+
+func TestStartServer(t *testing.T) {
+	errClose := errors.New("close failed")
+	url, shutdown := httpxtest.StartServer(t, http.NotFoundHandler(),
+		httpx.WithHealthEndpoints(),
+		httpx.WithCleanup(func(context.Context) error { return errClose }),
+	)
+	resp, err := http.Get(url + httpx.HealthzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if err := shutdown(); !errors.Is(err, errClose) {
+		t.Fatalf("shutdown() = %v, want %v", err, errClose)
+	}
+	if err := shutdown(); !errors.Is(err, errClose) {
+		t.Fatalf("second shutdown() = %v, want the first result", err)
+	}
+	if _, err := http.Get(url + "/"); err == nil {
+		t.Fatal("server still answering after shutdown")
+	}
+}
+
+func TestStartServerCleanup(t *testing.T) {
+	var url string
+	t.Run("inner", func(t *testing.T) {
+		url, _ = httpxtest.StartServer(t, http.NotFoundHandler())
+	})
+	if _, err := http.Get(url + "/"); err == nil {
+		t.Fatal("server still answering after the test ended")
+	}
+}
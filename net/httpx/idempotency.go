@@ -0,0 +1,283 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header Idempotency reads.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set to "true" on responses Idempotency
+// replays from its store.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotentResponse is a response recorded by Idempotency.
+type IdempotentResponse struct {
+	// Fingerprint identifies the request the response was for, so a key
+	// reused for a different request is detected.
+	Fingerprint string
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// IdempotencyStore keeps the keys and responses of Idempotency.
+// Implementations backed by a shared store such as Redis make keys work
+// across instances; they must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Lock claims key for a request for ttl. If the key holds a recorded
+	// response, Lock returns it instead; if another request has claimed it
+	// and not yet finished, Lock reports false and a nil response.
+	Lock(ctx context.Context, key string, ttl time.Duration) (ok bool, resp *IdempotentResponse, err error)
+
+	// Save records resp for key, which the caller has locked, for ttl.
+	Save(ctx context.Context, key string, resp *IdempotentResponse, ttl time.Duration) error
+
+	// Unlock releases key, which the caller has locked, without recording
+	// a response, so the request can be retried.
+	Unlock(ctx context.Context, key string) error
+}
+
+// IdempotencyOptions controls Idempotency.
+type IdempotencyOptions struct {
+	// Store keeps the keys and responses. Defaults to a new
+	// MemoryIdempotencyStore if nil.
+	Store IdempotencyStore
+
+	// TTL is how long keys and their responses are kept. Defaults to 24
+	// hours if zero.
+	TTL time.Duration
+
+	// Scope returns a prefix for the keys of a request, e.g. the
+	// authenticated user, so clients cannot see each other's responses.
+	// Defaults to none if nil.
+	Scope KeyFunc
+}
+
+// Idempotency returns middleware making POST and PATCH requests with an
+// Idempotency-Key header safe to retry, as payment APIs require. The first
+// request with a key is served and its response recorded; later ones with
+// the same key get the recorded response replayed, with an
+// Idempotent-Replayed header, instead of being served again:
+//
+//   - A key still being served answers 409 Conflict.
+//   - A key reused for a different method, URL or body answers 422
+//     Unprocessable Entity.
+//   - Responses with a 5xx status, and hijacked ones, are not recorded, so
+//     the request can be retried.
+//   - If the store fails, the request is refused with 503 rather than
+//     risking serving it twice.
+//
+// Request bodies are read into memory to fingerprint them. Other methods
+// and requests without the header are passed through. opts may be nil for
+// the defaults.
+func Idempotency(opts *IdempotencyOptions) func(http.Handler) http.Handler {
+	if opts == nil {
+		opts = &IdempotencyOptions{}
+	}
+	store, ttl, scope := opts.Store, orDefault(opts.TTL, defaultIdempotencyTTL), opts.Scope
+	if store == nil {
+		store = NewMemoryIdempotencyStore()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if scope != nil {
+				key = scope(r) + ":" + key
+			}
+			fingerprint, err := fingerprintRequest(r)
+			if err != nil {
+				writeProblem(w, http.StatusBadRequest, "the request body could not be read")
+				return
+			}
+
+			ctx := r.Context()
+			ok, stored, err := store.Lock(ctx, key, ttl)
+			switch {
+			case err != nil:
+				writeProblem(w, http.StatusServiceUnavailable, "the idempotency key could not be checked")
+				return
+			case stored != nil && stored.Fingerprint != fingerprint:
+				writeProblem(w, http.StatusUnprocessableEntity, "the idempotency key was used for a different request")
+				return
+			case stored != nil:
+				replay(w, stored)
+				return
+			case !ok:
+				writeProblem(w, http.StatusConflict, "a request with this idempotency key is in progress")
+				return
+			}
+
+			rec := &recordingWriter{ResponseWriter: w}
+			saved := false
+			defer func() {
+				if !saved { // 5xx, hijacked or panicking
+					store.Unlock(context.WithoutCancel(ctx), key)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			if rec.hijacked || rec.status >= 500 {
+				return
+			}
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			resp := &IdempotentResponse{Fingerprint: fingerprint, Status: rec.status, Header: rec.header, Body: rec.body.Bytes()}
+			if resp.Header == nil {
+				resp.Header = w.Header().Clone()
+			}
+			saved = store.Save(context.WithoutCancel(ctx), key, resp, ttl) == nil
+		})
+	}
+}
+
+// fingerprintRequest hashes the method, URL and body of r, replacing the
+// body with an in-memory copy.
+func fingerprintRequest(r *http.Request) (string, error) {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		h.Write(body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replay writes a recorded response.
+func replay(w http.ResponseWriter, resp *IdempotentResponse) {
+	h := w.Header()
+	for k, v := range resp.Header {
+		h[k] = append([]string(nil), v...)
+	}
+	h.Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// recordingWriter copies the response it writes through for recording.
+type recordingWriter struct {
+	http.ResponseWriter
+	status   int
+	header   http.Header
+	body     bytes.Buffer
+	hijacked bool
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if rw.status == 0 && (code < 100 || code > 199 || code == http.StatusSwitchingProtocols) {
+		rw.status = code
+		rw.header = rw.Header().Clone()
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (rw *recordingWriter) Flush() {
+	if rw.status == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker. A hijacked response is not recorded, as
+// the handler's writes to the connection cannot be seen.
+func (rw *recordingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil {
+		rw.hijacked = true
+	}
+	return conn, brw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter { return rw.ResponseWriter }
+
+// MemoryIdempotencyStore is an in-process IdempotencyStore. Expired keys
+// are dropped at most once a minute.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+}
+
+type idempotencyEntry struct {
+	resp    *IdempotentResponse // nil while locked
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore returns an empty MemoryIdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: make(map[string]*idempotencyEntry)}
+}
+
+// Lock implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Lock(_ context.Context, key string, ttl time.Duration) (bool, *IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.sweep(now)
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		return false, e.resp, nil
+	}
+	s.entries[key] = &idempotencyEntry{expires: now.Add(ttl)}
+	return true, nil, nil
+}
+
+// Save implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Save(_ context.Context, key string, resp *IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = &idempotencyEntry{resp: resp, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Unlock implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Unlock(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok && e.resp == nil {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// sweep drops expired entries, at most once a minute.
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for k, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+}
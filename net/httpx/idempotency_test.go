@@ -0,0 +1,115 @@
+package httpx_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestIdempotency(t *testing.T) {
+	var calls atomic.Int32
+	h := httpx.Idempotency(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Charge", fmt.Sprint(n))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "charged %s", body)
+	}))
+	do := func(method, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/charges", strings.NewReader(body))
+		if key != "" {
+			r.Header.Set(httpx.IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	first := do(http.MethodPost, "k1", "10")
+	again := do(http.MethodPost, "k1", "10")
+	if first.Code != http.StatusCreated || again.Code != http.StatusCreated {
+		t.Fatalf("statuses = %d, %d; want 201 twice", first.Code, again.Code)
+	}
+	if again.Body.String() != "charged 10" || again.Header().Get("X-Charge") != "1" {
+		t.Errorf("replay = %q (X-Charge %q), want the first response", again.Body, again.Header().Get("X-Charge"))
+	}
+	if again.Header().Get(httpx.IdempotentReplayedHeader) != "true" || first.Header().Get(httpx.IdempotentReplayedHeader) != "" {
+		t.Error("Idempotent-Replayed set on the wrong response")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("handler called %d times, want 1", got)
+	}
+
+	if rec := do(http.MethodPost, "k1", "20"); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("key reused with another body: status %d, want 422", rec.Code)
+	}
+	do(http.MethodPost, "k2", "fail")
+	if rec := do(http.MethodPost, "k2", "fail"); rec.Code != http.StatusServiceUnavailable || calls.Load() != 3 {
+		t.Errorf("5xx replayed: status %d after %d calls, want the request served again", rec.Code, calls.Load())
+	}
+	do(http.MethodPost, "", "10")
+	do(http.MethodPut, "k1", "10")
+	if got := calls.Load(); got != 5 {
+		t.Errorf("handler called %d times, want requests without key or with PUT passed through", got)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := httpx.Idempotency(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set(httpx.IdempotencyKeyHeader, "k")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), r.Clone(context.Background()))
+	}()
+	<-started
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	close(release)
+	<-done
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	s := httpx.NewMemoryIdempotencyStore()
+	if ok, _, _ := s.Lock(ctx, "k", time.Hour); !ok {
+		t.Fatal("Lock on a new key failed")
+	}
+	if ok, resp, _ := s.Lock(ctx, "k", time.Hour); ok || resp != nil {
+		t.Fatal("Lock on a locked key succeeded")
+	}
+	s.Unlock(ctx, "k")
+	if ok, _, _ := s.Lock(ctx, "k", time.Hour); !ok {
+		t.Fatal("Lock after Unlock failed")
+	}
+	s.Save(ctx, "k", &httpx.IdempotentResponse{Status: 200}, time.Hour)
+	s.Unlock(ctx, "k") // no effect once saved
+	if ok, resp, _ := s.Lock(ctx, "k", time.Hour); ok || resp == nil || resp.Status != 200 {
+		t.Fatalf("Lock after Save = %v, %+v; want the saved response", ok, resp)
+	}
+	s.Save(ctx, "short", &httpx.IdempotentResponse{}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if ok, _, _ := s.Lock(ctx, "short", time.Hour); !ok {
+		t.Fatal("Lock after expiry failed")
+	}
+}
@@ -0,0 +1,139 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const defaultMaxJSONBytes = 1 << 20
+
+// DecodeOptions controls DecodeJSON. The zero value is strict: a JSON
+// Content-Type is required, the body is limited to 1 MiB, and unknown
+// fields are rejected.
+type DecodeOptions struct {
+	// MaxBytes limits the size of the request body. Defaults to 1 MiB if
+	// zero.
+	MaxBytes int64
+
+	// AllowUnknownFields accepts object keys that do not match a field of
+	// the destination struct, instead of failing.
+	AllowUnknownFields bool
+
+	// AnyContentType skips the Content-Type check.
+	AnyContentType bool
+}
+
+// DecodeError is returned by DecodeJSON when the request is at fault. Its
+// message is safe to show to the client, and Status is the status to reply
+// with: 415 for a wrong Content-Type, 413 for a body over the limit and 400
+// for anything else.
+type DecodeError struct {
+	Status int
+	Err    error
+}
+
+func (e *DecodeError) Error() string { return e.Err.Error() }
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// DecodeJSON decodes the JSON body of r into v, which must be a pointer.
+// The body must hold exactly one JSON value. opts may be nil for the
+// defaults described on DecodeOptions. Errors caused by the request are
+// *DecodeError; others (e.g. a non-pointer v) are returned as is.
+//
+//	var in CreateItem
+//	if err := httpx.DecodeJSON(r, &in, nil); err != nil {
+//	    var de *httpx.DecodeError
+//	    if errors.As(err, &de) {
+//	        http.Error(w, de.Error(), de.Status)
+//	        return
+//	    }
+//	    ...
+//	}
+func DecodeJSON(r *http.Request, v any, opts *DecodeOptions) error {
+	if opts == nil {
+		opts = &DecodeOptions{}
+	}
+	if !opts.AnyContentType && !isJSON(r.Header.Get("Content-Type")) {
+		return &DecodeError{Status: http.StatusUnsupportedMediaType, Err: errors.New("httpx: Content-Type must be application/json")}
+	}
+	limit := int64(defaultMaxJSONBytes)
+	if opts.MaxBytes > 0 {
+		limit = opts.MaxBytes
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, limit))
+	if !opts.AllowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return decodeError(err)
+		}
+		return &DecodeError{Status: http.StatusBadRequest, Err: errors.New("httpx: request body must hold a single JSON value")}
+	}
+	return nil
+}
+
+// isJSON reports whether contentType is application/json or a +json type.
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mt == "application/json" || strings.HasSuffix(mt, "+json"))
+}
+
+// decodeError turns an error from json.Decoder into a *DecodeError with a
+// message fit for the client, leaving programming errors alone.
+func decodeError(err error) error {
+	var (
+		syntaxErr  *json.SyntaxError
+		typeErr    *json.UnmarshalTypeError
+		tooLarge   *http.MaxBytesError
+		invalidErr *json.InvalidUnmarshalError
+		msg        string
+	)
+	switch {
+	case errors.As(err, &invalidErr):
+		return err
+	case errors.As(err, &tooLarge):
+		return &DecodeError{Status: http.StatusRequestEntityTooLarge, Err: fmt.Errorf("httpx: request body larger than %d bytes", tooLarge.Limit)}
+	case errors.As(err, &syntaxErr):
+		msg = fmt.Sprintf("request body has badly-formed JSON at offset %d", syntaxErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		msg = "request body has badly-formed JSON"
+	case errors.As(err, &typeErr):
+		msg = fmt.Sprintf("request body has an invalid value for field %q at offset %d", typeErr.Field, typeErr.Offset)
+	case errors.Is(err, io.EOF):
+		msg = "request body is empty"
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		msg = "request body has unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+	default:
+		return &DecodeError{Status: http.StatusBadRequest, Err: fmt.Errorf("httpx: invalid request body: %w", err)}
+	}
+	return &DecodeError{Status: http.StatusBadRequest, Err: errors.New("httpx: " + msg)}
+}
+
+// JSON writes v as a JSON response with the given status. v is encoded
+// before anything is written, so if encoding fails the client gets a plain
+// 500 instead of a truncated body, and the error is returned.
+func JSON(w http.ResponseWriter, status int, v any) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	h := w.Header()
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,81 @@
+package httpx_test
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+type item struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        *httpx.DecodeOptions
+		wantStatus  int // 0 for success
+	}{
+		{"valid", "application/json", `{"name":"a","count":2}`, nil, 0},
+		{"+json with charset", "application/vnd.api+json; charset=utf-8", `{"name":"a"}`, nil, 0},
+		{"missing Content-Type", "", `{"name":"a"}`, nil, http.StatusUnsupportedMediaType},
+		{"AnyContentType", "text/plain", `{"name":"a"}`, &httpx.DecodeOptions{AnyContentType: true}, 0},
+		{"too large", "application/json", `{"name":"` + strings.Repeat("a", 64) + `"}`, &httpx.DecodeOptions{MaxBytes: 16}, http.StatusRequestEntityTooLarge},
+		{"unknown field", "application/json", `{"name":"a","extra":1}`, nil, http.StatusBadRequest},
+		{"AllowUnknownFields", "application/json", `{"name":"a","extra":1}`, &httpx.DecodeOptions{AllowUnknownFields: true}, 0},
+		{"syntax error", "application/json", `{"name":}`, nil, http.StatusBadRequest},
+		{"truncated", "application/json", `{"name":"a"`, nil, http.StatusBadRequest},
+		{"wrong type", "application/json", `{"count":"two"}`, nil, http.StatusBadRequest},
+		{"empty", "application/json", ``, nil, http.StatusBadRequest},
+		{"trailing value", "application/json", `{"name":"a"}{}`, nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			var v item
+			err := httpx.DecodeJSON(r, &v, tt.opts)
+			if tt.wantStatus == 0 {
+				if err != nil || v.Name != "a" {
+					t.Fatalf("DecodeJSON() = %v, decoded %+v", err, v)
+				}
+				return
+			}
+			var de *httpx.DecodeError
+			if !errors.As(err, &de) || de.Status != tt.wantStatus {
+				t.Fatalf("DecodeJSON() = %v, want a DecodeError with status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := httpx.JSON(rec, http.StatusCreated, item{Name: "a", Count: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("response = %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if got, want := rec.Body.String(), `{"name":"a","count":1}`+"\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	if err := httpx.JSON(rec, http.StatusOK, math.Inf(1)); err == nil {
+		t.Fatal("JSON() = nil, want an encoding error")
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status after encoding error = %d, want 500", rec.Code)
+	}
+}
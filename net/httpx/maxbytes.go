@@ -0,0 +1,116 @@
+package httpx
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// MaxBytes returns middleware limiting request bodies to n bytes, globally
+// or for the routes it wraps. Requests declaring a larger Content-Length
+// are rejected up front. Otherwise the body is wrapped in
+// http.MaxBytesReader, and once the handler's reads hit the limit, the
+// response it then starts, typically a 400 for the read error, is replaced
+// with 413 Content Too Large. Both have an application/problem+json body,
+// and the connection is closed after a body over the limit.
+//
+// The response writer passed to the handler still supports Flush and
+// Hijack.
+func MaxBytes(n int64) func(http.Handler) http.Handler {
+	detail := "request body exceeds " + strconv.FormatInt(n, 10) + " bytes"
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				w.Header().Set("Connection", "close")
+				writeProblem(w, http.StatusRequestEntityTooLarge, detail)
+				return
+			}
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, n)}
+			r2 := *r
+			r2.Body = body
+			mw := &maxBytesWriter{ResponseWriter: w, body: body, detail: detail}
+			next.ServeHTTP(mw, &r2)
+			if body.exceeded.Load() && !mw.wroteHeader {
+				mw.WriteHeader(http.StatusRequestEntityTooLarge)
+			}
+		})
+	}
+}
+
+// limitedBody records whether reads from a http.MaxBytesReader hit the
+// limit.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded atomic.Bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var tooLarge *http.MaxBytesError
+	if err != nil && errors.As(err, &tooLarge) {
+		b.exceeded.Store(true)
+	}
+	return n, err
+}
+
+// maxBytesWriter replaces the handler's response with a 413 if the body
+// limit was hit before it started.
+type maxBytesWriter struct {
+	http.ResponseWriter
+	body   *limitedBody
+	detail string
+
+	wroteHeader bool
+	replaced    bool
+}
+
+func (mw *maxBytesWriter) WriteHeader(code int) {
+	if mw.wroteHeader {
+		return
+	}
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		mw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	mw.wroteHeader = true
+	if mw.body.exceeded.Load() {
+		mw.replaced = true
+		writeProblem(mw.ResponseWriter, http.StatusRequestEntityTooLarge, mw.detail)
+		return
+	}
+	mw.ResponseWriter.WriteHeader(code)
+}
+
+func (mw *maxBytesWriter) Write(b []byte) (int, error) {
+	if !mw.wroteHeader {
+		mw.WriteHeader(http.StatusOK)
+	}
+	if mw.replaced {
+		return len(b), nil
+	}
+	return mw.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (mw *maxBytesWriter) Flush() {
+	if !mw.wroteHeader {
+		mw.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(mw.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker.
+func (mw *maxBytesWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(mw.ResponseWriter).Hijack()
+	if err == nil {
+		mw.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (mw *maxBytesWriter) Unwrap() http.ResponseWriter { return mw.ResponseWriter }
@@ -0,0 +1,70 @@
+package httpx_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestMaxBytes(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	})
+	ignore := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+	})
+	tests := []struct {
+		name          string
+		handler       http.Handler
+		body          string
+		contentLength int64 // -1 for unknown (chunked)
+		wantStatus    int
+		wantBody      string
+	}{
+		{"under limit", echo, "hello", 5, http.StatusOK, "hello"},
+		{"at limit", echo, "0123456789", 10, http.StatusOK, "0123456789"},
+		{"declared over limit", echo, "0123456789a", 11, http.StatusRequestEntityTooLarge, ""},
+		{"streamed over limit", echo, "0123456789a", -1, http.StatusRequestEntityTooLarge, ""},
+		{"handler ignores error", ignore, "0123456789a", -1, http.StatusRequestEntityTooLarge, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.ContentLength = tt.contentLength
+			rec := httptest.NewRecorder()
+			httpx.MaxBytes(10)(tt.handler).ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if rec.Body.String() != tt.wantBody {
+					t.Errorf("body = %q, want %q", rec.Body, tt.wantBody)
+				}
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Content-Type = %q, want application/problem+json", ct)
+			}
+			var p struct {
+				Status int    `json:"status"`
+				Detail string `json:"detail"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+				t.Fatalf("body %q: %v", rec.Body, err)
+			}
+			if p.Status != http.StatusRequestEntityTooLarge || p.Detail != "request body exceeds 10 bytes" {
+				t.Errorf("problem = %+v", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rin2yh/gouse/internal/promtext"
+)
+
+// defaultBuckets are the request duration histogram bounds, in seconds,
+// matching the Prometheus client library's defaults.
+var defaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// MetricsOptions controls NewMetrics.
+type MetricsOptions struct {
+	// Buckets are the upper bounds, in seconds, of the request duration
+	// histogram buckets, in increasing order. Defaults to the Prometheus
+	// client library's default buckets (5ms to 10s) if empty.
+	Buckets []float64
+
+	// Route returns the route label of a request once it has been served.
+	// It must return a template (e.g. "/users/{id}") rather than the path
+	// itself, or every distinct URL becomes a new time series. Defaults to
+	// the pattern of the Go 1.23+ http.ServeMux that served the request,
+	// or "other" without one.
+	Route func(r *http.Request) string
+}
+
+// Metrics collects HTTP server metrics and serves them in the Prometheus
+// text exposition format, without depending on the Prometheus client
+// library:
+//
+//	<ns>_http_requests_in_flight                                  gauge
+//	<ns>_http_request_duration_seconds{method,route,status}       histogram
+//	<ns>_http_response_size_bytes_total{method,route,status}      counter
+//
+// Wrap handlers with Middleware and mount the Metrics itself, an
+// http.Handler, as the metrics endpoint:
+//
+//	m := httpx.NewMetrics("myapp", nil)
+//	mux.Handle("GET /metrics", m)
+//	err := httpx.Run(":8080", m.Middleware(mux))
+type Metrics struct {
+	prefix  string
+	buckets []float64
+	route   func(r *http.Request) string
+
+	mu       sync.Mutex
+	inFlight int64
+	series   map[seriesKey]*series
+}
+
+type seriesKey struct {
+	method, route string
+	status        int
+}
+
+type series struct {
+	counts []uint64 // per bucket, not cumulative; the last is +Inf
+	sum    float64
+	bytes  uint64
+}
+
+// NewMetrics returns a Metrics whose metric names are prefixed with
+// namespace and an underscore; an empty namespace means no prefix. opts
+// may be nil for the defaults.
+func NewMetrics(namespace string, opts *MetricsOptions) *Metrics {
+	if opts == nil {
+		opts = &MetricsOptions{}
+	}
+	m := &Metrics{buckets: opts.Buckets, route: opts.Route, series: make(map[seriesKey]*series)}
+	if namespace != "" {
+		m.prefix = namespace + "_"
+	}
+	if len(m.buckets) == 0 {
+		m.buckets = defaultBuckets
+	}
+	if m.route == nil {
+		m.route = defaultRoute
+	}
+	return m
+}
+
+func defaultRoute(r *http.Request) string {
+	if p := requestPattern(r); p != "" {
+		return p
+	}
+	return "other"
+}
+
+// Middleware returns next instrumented by m.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.inFlight++
+		m.mu.Unlock()
+		begin := time.Now()
+		rw := NewResponseWriter(w)
+		defer func() {
+			status := rw.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			m.observe(seriesKey{methodLabel(r.Method), m.route(r), status}, time.Since(begin), rw.BytesWritten())
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// methodLabel bounds the method label to the standard methods.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+func (m *Metrics) observe(key seriesKey, took time.Duration, bytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight--
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{counts: make([]uint64, len(m.buckets)+1)}
+		m.series[key] = s
+	}
+	secs := took.Seconds()
+	s.counts[sort.SearchFloat64s(m.buckets, secs)]++
+	s.sum += secs
+	s.bytes += uint64(bytes)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", promtext.ContentType)
+	_, _ = m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format, for
+// appending to an existing metrics endpoint.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]seriesKey, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+
+	mw := promtext.NewWriter(w)
+	name := m.prefix + "http_requests_in_flight"
+	mw.Metric(name, "gauge", "Requests currently being served.")
+	mw.Printf("%s %d\n", name, m.inFlight)
+
+	name = m.prefix + "http_request_duration_seconds"
+	mw.Metric(name, "histogram", "Time taken to serve requests.")
+	for _, k := range keys {
+		s, labels := m.series[k], k.labels()
+		var cumulative uint64
+		for i, bound := range m.buckets {
+			cumulative += s.counts[i]
+			mw.Printf("%s_bucket{%s,%s} %d\n", name, labels, promtext.Label("le", strconv.FormatFloat(bound, 'g', -1, 64)), cumulative)
+		}
+		cumulative += s.counts[len(m.buckets)]
+		mw.Printf("%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, cumulative)
+		mw.Printf("%s_sum{%s} %g\n", name, labels, s.sum)
+		mw.Printf("%s_count{%s} %d\n", name, labels, cumulative)
+	}
+
+	name = m.prefix + "http_response_size_bytes_total"
+	mw.Metric(name, "counter", "Response body bytes written.")
+	for _, k := range keys {
+		mw.Printf("%s{%s} %d\n", name, k.labels(), m.series[k].bytes)
+	}
+
+	return mw.Flush()
+}
+
+func (k seriesKey) labels() string {
+	return promtext.Label("method", k.method) + "," + promtext.Label("route", k.route) + "," +
+		promtext.Label("status", strconv.Itoa(k.status))
+}
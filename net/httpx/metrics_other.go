@@ -0,0 +1,10 @@
+//go:build !go1.23
+
+package httpx
+
+import "net/http"
+
+// requestPattern returns "": Request.Pattern needs Go 1.23.
+func requestPattern(*http.Request) string {
+	return ""
+}
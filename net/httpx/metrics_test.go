@@ -0,0 +1,85 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestMetrics(t *testing.T) {
+	m := httpx.NewMetrics("app", &httpx.MetricsOptions{
+		Buckets: []float64{0.1, 1},
+		Route: func(r *http.Request) string {
+			if strings.HasPrefix(r.URL.Path, "/users/") {
+				return "/users/{id}"
+			}
+			return "other"
+		},
+	})
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	for _, target := range []string{"/users/1", "/users/2", "/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/users/3", nil))
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	got := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE app_http_requests_in_flight gauge\napp_http_requests_in_flight 0\n",
+		"# TYPE app_http_request_duration_seconds histogram\n",
+		`app_http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="200",le="0.1"} 2`,
+		`app_http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="200",le="+Inf"} 2`,
+		`app_http_request_duration_seconds_count{method="GET",route="/users/{id}",status="200"} 2`,
+		`app_http_request_duration_seconds_count{method="GET",route="other",status="404"} 1`,
+		`app_http_request_duration_seconds_count{method="OTHER",route="/users/{id}",status="200"} 1`,
+		"# TYPE app_http_response_size_bytes_total counter\n",
+		`app_http_response_size_bytes_total{method="GET",route="/users/{id}",status="200"} 10`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("metrics missing %q\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "/users/1") {
+		t.Errorf("metrics labelled by path instead of route:\n%s", got)
+	}
+}
+
+func TestMetricsLabelEscaping(t *testing.T) {
+	m := httpx.NewMetrics("", &httpx.MetricsOptions{
+		Route: func(r *http.Request) string { return "/café/\"quoted\"" },
+	})
+	m.Middleware(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `http_response_size_bytes_total{method="GET",route="/café/\"quoted\"",status="404"}`
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("metrics missing %s\n%s", want, b.String())
+	}
+}
+
+func TestMetricsInFlight(t *testing.T) {
+	m := httpx.NewMetrics("", nil)
+	var during string
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+		m.WriteTo(&b)
+		during = b.String()
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(during, "\nhttp_requests_in_flight 1\n") {
+		t.Errorf("in-flight gauge while serving:\n%s", during)
+	}
+}
@@ -0,0 +1,142 @@
+//go:build go1.23
+
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strings"
+)
+
+// Paginate returns a sequence of the pages of a paginated API: the
+// response to req, then to each request next derives from the previous
+// response, until next reports false. Requests are sent with c, so they
+// are retried and wait out 429 responses as c's RetryPolicy says; a nil c
+// is the zero Client.
+//
+// Each body is read into memory before the page is yielded, so the loop
+// body may read it, and is rewound before next is called, so next can
+// parse a cursor from it. A request error, or a response without a 2xx
+// status once retries are exhausted, is yielded with a nil response and
+// ends the sequence. Stopping the loop early stops fetching.
+//
+//	for resp, err := range httpx.Paginate(ctx, c, req, httpx.NextLink) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // decode resp.Body
+//	}
+//
+// Paginate requires Go 1.23 or later.
+func Paginate(ctx context.Context, c *Client, req *http.Request, next func(resp *http.Response) (*http.Request, bool)) iter.Seq2[*http.Response, error] {
+	if c == nil {
+		c = &Client{}
+	}
+	return func(yield func(*http.Response, error) bool) {
+		for r := req; r != nil; {
+			resp, err := fetchPage(ctx, c, r)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			body := resp.Body.(*pageBody)
+			if !yield(resp, nil) {
+				return
+			}
+			resp.Body = body.rewind()
+			var ok bool
+			if r, ok = next(resp); !ok {
+				return
+			}
+		}
+	}
+}
+
+// fetchPage sends req and reads the body of a 2xx response into memory.
+func fetchPage(ctx context.Context, c *Client, req *http.Request) (*http.Response, error) {
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("httpx: fetching page %s: %s", req.URL.Redacted(), resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("httpx: reading page %s: %w", req.URL.Redacted(), err)
+	}
+	resp.Body = &pageBody{Reader: bytes.NewReader(b), b: b}
+	return resp, nil
+}
+
+// pageBody is a response body held in memory.
+type pageBody struct {
+	*bytes.Reader
+	b []byte
+}
+
+func (b *pageBody) Close() error { return nil }
+
+func (b *pageBody) rewind() *pageBody {
+	b.Reader.Reset(b.b)
+	return b
+}
+
+// NextLink is a next function for Paginate following the rel="next" URL
+// of the response's Link header (RFC 8288), as GitHub's API and many
+// others paginate. The URL is resolved against the request's and fetched
+// with GET and the request's headers, except that credentials (the
+// Authorization and Cookie headers) are dropped when it points to another
+// scheme or host. Paginate sends it with its context.
+func NextLink(resp *http.Response) (*http.Request, bool) {
+	target, ok := nextLink(resp.Header.Values("Link"))
+	if !ok {
+		return nil, false
+	}
+	u, err := resp.Request.URL.Parse(target)
+	if err != nil {
+		return nil, false
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, false
+	}
+	req.Header = resp.Request.Header.Clone()
+	if prev := resp.Request.URL; u.Scheme != prev.Scheme || !strings.EqualFold(u.Host, prev.Host) {
+		// As http.Client does on redirects, keep credentials to their origin.
+		for _, h := range []string{"Authorization", "Www-Authenticate", "Cookie", "Cookie2", "Proxy-Authorization"} {
+			req.Header.Del(h)
+		}
+	}
+	return req, true
+}
+
+// nextLink returns the target of the first rel="next" link in the Link
+// header values.
+func nextLink(values []string) (string, bool) {
+	for _, v := range values {
+		for _, link := range strings.Split(v, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return target[1 : len(target)-1], true
+					}
+				}
+			}
+		}
+	}
+	return "", false
+}
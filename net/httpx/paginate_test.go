@@ -0,0 +1,128 @@
+//go:build go1.23
+
+package httpx_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+// pagedServer serves pages 1 to 3 of ?page=N, linking each to the next.
+// The first request for page 2 is rate limited.
+func pagedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	limited := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 2 && !limited {
+			limited = true
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if page > 3 {
+			http.NotFound(w, r)
+			return
+		}
+		if page < 3 {
+			w.Header().Add("Link", fmt.Sprintf(`</items?page=%d>; rel="next", </items?page=1>; rel="first"`, page+1))
+		}
+		fmt.Fprintf(w, "page %d", page)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPaginate(t *testing.T) {
+	srv := pagedServer(t)
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/items?page=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &httpx.Client{Retry: httpx.RetryPolicy{Backoff: time.Millisecond}}
+
+	var pages []string
+	for resp, err := range httpx.Paginate(context.Background(), c, req, httpx.NextLink) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		pages = append(pages, string(body))
+	}
+	if got, want := strings.Join(pages, ","), "page 1,page 2,page 3"; got != want {
+		t.Fatalf("pages = %q, want %q", got, want)
+	}
+}
+
+func TestPaginateCursorAndErrors(t *testing.T) {
+	srv := pagedServer(t)
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/items?page=3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// next reads the body the loop already consumed, and asks for a
+	// missing page.
+	next := func(resp *http.Response) (*http.Request, bool) {
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "page 3" {
+			t.Errorf("next got body %q, want it rewound", body)
+		}
+		r, _ := http.NewRequest(http.MethodGet, srv.URL+"/items?page=4", nil)
+		return r, true
+	}
+	var errs int
+	for resp, err := range httpx.Paginate(context.Background(), nil, req, next) {
+		if err != nil {
+			errs++
+			continue
+		}
+		io.ReadAll(resp.Body)
+	}
+	if errs != 1 {
+		t.Fatalf("got %d errors, want the 404 yielded once", errs)
+	}
+}
+
+func TestNextLinkCrossOrigin(t *testing.T) {
+	tests := []struct {
+		name, link string
+		wantAuth   bool
+	}{
+		{"same origin", "</items?page=2>", true},
+		{"absolute same origin", "<https://api.example.com/items?page=2>", true},
+		{"other host", "<https://evil.example.net/collect>", false},
+		{"other scheme", "<http://api.example.com/items?page=2>", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "https://api.example.com/items?page=1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer secret")
+			req.Header.Set("Cookie", "session=secret")
+			req.Header.Set("Accept", "application/json")
+			resp := &http.Response{Request: req, Header: http.Header{"Link": {tt.link + `; rel="next"`}}}
+
+			next, ok := httpx.NextLink(resp)
+			if !ok {
+				t.Fatal("NextLink() found no next link")
+			}
+			if got := next.Header.Get("Authorization") != "" && next.Header.Get("Cookie") != ""; got != tt.wantAuth {
+				t.Errorf("credentials sent to %s: %v, want %v", next.URL, got, tt.wantAuth)
+			}
+			if next.Header.Get("Accept") == "" {
+				t.Error("other headers dropped")
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package httpx
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: Burst requests may be made at once, and tokens
+// are refilled at Rate per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitStore keeps the token buckets of RateLimit. Implementations
+// backed by a shared store such as Redis let several instances enforce one
+// limit; they must be safe for concurrent use.
+type RateLimitStore interface {
+	// Take removes a token from the bucket for key under limit. If none is
+	// left it reports false and how long until one will be.
+	Take(ctx context.Context, key string, limit Limit) (ok bool, retryAfter time.Duration, err error)
+}
+
+// KeyFunc returns the key a request is rate limited under. An empty key
+// exempts the request.
+type KeyFunc func(r *http.Request) string
+
+// ClientIP is a KeyFunc returning the IP address of the connection's peer.
+// Behind a proxy that is the proxy's address; use HeaderKey with the
+// header the proxy sets instead.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// HeaderKey returns a KeyFunc keying requests by the value of header, e.g.
+// an API key. Requests without the header are not limited.
+func HeaderKey(header string) KeyFunc {
+	return func(r *http.Request) string { return r.Header.Get(header) }
+}
+
+// RateLimitOptions controls RateLimit.
+type RateLimitOptions struct {
+	// Key selects the bucket of a request. Defaults to ClientIP if nil.
+	Key KeyFunc
+
+	// Store keeps the buckets. Defaults to a new MemoryStore if nil.
+	Store RateLimitStore
+}
+
+// RateLimit returns middleware allowing each client limit.Burst requests at
+// once and limit.Rate per second on average. Requests over the limit get
+// 429 Too Many Requests with a Retry-After header. If the store fails, the
+// request is let through rather than turning an outage of the store into
+// one of the service. opts may be nil for the defaults.
+func RateLimit(limit Limit, opts *RateLimitOptions) func(http.Handler) http.Handler {
+	if opts == nil {
+		opts = &RateLimitOptions{}
+	}
+	key, store := opts.Key, opts.Store
+	if key == nil {
+		key = ClientIP
+	}
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ok, retryAfter, err := store.Take(r.Context(), k, limit)
+			if err != nil || ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			secs := max(int(math.Ceil(retryAfter.Seconds())), 1)
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		})
+	}
+}
+
+// MemoryStore is an in-process RateLimitStore. Buckets that have refilled
+// completely are dropped, so memory is bounded by the number of clients
+// active within one refill period.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Duration // how long an empty bucket takes to refill
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Take implements RateLimitStore.
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.sweep(now)
+
+	burst := float64(max(limit.Burst, 1))
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		if limit.Rate > 0 {
+			b.full = time.Duration(burst / limit.Rate * float64(time.Second))
+		}
+		s.buckets[key] = b
+	}
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	if limit.Rate <= 0 {
+		return false, time.Duration(math.MaxInt64), nil
+	}
+	return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second)), nil
+}
+
+// sweep drops the buckets that have refilled, at most once a minute.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for k, b := range s.buckets {
+		if b.full > 0 && now.Sub(b.last) >= b.full {
+			delete(s.buckets, k)
+		}
+	}
+}
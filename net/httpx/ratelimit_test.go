@@ -0,0 +1,89 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, httpx.Limit) (bool, time.Duration, error) {
+	return false, 0, errors.New("store down")
+}
+
+func TestRateLimit(t *testing.T) {
+	limit := httpx.Limit{Rate: 0.5, Burst: 2}
+	type req struct {
+		remoteAddr, apiKey string
+		wantStatus         int
+	}
+	tests := []struct {
+		name string
+		opts *httpx.RateLimitOptions
+		reqs []req
+	}{
+		{"by client IP", nil, []req{
+			{"192.0.2.1:1000", "", 200},
+			{"192.0.2.1:1001", "", 200},
+			{"192.0.2.1:1002", "", 429},
+			{"192.0.2.2:1000", "", 200},
+		}},
+		{"by header", &httpx.RateLimitOptions{Key: httpx.HeaderKey("X-API-Key")}, []req{
+			{"192.0.2.1:1000", "k1", 200},
+			{"192.0.2.2:1000", "k1", 200},
+			{"192.0.2.3:1000", "k1", 429},
+			{"192.0.2.3:1000", "k2", 200},
+			{"192.0.2.3:1000", "", 200},
+			{"192.0.2.3:1000", "", 200},
+			{"192.0.2.3:1000", "", 200},
+		}},
+		{"store failure lets requests through", &httpx.RateLimitOptions{Store: failingStore{}}, []req{
+			{"192.0.2.1:1000", "", 200},
+			{"192.0.2.1:1000", "", 200},
+			{"192.0.2.1:1000", "", 200},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := httpx.RateLimit(limit, tt.opts)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			for i, rq := range tt.reqs {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.RemoteAddr = rq.remoteAddr
+				if rq.apiKey != "" {
+					r.Header.Set("X-API-Key", rq.apiKey)
+				}
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, r)
+				if rec.Code != rq.wantStatus {
+					t.Fatalf("request %d: status = %d, want %d", i, rec.Code, rq.wantStatus)
+				}
+				if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "2" {
+					t.Errorf("request %d: Retry-After = %q, want 2", i, rec.Header().Get("Retry-After"))
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreRefill(t *testing.T) {
+	s := httpx.NewMemoryStore()
+	limit := httpx.Limit{Rate: 50, Burst: 1}
+	ctx := context.Background()
+	if ok, _, _ := s.Take(ctx, "k", limit); !ok {
+		t.Fatal("first Take() = false")
+	}
+	ok, retryAfter, _ := s.Take(ctx, "k", limit)
+	if ok || retryAfter <= 0 || retryAfter > 20*time.Millisecond {
+		t.Fatalf("Take() on empty bucket = (%v, %v), want (false, <=20ms)", ok, retryAfter)
+	}
+	time.Sleep(retryAfter + 5*time.Millisecond)
+	if ok, _, _ := s.Take(ctx, "k", limit); !ok {
+		t.Fatal("Take() after refill = false")
+	}
+}
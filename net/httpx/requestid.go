@@ -0,0 +1,75 @@
+package httpx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader is the header RequestIDMiddleware reads and sets.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// RequestIDMiddleware gives every request an ID: the X-Request-ID header
+// sent by the client or a proxy, or else a new ULID. The ID is stored in
+// the request context (see RequestID) and echoed in the X-Request-ID
+// response header. Incoming IDs longer than 128 bytes or containing
+// anything but printable ASCII are replaced, so they are safe to log.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newULID(time.Now())
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestID returns the ID RequestIDMiddleware assigned to the request ctx
+// belongs to, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// crockford is the Crockford base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID for t: 48 bits of Unix milliseconds followed by
+// 80 random bits, as 26 Crockford base32 characters, so IDs sort by time.
+func newULID(t time.Time) string {
+	var b [16]byte
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(b[:6], ms[2:])
+	rand.Read(b[6:])
+
+	// 128 bits in 26 characters: the first carries 3 bits, the rest 5.
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
@@ -0,0 +1,77 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"incoming ID kept", "req-123", true},
+		{"generated when missing", "", false},
+		{"unsafe ID replaced", "bad\nid", false},
+		{"overlong ID replaced", strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := httpx.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = httpx.RequestID(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(httpx.RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			before := time.Now().UnixMilli()
+			h.ServeHTTP(rec, req)
+
+			if header := rec.Header().Get(httpx.RequestIDHeader); header != got {
+				t.Errorf("response header = %q, context ID = %q", header, got)
+			}
+			if tt.keep {
+				if got != tt.incoming {
+					t.Errorf("RequestID() = %q, want %q", got, tt.incoming)
+				}
+				return
+			}
+			if ms, ok := ulidTime(got); !ok || ms < before || ms > time.Now().UnixMilli() {
+				t.Errorf("RequestID() = %q, want a ULID for the current time", got)
+			}
+		})
+	}
+}
+
+func TestRequestIDMissing(t *testing.T) {
+	if got := httpx.RequestID(httptest.NewRequest(http.MethodGet, "/", nil).Context()); got != "" {
+		t.Fatalf("RequestID() = %q, want empty", got)
+	}
+}
+
+// ulidTime decodes the millisecond timestamp of a ULID.
+func ulidTime(id string) (int64, bool) {
+	const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	if len(id) != 26 {
+		return 0, false
+	}
+	var ms int64
+	for i, c := range id {
+		v := strings.IndexRune(alphabet, c)
+		if v < 0 {
+			return 0, false
+		}
+		if i < 10 {
+			ms = ms<<5 | int64(v)
+		}
+	}
+	return ms, true
+}
@@ -0,0 +1,102 @@
+package httpx
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter wraps an http.ResponseWriter and records the status code,
+// the number of body bytes written and the first write error, for logging
+// and metrics middleware.
+//
+// It implements http.Flusher, http.Hijacker and http.Pusher whatever the
+// wrapped writer supports; when the wrapped writer (or any writer it
+// unwraps to) lacks the feature, Flush does nothing and Hijack and Push
+// return an error wrapping http.ErrNotSupported. Unwrap exposes the wrapped
+// writer to http.ResponseController.
+type ResponseWriter struct {
+	w        http.ResponseWriter
+	status   int
+	bytes    int64
+	err      error
+	hijacked bool
+}
+
+// NewResponseWriter returns a ResponseWriter wrapping w.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{w: w}
+}
+
+// Header implements http.ResponseWriter.
+func (w *ResponseWriter) Header() http.Header { return w.w.Header() }
+
+// WriteHeader implements http.ResponseWriter. Informational 1xx headers
+// other than 101 Switching Protocols are passed on but not recorded.
+func (w *ResponseWriter) WriteHeader(code int) {
+	if w.status == 0 && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		w.status = code
+	}
+	w.w.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter.
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.w.Write(b)
+	w.bytes += int64(n)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (w *ResponseWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	http.NewResponseController(w.w).Flush()
+}
+
+// Hijack implements http.Hijacker.
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.w).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Push implements http.Pusher.
+func (w *ResponseWriter) Push(target string, opts *http.PushOptions) error {
+	for rw := w.w; ; {
+		switch t := rw.(type) {
+		case http.Pusher:
+			return t.Push(target, opts)
+		case interface{ Unwrap() http.ResponseWriter }:
+			rw = t.Unwrap()
+		default:
+			return http.ErrNotSupported
+		}
+	}
+}
+
+// Unwrap returns the wrapped writer.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter { return w.w }
+
+// Status returns the response status: 200 if the handler wrote a body
+// without calling WriteHeader, 0 if it has written nothing yet.
+func (w *ResponseWriter) Status() int { return w.status }
+
+// BytesWritten returns the number of body bytes written.
+func (w *ResponseWriter) BytesWritten() int64 { return w.bytes }
+
+// Err returns the first error returned by the wrapped writer's Write,
+// typically because the client went away.
+func (w *ResponseWriter) Err() error { return w.err }
+
+// Hijacked reports whether the connection was taken over with Hijack.
+func (w *ResponseWriter) Hijacked() bool { return w.hijacked }
@@ -0,0 +1,117 @@
+package httpx_test
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+// failingWriter fails every Write after the first n bytes.
+type failingWriter struct {
+	http.ResponseWriter
+	n int
+}
+
+var errWrite = errors.New("write failed")
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if len(b) > w.n {
+		w.ResponseWriter.Write(b[:w.n])
+		n := w.n
+		w.n = 0
+		return n, errWrite
+	}
+	w.n -= len(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func TestResponseWriter(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    func(w http.ResponseWriter)
+		wantStatus int
+		wantBytes  int64
+		wantErr    error
+	}{
+		{"nothing written", func(w http.ResponseWriter) {}, 0, 0, nil},
+		{"implicit 200", func(w http.ResponseWriter) { w.Write([]byte("hey")) }, 200, 3, nil},
+		{"first status wins", func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusNotFound)
+			w.WriteHeader(http.StatusOK)
+		}, 404, 0, nil},
+		{"1xx not recorded", func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusEarlyHints)
+			w.WriteHeader(http.StatusAccepted)
+		}, 202, 0, nil},
+		{"flush implies 200", func(w http.ResponseWriter) { w.(http.Flusher).Flush() }, 200, 0, nil},
+		{"write error", func(w http.ResponseWriter) {
+			w.Write([]byte("abc"))
+			w.Write([]byte("defgh"))
+			w.Write([]byte("ij"))
+		}, 200, 4, errWrite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rw := httpx.NewResponseWriter(&failingWriter{ResponseWriter: rec, n: 4})
+			tt.handler(rw)
+			if rw.Status() != tt.wantStatus || rw.BytesWritten() != tt.wantBytes || !errors.Is(rw.Err(), tt.wantErr) {
+				t.Fatalf("recorded (%d, %d, %v), want (%d, %d, %v)",
+					rw.Status(), rw.BytesWritten(), rw.Err(), tt.wantStatus, tt.wantBytes, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResponseWriterPassthrough(t *testing.T) {
+	t.Run("unsupported", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rw := httpx.NewResponseWriter(rec)
+		rw.Flush()
+		if !rec.Flushed {
+			t.Error("Flush did not reach the recorder")
+		}
+		if _, _, err := rw.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Hijack() = %v, want %v", err, http.ErrNotSupported)
+		}
+		if err := rw.Push("/style.css", nil); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Push() = %v, want %v", err, http.ErrNotSupported)
+		}
+	})
+
+	t.Run("hijack", func(t *testing.T) {
+		hijacked := make(chan bool, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := httpx.NewResponseWriter(w)
+			conn, buf, err := rw.Hijack()
+			if err != nil {
+				t.Error(err)
+				hijacked <- false
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 204 No Content\r\n\r\n")
+			buf.Flush()
+			hijacked <- rw.Hijacked()
+		}))
+		defer srv.Close()
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusNoContent || !<-hijacked {
+			t.Fatalf("status %d over hijacked connection, want 204 and Hijacked", resp.StatusCode)
+		}
+	})
+}
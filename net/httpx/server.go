@@ -0,0 +1,68 @@
+package httpx
+
+import (
+	"net/http"
+	"time"
+)
+
+// Timeouts and limits NewServer sets unless overridden.
+const (
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultMaxHeaderBytes    = 64 << 10
+)
+
+// ServerOption configures the *http.Server built by NewServer. Any
+// func(*http.Server) can be converted to one for settings without a
+// dedicated option.
+type ServerOption func(*http.Server)
+
+// WithReadHeaderTimeout sets http.Server.ReadHeaderTimeout.
+func WithReadHeaderTimeout(d time.Duration) ServerOption {
+	return func(s *http.Server) { s.ReadHeaderTimeout = d }
+}
+
+// WithReadTimeout sets http.Server.ReadTimeout, which covers the request
+// body as well as the headers.
+func WithReadTimeout(d time.Duration) ServerOption {
+	return func(s *http.Server) { s.ReadTimeout = d }
+}
+
+// WithWriteTimeout sets http.Server.WriteTimeout. Use 0 for servers that
+// stream long responses, and bound the handlers with Timeout instead.
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return func(s *http.Server) { s.WriteTimeout = d }
+}
+
+// WithIdleTimeout sets http.Server.IdleTimeout, how long keep-alive
+// connections wait for the next request.
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return func(s *http.Server) { s.IdleTimeout = d }
+}
+
+// WithMaxHeaderBytes sets http.Server.MaxHeaderBytes.
+func WithMaxHeaderBytes(n int) ServerOption {
+	return func(s *http.Server) { s.MaxHeaderBytes = n }
+}
+
+// NewServer returns an *http.Server for h on addr with timeouts set, unlike
+// the zero value, whose unlimited timeouts let slow clients (slowloris)
+// tie up connections: the Default* constants above apply unless opts say
+// otherwise. The result can be run with graceful.Run.
+func NewServer(addr string, h http.Handler, opts ...ServerOption) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		ReadTimeout:       DefaultReadTimeout,
+		WriteTimeout:      DefaultWriteTimeout,
+		IdleTimeout:       DefaultIdleTimeout,
+		MaxHeaderBytes:    DefaultMaxHeaderBytes,
+	}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	return srv
+}
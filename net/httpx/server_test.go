@@ -0,0 +1,45 @@
+package httpx_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestNewServer(t *testing.T) {
+	type limits struct {
+		readHeader, read, write, idle time.Duration
+		maxHeaderBytes                int
+	}
+	tests := []struct {
+		name string
+		opts []httpx.ServerOption
+		want limits
+	}{
+		{"defaults", nil, limits{
+			httpx.DefaultReadHeaderTimeout, httpx.DefaultReadTimeout, httpx.DefaultWriteTimeout,
+			httpx.DefaultIdleTimeout, httpx.DefaultMaxHeaderBytes,
+		}},
+		{"overridden", []httpx.ServerOption{
+			httpx.WithReadHeaderTimeout(time.Second),
+			httpx.WithReadTimeout(2 * time.Second),
+			httpx.WithWriteTimeout(0),
+			httpx.WithIdleTimeout(3 * time.Second),
+			httpx.WithMaxHeaderBytes(4096),
+		}, limits{time.Second, 2 * time.Second, 0, 3 * time.Second, 4096}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httpx.NewServer(":8080", http.NotFoundHandler(), tt.opts...)
+			if srv.Addr != ":8080" || srv.Handler == nil {
+				t.Errorf("Addr, Handler = %q, %v", srv.Addr, srv.Handler)
+			}
+			got := limits{srv.ReadHeaderTimeout, srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout, srv.MaxHeaderBytes}
+			if got != tt.want {
+				t.Errorf("NewServer() limits = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package httpx
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/rin2yh/gouse/net/graceful"
+)
+
+// Running is a server started by Start.
+type Running struct {
+	addr net.Addr
+	h    *graceful.Handle
+}
+
+// Start is like RunWithContext but returns once the listener is bound,
+// leaving the server running in the background. Errors that prevent
+// serving, such as the address being in use, are returned directly.
+//
+// Addr reports the bound address, so ":0" can be used to pick a free port:
+//
+//	srv, err := httpx.Start(ctx, "127.0.0.1:0", mux)
+//	if err != nil {
+//	    return err
+//	}
+//	url := "http://" + srv.Addr().String()
+//	...
+//	err = srv.Shutdown(ctx)
+func Start(ctx context.Context, addr string, handler http.Handler, opts ...Option) (*Running, error) {
+	o := newOptions(opts)
+	srvs, bound, err := o.bind(addr, handler)
+	if err != nil {
+		return nil, err
+	}
+	return &Running{addr: bound, h: graceful.StartAll(ctx, srvs, &o.cfg)}, nil
+}
+
+// Addr returns the address the server listens on.
+func (r *Running) Addr() net.Addr { return r.addr }
+
+// Done returns a channel that is closed once the server has shut down.
+func (r *Running) Done() <-chan struct{} { return r.h.Done() }
+
+// Wait blocks until the server has shut down and returns the error
+// RunWithContext would have returned.
+func (r *Running) Wait() error { return r.h.Wait() }
+
+// Shutdown shuts the server down gracefully, as on SIGTERM, and waits for
+// it to finish or for ctx to be done. It returns the same error as Wait,
+// or ctx's error if ctx ended first; shutdown then carries on in the
+// background.
+func (r *Running) Shutdown(ctx context.Context) error { return r.h.Stop(ctx) }
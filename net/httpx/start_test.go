@@ -0,0 +1,45 @@
+package httpx_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestStart(t *testing.T) {
+	srv, err := httpx.Start(context.Background(), "127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + srv.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusTeapot)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+	select {
+	case <-srv.Done():
+	default:
+		t.Fatal("Done not closed after Shutdown")
+	}
+}
+
+func TestStartAddrInUse(t *testing.T) {
+	_, addr := listen(t)
+	if srv, err := httpx.Start(context.Background(), addr, http.NotFoundHandler()); err == nil {
+		srv.Shutdown(context.Background())
+		t.Fatal("Start() on a bound address = nil error")
+	}
+}
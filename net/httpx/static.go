@@ -0,0 +1,211 @@
+package httpx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCacheControl = "no-cache"
+
+// StaticOptions controls Static. The zero value serves files with
+// revalidation on every use and no SPA fallback.
+type StaticOptions struct {
+	// CacheControl is the Cache-Control header sent with every file.
+	// Defaults to "no-cache" if empty, which lets clients cache files but
+	// makes them revalidate with the ETag first; use e.g.
+	// "public, max-age=31536000, immutable" for fingerprinted assets.
+	CacheControl string
+
+	// SPA serves /index.html, with Cache-Control "no-cache", for missing
+	// paths without a file extension, so client-side routes of a single
+	// page application load the app instead of 404.
+	SPA bool
+}
+
+// Static returns a handler serving the files of fsys, such as an embed.FS.
+// Compared to http.FileServer it:
+//
+//   - sets an ETag from a hash of the content, so conditional requests
+//     work for embedded files, which have no modification time;
+//   - sets Cache-Control as configured in opts;
+//   - serves name.br or name.gz instead of name, with Content-Encoding set,
+//     when the client accepts that encoding and the pre-compressed file
+//     exists in fsys;
+//   - optionally falls back to index.html for single page applications.
+//
+// A directory is served through its index.html; there are no directory
+// listings. Only GET and HEAD are allowed. opts may be nil for the
+// defaults.
+func Static(fsys fs.FS, opts *StaticOptions) http.Handler {
+	if opts == nil {
+		opts = &StaticOptions{}
+	}
+	s := &static{fsys: fsys, opts: *opts}
+	if s.opts.CacheControl == "" {
+		s.opts.CacheControl = defaultCacheControl
+	}
+	return s
+}
+
+type static struct {
+	fsys  fs.FS
+	opts  StaticOptions
+	etags sync.Map // etagKey -> string
+}
+
+// etagKey identifies a version of a file; embedded files all have a zero
+// ModTime but never change.
+type etagKey struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (s *static) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+	cacheControl := s.opts.CacheControl
+	info, err := fs.Stat(s.fsys, name)
+	if err == nil && info.IsDir() {
+		name = path.Join(name, "index.html")
+		info, err = fs.Stat(s.fsys, name)
+	}
+	if errors.Is(err, fs.ErrNotExist) && s.opts.SPA && path.Ext(name) == "" {
+		name, cacheControl = "index.html", defaultCacheControl
+		info, err = fs.Stat(s.fsys, name)
+	}
+	if err != nil || info.IsDir() {
+		if err == nil || errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h := w.Header()
+	h.Add("Vary", "Accept-Encoding")
+	ctype := mime.TypeByExtension(path.Ext(name))
+	for _, enc := range []struct{ name, ext string }{{"br", ".br"}, {"gzip", ".gz"}} {
+		if !acceptsEncoding(r.Header.Get("Accept-Encoding"), enc.name) {
+			continue
+		}
+		if ci, err := fs.Stat(s.fsys, name+enc.ext); err == nil && !ci.IsDir() {
+			if ctype == "" {
+				ctype = "application/octet-stream" // sniffing would see compressed bytes
+			}
+			h.Set("Content-Encoding", enc.name)
+			name, info = name+enc.ext, ci
+			break
+		}
+	}
+	if ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+
+	content, err := s.open(name)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+	etag, err := s.etag(name, info)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", cacheControl)
+	http.ServeContent(w, r, name, info.ModTime(), content)
+}
+
+// open returns the content of name as an io.ReadSeeker, reading it into
+// memory if the fs.File cannot seek.
+func (s *static) open(name string) (readSeekCloser, error) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if rs, ok := f.(readSeekCloser); ok {
+		return rs, nil
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{bytes.NewReader(b)}, nil
+}
+
+type readSeekCloser interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+type nopCloser struct{ io.ReadSeeker }
+
+func (nopCloser) Close() error { return nil }
+
+// etag returns the strong ETag of the named file, hashing it the first
+// time each version is seen.
+func (s *static) etag(name string, info fs.FileInfo) (string, error) {
+	key := etagKey{name, info.Size(), info.ModTime()}
+	if v, ok := s.etags.Load(key); ok {
+		return v.(string), nil
+	}
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return "", err
+	}
+	etag := formatETag(sum.Sum(nil), false)
+	s.etags.Store(key, etag)
+	return etag, nil
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header value allows
+// enc, i.e. lists it without q=0, or lists "*" without q=0 and does not
+// list enc. An explicit coding takes precedence over "*" wherever it appears.
+func acceptsEncoding(header, enc string) bool {
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.TrimSpace(coding)
+		explicit := strings.EqualFold(coding, enc)
+		if !explicit && coding != "*" {
+			continue
+		}
+		accepted := true
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && q == 0 {
+				accepted = false
+			}
+		}
+		if explicit {
+			return accepted
+		}
+		wildcard = accepted
+	}
+	return wildcard
+}
@@ -0,0 +1,92 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestStatic(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("<html>app</html>")},
+		"app.js":        {Data: []byte("console.log(1)")},
+		"app.js.br":     {Data: []byte("br-bytes")},
+		"app.js.gz":     {Data: []byte("gz-bytes")},
+		"docs/a.txt":    {Data: []byte("a")},
+		"assets/x.css":  {Data: []byte("body{}")},
+		"assets/y.woff": {Data: []byte("font")},
+	}
+	plain := httpx.Static(fsys, nil)
+	spa := httpx.Static(fsys, &httpx.StaticOptions{SPA: true, CacheControl: "public, max-age=60"})
+
+	tests := []struct {
+		name           string
+		handler        http.Handler
+		method, path   string
+		acceptEncoding string
+		wantStatus     int
+		wantBody       string
+		wantEncoding   string
+		wantCache      string
+	}{
+		{"file", plain, "GET", "/app.js", "", 200, "console.log(1)", "", "no-cache"},
+		{"brotli preferred", plain, "GET", "/app.js", "gzip, br", 200, "br-bytes", "br", "no-cache"},
+		{"gzip", plain, "GET", "/app.js", "gzip", 200, "gz-bytes", "gzip", "no-cache"},
+		{"refused encoding", plain, "GET", "/app.js", "br;q=0", 200, "console.log(1)", "", "no-cache"},
+		{"wildcard", plain, "GET", "/app.js", "*", 200, "br-bytes", "br", "no-cache"},
+		{"explicit over refused wildcard", plain, "GET", "/app.js", "*;q=0, gzip", 200, "gz-bytes", "gzip", "no-cache"},
+		{"refused over wildcard", plain, "GET", "/app.js", "*, br;q=0", 200, "gz-bytes", "gzip", "no-cache"},
+		{"root index", plain, "GET", "/", "", 200, "<html>app</html>", "", "no-cache"},
+		{"directory without index", plain, "GET", "/docs/", "", 404, "404 page not found\n", "", ""},
+		{"missing", plain, "GET", "/settings", "", 404, "404 page not found\n", "", ""},
+		{"path traversal", plain, "GET", "/../app.js", "", 200, "console.log(1)", "", "no-cache"},
+		{"method not allowed", plain, "POST", "/app.js", "", 405, "Method Not Allowed\n", "", ""},
+		{"SPA file", spa, "GET", "/assets/x.css", "", 200, "body{}", "", "public, max-age=60"},
+		{"SPA route", spa, "GET", "/settings/profile", "", 200, "<html>app</html>", "", "no-cache"},
+		{"SPA missing asset", spa, "GET", "/assets/z.css", "", 404, "404 page not found\n", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.URL.Path = tt.path
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus || rec.Body.String() != tt.wantBody {
+				t.Fatalf("response = %d %q, want %d %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.wantCache {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCache)
+			}
+			if tt.wantStatus == 200 && rec.Header().Get("ETag") == "" {
+				t.Error("ETag missing")
+			}
+		})
+	}
+}
+
+func TestStaticETag(t *testing.T) {
+	h := httpx.Static(fstest.MapFS{"app.js": {Data: []byte("console.log(1)")}}, nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Header().Get("Content-Type") != "text/javascript; charset=utf-8" {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("conditional GET with ETag %s = %d, want 304", etag, rec.Code)
+	}
+}
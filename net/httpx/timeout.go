@@ -0,0 +1,123 @@
+package httpx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultTimeoutMsg = "request timed out\n"
+
+// Timeout returns middleware that gives each request d to complete. The
+// request context carries the deadline, so handlers and the calls they make
+// can stop early. If the handler has not started the response by then, the
+// client gets 503 Service Unavailable with msg as the body (a default
+// message if empty); either way, the handler's later writes fail with
+// http.ErrHandlerTimeout.
+//
+// Unlike http.TimeoutHandler, the response is not buffered: writes go
+// straight to the client and http.Flusher works, so streaming handlers can
+// be wrapped too. A panic in the handler is re-raised in the serving
+// goroutine.
+func Timeout(d time.Duration, msg string) func(http.Handler) http.Handler {
+	if msg == "" {
+		msg = defaultTimeoutMsg
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			tw := &timeoutWriter{w: w, header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+						return
+					}
+					close(done)
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+			}()
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+			case <-ctx.Done():
+				tw.timeout(msg)
+			}
+		})
+	}
+}
+
+// timeoutWriter passes writes through until the request times out. The
+// handler gets its own header map, copied to w when the response starts,
+// so the 503 written on timeout does not race with it.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	w           http.ResponseWriter
+	header      http.Header
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.header }
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.timedOut {
+		tw.writeHeaderLocked(code)
+	}
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	if tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	dst := tw.w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	return tw.w.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	http.NewResponseController(tw.w).Flush()
+}
+
+// timeout stops passing writes through and, if the handler had not started
+// the response, writes the 503.
+func (tw *timeoutWriter) timeout(msg string) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	if tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	tw.w.WriteHeader(http.StatusServiceUnavailable)
+	io.WriteString(tw.w, msg)
+}
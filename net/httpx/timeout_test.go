@@ -0,0 +1,69 @@
+package httpx_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestTimeout(t *testing.T) {
+	lateWrite := make(chan error, 1)
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		msg        string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "fast handler passes through",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if _, ok := r.Context().Deadline(); !ok {
+					t.Error("request context has no deadline")
+				}
+				w.Header().Set("X-Handler", "1")
+				w.WriteHeader(http.StatusAccepted)
+				w.Write([]byte("done"))
+			},
+			wantStatus: http.StatusAccepted,
+			wantBody:   "done",
+		},
+		{
+			name: "slow handler gets 503",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+				_, err := w.Write([]byte("too late"))
+				lateWrite <- err
+			},
+			msg:        "try again later",
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "try again later",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			httpx.Timeout(50*time.Millisecond, tt.msg)(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.wantStatus || rec.Body.String() != tt.wantBody {
+				t.Fatalf("response = %d %q, want %d %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+	if err := <-lateWrite; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Fatalf("write after timeout = %v, want %v", err, http.ErrHandlerTimeout)
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("recovered %v, want the handler's panic", p)
+		}
+	}()
+	h := httpx.Timeout(time.Second, "")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("boom") }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,55 @@
+package httpx
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNoCertificate is returned by Run when TLS is enabled with
+// WithTLSConfig but neither the config nor WithTLS provides a certificate.
+var ErrNoCertificate = errors.New("httpx: TLS enabled without a certificate")
+
+// WithTLS serves HTTPS using the certificate and key in the given PEM
+// files, as http.Server.ListenAndServeTLS does. The files are loaded before
+// Run binds its listener, so a bad certificate fails fast.
+func WithTLS(certFile, keyFile string) Option {
+	return func(o *options) {
+		o.tls = true
+		o.certFile, o.keyFile = certFile, keyFile
+	}
+}
+
+// WithTLSConfig serves HTTPS with cfg, which must provide the certificate
+// (Certificates, GetCertificate or GetConfigForClient) unless WithTLS is
+// also given. cfg is cloned; HTTP/2 is enabled unless cfg.NextProtos says
+// otherwise.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tls = true
+		o.tlsConfig = cfg
+	}
+}
+
+// configureTLS sets srv.TLSConfig from o, loading the certificate files.
+func (o *options) configureTLS(srv *http.Server) error {
+	if !o.tls {
+		return nil
+	}
+	cfg := &tls.Config{}
+	if o.tlsConfig != nil {
+		cfg = o.tlsConfig.Clone()
+	}
+	if o.certFile != "" || o.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+		if err != nil {
+			return fmt.Errorf("httpx: loading TLS certificate: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	} else if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil && cfg.GetConfigForClient == nil {
+		return ErrNoCertificate
+	}
+	srv.TLSConfig = cfg
+	return nil
+}
@@ -0,0 +1,114 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultMaxIdleConns          = 512
+	defaultMaxIdleConnsPerHost   = 64
+	defaultDialTimeout           = 5 * time.Second
+	defaultKeepAlive             = 30 * time.Second
+	defaultTLSHandshakeTimeout   = 5 * time.Second
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultExpectContinueTimeout = time.Second
+)
+
+// TransportOptions controls NewTransport. The zero value gives the
+// defaults noted on each field.
+type TransportOptions struct {
+	// MaxIdleConns caps the idle connections kept across all hosts.
+	// Defaults to 512 if zero.
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost caps the idle connections kept per host; the
+	// http.DefaultTransport limit of 2 makes high-QPS callers open and
+	// close connections constantly. Defaults to 64 if zero.
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost caps all connections per host. Zero means no limit.
+	MaxConnsPerHost int
+
+	// DialTimeout bounds establishing a TCP connection. Defaults to 5
+	// seconds if zero.
+	DialTimeout time.Duration
+
+	// TLSHandshakeTimeout bounds the TLS handshake. Defaults to 5 seconds
+	// if zero.
+	TLSHandshakeTimeout time.Duration
+
+	// ResponseHeaderTimeout bounds the wait for response headers once the
+	// request is written. Zero means no limit; the request context still
+	// applies.
+	ResponseHeaderTimeout time.Duration
+
+	// IdleConnTimeout is how long idle connections are kept. Defaults to
+	// 90 seconds if zero.
+	IdleConnTimeout time.Duration
+
+	// TLSConfig is used for HTTPS connections, cloned. TLS 1.2 is the
+	// minimum version unless it says otherwise.
+	TLSConfig *tls.Config
+
+	// Proxy selects the proxy for a request. Defaults to
+	// http.ProxyFromEnvironment if nil.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// DialContext, if set, replaces the default net.Dialer, e.g. to dial
+	// through a service mesh or pin addresses in tests.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// NewTransport returns an *http.Transport tuned for services calling other
+// services: larger idle pools than http.DefaultTransport, bounded dial and
+// handshake times, and HTTP/2 when the server offers it. opts may be nil
+// for the defaults.
+func NewTransport(opts *TransportOptions) *http.Transport {
+	if opts == nil {
+		opts = &TransportOptions{}
+	}
+	dial := opts.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{
+			Timeout:   orDefault(opts.DialTimeout, defaultDialTimeout),
+			KeepAlive: defaultKeepAlive,
+		}).DialContext
+	}
+	proxy := opts.Proxy
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+	tlsConfig := &tls.Config{}
+	if opts.TLSConfig != nil {
+		tlsConfig = opts.TLSConfig.Clone()
+	}
+	if tlsConfig.MinVersion == 0 {
+		tlsConfig.MinVersion = tls.VersionTLS12
+	}
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dial,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          orDefault(opts.MaxIdleConns, defaultMaxIdleConns),
+		MaxIdleConnsPerHost:   orDefault(opts.MaxIdleConnsPerHost, defaultMaxIdleConnsPerHost),
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		TLSHandshakeTimeout:   orDefault(opts.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: opts.ResponseHeaderTimeout,
+		IdleConnTimeout:       orDefault(opts.IdleConnTimeout, defaultIdleConnTimeout),
+		ExpectContinueTimeout: defaultExpectContinueTimeout,
+	}
+}
+
+// orDefault returns v, or def if v is zero or negative.
+func orDefault[T int | time.Duration](v, def T) T {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
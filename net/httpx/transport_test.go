@@ -0,0 +1,51 @@
+package httpx_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestNewTransport(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		tr := httpx.NewTransport(nil)
+		if tr.MaxIdleConnsPerHost <= http.DefaultMaxIdleConnsPerHost || tr.MaxIdleConns == 0 {
+			t.Errorf("idle pool = %d per host, %d total", tr.MaxIdleConnsPerHost, tr.MaxIdleConns)
+		}
+		if tr.TLSHandshakeTimeout == 0 || tr.IdleConnTimeout == 0 || tr.Proxy == nil || !tr.ForceAttemptHTTP2 {
+			t.Errorf("unset defaults: %+v", tr)
+		}
+		if tr.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+			t.Errorf("TLS MinVersion = %x, want TLS 1.2", tr.TLSClientConfig.MinVersion)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		errDial := errors.New("dial hook")
+		cfg := &tls.Config{MinVersion: tls.VersionTLS13, ServerName: "api.internal"}
+		tr := httpx.NewTransport(&httpx.TransportOptions{
+			MaxIdleConnsPerHost:   7,
+			ResponseHeaderTimeout: time.Second,
+			TLSConfig:             cfg,
+			DialContext: func(context.Context, string, string) (net.Conn, error) {
+				return nil, errDial
+			},
+		})
+		if tr.MaxIdleConnsPerHost != 7 || tr.ResponseHeaderTimeout != time.Second {
+			t.Errorf("options not applied: %d, %v", tr.MaxIdleConnsPerHost, tr.ResponseHeaderTimeout)
+		}
+		if tr.TLSClientConfig == cfg || tr.TLSClientConfig.MinVersion != tls.VersionTLS13 || tr.TLSClientConfig.ServerName != "api.internal" {
+			t.Error("TLSConfig not cloned as given")
+		}
+		_, err := (&http.Client{Transport: tr}).Get("http://example.invalid/")
+		if !errors.Is(err, errDial) {
+			t.Errorf("Get() = %v, want the DialContext error", err)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+
+	"github.com/rin2yh/gouse/net/graceful"
+)
+
+// WithUnixSocket serves on the unix domain socket at path instead of addr,
+// which is then ignored. A stale socket file left by a previous process is
+// removed first (see graceful.ListenUnix); the socket's permissions are
+// set to perm unless it is zero, and the file is removed once the server
+// has shut down.
+func WithUnixSocket(path string, perm os.FileMode) Option {
+	return func(o *options) {
+		o.unixPath, o.unixPerm = path, perm
+	}
+}
+
+// listenUnix listens on the socket given by WithUnixSocket and arranges for
+// its removal.
+func (o *options) listenUnix() (net.Listener, error) {
+	ln, err := graceful.ListenUnix(o.unixPath)
+	if err != nil {
+		return nil, err
+	}
+	if o.unixPerm != 0 {
+		if err := os.Chmod(o.unixPath, o.unixPerm); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	path := o.unixPath
+	o.cfg.Cleanups = append(o.cfg.Cleanups, func(context.Context) error {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	})
+	return ln, nil
+}
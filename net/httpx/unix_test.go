@@ -0,0 +1,51 @@
+//go:build unix
+
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rin2yh/gouse/net/httpx"
+)
+
+func TestWithUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	// A stale socket file, as left by a crashed process.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	cancel, done := startRun(t, client, "http://unix/", func(ctx context.Context) error {
+		return httpx.RunWithContext(ctx, ":0", http.NotFoundHandler(), httpx.WithUnixSocket(path, 0o660))
+	})
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o660 {
+		t.Errorf("socket permissions = %o, want 660", perm)
+	}
+
+	cancel()
+	if err := awaitShutdown(t, done); err != nil {
+		t.Fatalf("RunWithContext() = %v, want nil", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("socket file after shutdown: %v, want it removed", err)
+	}
+}
@@ -0,0 +1,109 @@
+// Package page provides cursor-based pagination helpers.
+//
+// Cursors are opaque to clients: each is the base64url encoding of a token
+// followed by its HMAC-SHA256 signature, so a tampered or forged cursor is
+// rejected by Decode.
+//
+// Typical handler flow:
+//
+//	codec := page.NewCodec(secret)
+//
+//	after, err := codec.Decode(r.URL.Query().Get("cursor"))
+//	if err != nil {
+//	    // respond 400
+//	}
+//	rows := store.ListAfter(after, page.FetchLimit(limit))
+//	p := page.Build(rows, limit, func(last Row) string {
+//	    return codec.Encode(last.ID)
+//	})
+//	json.NewEncoder(w).Encode(p)
+package page
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// ErrInvalidCursor is returned by Decode when a cursor is malformed or its
+// signature does not match.
+var ErrInvalidCursor = errors.New("page: invalid cursor")
+
+// Codec encodes and decodes signed cursors.
+type Codec struct {
+	key []byte
+}
+
+// NewCodec returns a Codec that signs cursors with key.
+// The same key must be used to decode cursors produced by Encode.
+func NewCodec(key []byte) *Codec {
+	return &Codec{key: append([]byte(nil), key...)}
+}
+
+// Encode returns an opaque cursor for token.
+// An empty token yields an empty cursor.
+func (c *Codec) Encode(token string) string {
+	if token == "" {
+		return ""
+	}
+	buf := append([]byte(token), c.sign([]byte(token))...)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// Decode returns the token stored in cursor.
+// An empty cursor (the first page) decodes to an empty token.
+func (c *Codec) Decode(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(buf) <= sha256.Size {
+		return "", ErrInvalidCursor
+	}
+	token, sig := buf[:len(buf)-sha256.Size], buf[len(buf)-sha256.Size:]
+	if !hmac.Equal(sig, c.sign(token)) {
+		return "", ErrInvalidCursor
+	}
+	return string(token), nil
+}
+
+func (c *Codec) sign(token []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(token)
+	return mac.Sum(nil)
+}
+
+// Page is a JSON response envelope for one page of results.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// FetchLimit returns the number of rows to fetch for a page of size limit.
+// One extra row is fetched so Build can tell whether another page exists.
+// A limit below 1 is treated as 1, as in Build.
+func FetchLimit(limit int) int {
+	return max(limit, 1) + 1
+}
+
+// Build turns rows fetched with FetchLimit(limit) into a Page.
+// If more than limit rows are present, the extra row is dropped, HasMore is
+// set and NextCursor is obtained by calling next with the last kept item.
+// A limit below 1 is treated as 1, so a page that has more always has a
+// cursor to fetch it with.
+//
+// Items is never nil, so it always encodes as a JSON array.
+func Build[T any](rows []T, limit int, next func(last T) string) Page[T] {
+	limit = max(limit, 1)
+	if len(rows) <= limit {
+		if rows == nil {
+			rows = []T{}
+		}
+		return Page[T]{Items: rows}
+	}
+
+	items := rows[:limit]
+	return Page[T]{Items: items, NextCursor: next(items[limit-1]), HasMore: true}
+}
@@ -0,0 +1,127 @@
+package page_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/rin2yh/gouse/page"
+)
+
+func TestCodec(t *testing.T) {
+	codec := page.NewCodec([]byte("secret"))
+
+	t.Run("round trip", func(t *testing.T) {
+		tests := map[string]string{
+			"simple":  "42",
+			"binary":  "id\x00\xff",
+			"unicode": "名前:10",
+		}
+		for name, token := range tests {
+			t.Run(name, func(t *testing.T) {
+				got, err := codec.Decode(codec.Encode(token))
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if got != token {
+					t.Errorf("Decode(Encode(%q)) = %q", token, got)
+				}
+			})
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if c := codec.Encode(""); c != "" {
+			t.Fatalf("Encode(\"\") = %q, want empty", c)
+		}
+		if got, err := codec.Decode(""); err != nil || got != "" {
+			t.Fatalf("Decode(\"\") = %q, %v", got, err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		valid := codec.Encode("42")
+		other := page.NewCodec([]byte("other")).Encode("42")
+		tests := map[string]string{
+			"not base64": "!!!",
+			"too short":  "YWJj",
+			"tampered":   "x" + valid[1:],
+			"wrong key":  other,
+		}
+		for name, cursor := range tests {
+			t.Run(name, func(t *testing.T) {
+				if _, err := codec.Decode(cursor); !errors.Is(err, page.ErrInvalidCursor) {
+					t.Fatalf("Decode(%q) error = %v, want ErrInvalidCursor", cursor, err)
+				}
+			})
+		}
+	})
+}
+
+func TestBuild(t *testing.T) {
+	next := func(last int) string { return strconv.Itoa(last) }
+
+	tests := map[string]struct {
+		rows  []int
+		limit int
+		want  page.Page[int]
+	}{
+		"more available": {
+			rows:  []int{1, 2, 3, 4},
+			limit: 3,
+			want:  page.Page[int]{Items: []int{1, 2, 3}, NextCursor: "3", HasMore: true},
+		},
+		"exactly limit": {
+			rows:  []int{1, 2, 3},
+			limit: 3,
+			want:  page.Page[int]{Items: []int{1, 2, 3}},
+		},
+		"fewer than limit": {
+			rows:  []int{1},
+			limit: 3,
+			want:  page.Page[int]{Items: []int{1}},
+		},
+		"nil rows": {
+			rows:  nil,
+			limit: 3,
+			want:  page.Page[int]{Items: []int{}},
+		},
+		"zero limit": {
+			rows:  []int{1, 2},
+			limit: 0,
+			want:  page.Page[int]{Items: []int{1}, NextCursor: "1", HasMore: true},
+		},
+		"negative limit": {
+			rows:  []int{1, 2},
+			limit: -1,
+			want:  page.Page[int]{Items: []int{1}, NextCursor: "1", HasMore: true},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := page.Build(tt.rows, tt.limit, next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Build() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("fetch limit", func(t *testing.T) {
+		for limit, want := range map[int]int{20: 21, 1: 2, 0: 2, -1: 2} {
+			if got := page.FetchLimit(limit); got != want {
+				t.Errorf("FetchLimit(%d) = %d, want %d", limit, got, want)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		b, err := json.Marshal(page.Build[int](nil, 10, next))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `{"items":[],"has_more":false}`; string(b) != want {
+			t.Fatalf("json = %s, want %s", b, want)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+// Package parsex provides strict parsing helpers for configuration values
+// and query parameters.
+//
+// Every function returns an *Error on failure that records the original
+// input, so callers can report exactly which value was rejected:
+//
+//	n, err := parsex.ParseInt[uint16](r.URL.Query().Get("port"))
+//	if err != nil {
+//	    return err // parsex.ParseInt: parsing "70000": value out of range
+//	}
+package parsex
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// ErrSyntax indicates that a value does not have the right syntax for the
+// target type. It is the same value as strconv.ErrSyntax.
+var ErrSyntax = strconv.ErrSyntax
+
+// ErrRange indicates that a value is out of range for the target type.
+// It is the same value as strconv.ErrRange.
+var ErrRange = strconv.ErrRange
+
+// Error records a failed parse.
+type Error struct {
+	Func  string // the failing function (ParseInt, ParseBool, ParseDuration)
+	Input string // the original input
+	Err   error  // the reason the parse failed (ErrSyntax, ErrRange, ...)
+}
+
+func (e *Error) Error() string {
+	return "parsex." + e.Func + ": parsing " + strconv.Quote(e.Input) + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Integer is the set of integer types accepted by ParseInt.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// ParseInt parses s as a base-10 integer of type T.
+// Surrounding whitespace is ignored. Values outside the range of T are
+// rejected with ErrRange rather than silently truncated.
+func ParseInt[T Integer](s string) (T, error) {
+	var zero T
+	bitSize := int(unsafe.Sizeof(zero)) * 8
+	in := strings.TrimSpace(s)
+
+	// T is signed when its zero value minus one wraps below zero.
+	if zero-1 < 0 {
+		n, err := strconv.ParseInt(in, 10, bitSize)
+		if err != nil {
+			return zero, numError("ParseInt", s, err)
+		}
+		return T(n), nil
+	}
+
+	n, err := strconv.ParseUint(in, 10, bitSize)
+	if err != nil {
+		return zero, numError("ParseInt", s, err)
+	}
+	return T(n), nil
+}
+
+// ParseBool parses s as a boolean. In addition to the values accepted by
+// strconv.ParseBool, it understands "y", "yes", "on" and "n", "no", "off".
+// Matching is case-insensitive and surrounding whitespace is ignored.
+func ParseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	}
+	return false, &Error{Func: "ParseBool", Input: s, Err: ErrSyntax}
+}
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+	year = 365 * day
+)
+
+// ParseDuration parses s like time.ParseDuration, additionally accepting
+// "d" (24h), "w" (7d) and "y" (365d) units, e.g. "1w2d", "1d12h", "-2d".
+// Only a leading sign is accepted, so "1d-1h" is a syntax error.
+// Surrounding whitespace is ignored.
+func ParseDuration(s string) (time.Duration, error) {
+	in := strings.TrimSpace(s)
+
+	body := strings.TrimLeft(in, "+-")
+	if len(in)-len(body) > 1 {
+		return 0, &Error{Func: "ParseDuration", Input: s, Err: ErrSyntax}
+	}
+	if body == "" {
+		return 0, &Error{Func: "ParseDuration", Input: s, Err: ErrSyntax}
+	}
+	neg := strings.HasPrefix(in, "-")
+
+	// Extract day, week and year components; everything else is handed to
+	// time.ParseDuration unchanged.
+	var days time.Duration
+	var rest strings.Builder
+	for body != "" {
+		i := 0
+		for i < len(body) && (body[i] == '.' || '0' <= body[i] && body[i] <= '9') {
+			i++
+		}
+		j := i
+		for j < len(body) && body[j] != '.' && (body[j] < '0' || body[j] > '9') {
+			j++
+		}
+		num, unit := body[:i], body[i:j]
+		body = body[j:]
+
+		var scale time.Duration
+		switch unit {
+		case "d":
+			scale = day
+		case "w":
+			scale = week
+		case "y":
+			scale = year
+		default:
+			if strings.ContainsAny(unit, "+-") {
+				return 0, &Error{Func: "ParseDuration", Input: s, Err: ErrSyntax}
+			}
+			rest.WriteString(num)
+			rest.WriteString(unit)
+			continue
+		}
+
+		if num == "" || num == "." {
+			return 0, &Error{Func: "ParseDuration", Input: s, Err: ErrSyntax}
+		}
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, numError("ParseDuration", s, err)
+		}
+		v := f * float64(scale)
+		if v > float64(1<<63-1)-float64(days) {
+			return 0, &Error{Func: "ParseDuration", Input: s, Err: ErrRange}
+		}
+		days += time.Duration(v)
+	}
+
+	var d time.Duration
+	if rest.Len() > 0 {
+		var err error
+		d, err = time.ParseDuration(rest.String())
+		if err != nil {
+			return 0, &Error{Func: "ParseDuration", Input: s, Err: ErrSyntax}
+		}
+	}
+
+	if d > 1<<63-1-days {
+		return 0, &Error{Func: "ParseDuration", Input: s, Err: ErrRange}
+	}
+	total := days + d
+	if neg {
+		total = -total
+	}
+	return total, nil
+}
+
+// numError converts a *strconv.NumError into an *Error for fn.
+func numError(fn, input string, err error) *Error {
+	var ne *strconv.NumError
+	if errors.As(err, &ne) {
+		err = ne.Err
+	}
+	return &Error{Func: fn, Input: input, Err: err}
+}
@@ -0,0 +1,142 @@
+package parsex_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/parsex"
+)
+
+func TestParseInt(t *testing.T) {
+	t.Run("int", func(t *testing.T) {
+		tests := map[string]struct {
+			input   string
+			want    int
+			wantErr error
+		}{
+			"positive":   {"42", 42, nil},
+			"negative":   {"-7", -7, nil},
+			"whitespace": {" 10 ", 10, nil},
+			"empty":      {"", 0, parsex.ErrSyntax},
+			"not number": {"abc", 0, parsex.ErrSyntax},
+		}
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				got, err := parsex.ParseInt[int](tt.input)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseInt(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+				}
+				if got != tt.want {
+					t.Errorf("ParseInt(%q) = %v, want %v", tt.input, got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("int8 range", func(t *testing.T) {
+		if _, err := parsex.ParseInt[int8]("128"); !errors.Is(err, parsex.ErrRange) {
+			t.Fatalf("expected ErrRange, got %v", err)
+		}
+		if got, err := parsex.ParseInt[int8]("-128"); err != nil || got != -128 {
+			t.Fatalf("ParseInt[int8](\"-128\") = %v, %v", got, err)
+		}
+	})
+
+	t.Run("uint16", func(t *testing.T) {
+		if _, err := parsex.ParseInt[uint16]("-1"); !errors.Is(err, parsex.ErrSyntax) {
+			t.Fatalf("expected ErrSyntax, got %v", err)
+		}
+		if _, err := parsex.ParseInt[uint16]("70000"); !errors.Is(err, parsex.ErrRange) {
+			t.Fatalf("expected ErrRange, got %v", err)
+		}
+		if got, err := parsex.ParseInt[uint16]("8080"); err != nil || got != 8080 {
+			t.Fatalf("ParseInt[uint16](\"8080\") = %v, %v", got, err)
+		}
+	})
+
+	t.Run("error records input", func(t *testing.T) {
+		_, err := parsex.ParseInt[int]("x1")
+		var pe *parsex.Error
+		if !errors.As(err, &pe) {
+			t.Fatalf("expected *parsex.Error, got %T", err)
+		}
+		if pe.Func != "ParseInt" || pe.Input != "x1" {
+			t.Fatalf("unexpected error fields: %+v", pe)
+		}
+		if want := `parsex.ParseInt: parsing "x1": invalid syntax`; err.Error() != want {
+			t.Fatalf("Error() = %q, want %q", err.Error(), want)
+		}
+	})
+}
+
+func TestParseBool(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    bool
+		wantErr bool
+	}{
+		"true":       {"true", true, false},
+		"yes":        {"yes", true, false},
+		"on upper":   {"ON", true, false},
+		"y":          {"y", true, false},
+		"one":        {"1", true, false},
+		"false":      {"false", false, false},
+		"no":         {"No", false, false},
+		"off":        {" off ", false, false},
+		"zero":       {"0", false, false},
+		"empty":      {"", false, true},
+		"unknown":    {"maybe", false, true},
+		"enable":     {"enable", false, true},
+		"whitespace": {"   ", false, true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parsex.ParseBool(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBool(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBool(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    time.Duration
+		wantErr error
+	}{
+		"standard":        {"1h30m", 90 * time.Minute, nil},
+		"zero":            {"0", 0, nil},
+		"day":             {"1d", 24 * time.Hour, nil},
+		"week":            {"2w", 14 * 24 * time.Hour, nil},
+		"mixed":           {"1w2d3h", (9*24 + 3) * time.Hour, nil},
+		"year":            {"1y", 365 * 24 * time.Hour, nil},
+		"fractional day":  {"1.5d", 36 * time.Hour, nil},
+		"negative":        {"-1d12h", -36 * time.Hour, nil},
+		"sub-second":      {"1d500ms", 24*time.Hour + 500*time.Millisecond, nil},
+		"empty":           {"", 0, parsex.ErrSyntax},
+		"sign only":       {"-", 0, parsex.ErrSyntax},
+		"missing unit":    {"5", 0, parsex.ErrSyntax},
+		"missing number":  {"d", 0, parsex.ErrSyntax},
+		"unknown unit":    {"3x", 0, parsex.ErrSyntax},
+		"double sign":     {"--1d", 0, parsex.ErrSyntax},
+		"inner sign":      {"1d-1h", 0, parsex.ErrSyntax},
+		"out of range":    {"100000w", 0, parsex.ErrRange},
+		"with whitespace": {" 1d ", 24 * time.Hour, nil},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parsex.ParseDuration(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseDuration(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
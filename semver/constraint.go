@@ -0,0 +1,164 @@
+package semver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidConstraint is returned by ParseConstraint for malformed input.
+var ErrInvalidConstraint = errors.New("semver: invalid constraint")
+
+// Constraint is a parsed version range such as ">=1.2.0 <2" or "^1.4 || ~2.0".
+type Constraint struct {
+	src    string
+	groups [][]comparator // OR of ANDs
+}
+
+// comparator matches versions in [lo, hi), with inclusivity controlled by
+// loIncl/hiIncl; a nil bound is unbounded. negate inverts the match.
+type comparator struct {
+	lo, hi         *Version
+	loIncl, hiIncl bool
+	negate         bool
+}
+
+// ParseConstraint parses a constraint expression.
+//
+// Comparators separated by whitespace must all match; groups separated by
+// "||" are alternatives. Supported operators are =, !=, >, >=, <, <=,
+// ~ (patch-level changes) and ^ (changes that do not modify the left-most
+// non-zero component). A bare version means "=". Versions may be partial:
+// "<2" is "<2.0.0", and "1.2" or "=1.2" matches any 1.2.x.
+//
+// Prerelease versions are compared by normal precedence, so ">=1.0.0"
+// also matches "2.0.0-rc.1".
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{src: s}
+	for _, group := range strings.Split(s, "||") {
+		fields := strings.Fields(group)
+		if len(fields) == 0 {
+			return Constraint{}, fmt.Errorf("%w: %q", ErrInvalidConstraint, s)
+		}
+
+		var cmps []comparator
+		for i := 0; i < len(fields); i++ {
+			tok := fields[i]
+			// Allow whitespace between an operator and its version: ">= 1.2".
+			if strings.Trim(tok, "=!<>~^") == "" && i+1 < len(fields) {
+				i++
+				tok += fields[i]
+			}
+			cmp, err := parseComparator(tok)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("%w: %q", ErrInvalidConstraint, s)
+			}
+			cmps = append(cmps, cmp)
+		}
+		c.groups = append(c.groups, cmps)
+	}
+	return c, nil
+}
+
+// MustParseConstraint is like ParseConstraint but panics if s cannot be parsed.
+func MustParseConstraint(s string) Constraint {
+	c, err := ParseConstraint(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func parseComparator(tok string) (comparator, error) {
+	op := tok[:len(tok)-len(strings.TrimLeft(tok, "=!<>~^"))]
+	v, n, err := parse(tok[len(op):])
+	if err != nil {
+		return comparator{}, err
+	}
+
+	// lo is v with missing components zeroed; next is the first version
+	// beyond the range the partial version describes.
+	lo := v
+	next := v
+	switch n {
+	case 1:
+		next = Version{Major: v.Major + 1}
+	case 2:
+		next = Version{Major: v.Major, Minor: v.Minor + 1}
+	}
+
+	switch op {
+	case "", "=", "!=":
+		if n == 3 {
+			return comparator{lo: &lo, hi: &lo, loIncl: true, hiIncl: true, negate: op == "!="}, nil
+		}
+		return comparator{lo: &lo, hi: &next, loIncl: true, negate: op == "!="}, nil
+	case ">":
+		if n == 3 {
+			return comparator{lo: &lo}, nil
+		}
+		return comparator{lo: &next, loIncl: true}, nil
+	case ">=":
+		return comparator{lo: &lo, loIncl: true}, nil
+	case "<":
+		return comparator{hi: &lo}, nil
+	case "<=":
+		if n == 3 {
+			return comparator{hi: &lo, hiIncl: true}, nil
+		}
+		return comparator{hi: &next}, nil
+	case "~":
+		hi := Version{Major: v.Major, Minor: v.Minor + 1}
+		if n == 1 {
+			hi = Version{Major: v.Major + 1}
+		}
+		return comparator{lo: &lo, hi: &hi, loIncl: true}, nil
+	case "^":
+		var hi Version
+		switch {
+		case v.Major > 0 || n == 1:
+			hi = Version{Major: v.Major + 1}
+		case v.Minor > 0 || n == 2:
+			hi = Version{Minor: v.Minor + 1}
+		default:
+			hi = Version{Patch: v.Patch + 1}
+		}
+		return comparator{lo: &lo, hi: &hi, loIncl: true}, nil
+	}
+	return comparator{}, ErrInvalidConstraint
+}
+
+func (c comparator) check(v Version) bool {
+	in := true
+	if c.lo != nil {
+		cmp := Compare(v, *c.lo)
+		in = cmp > 0 || (c.loIncl && cmp == 0)
+	}
+	if in && c.hi != nil {
+		cmp := Compare(v, *c.hi)
+		in = cmp < 0 || (c.hiIncl && cmp == 0)
+	}
+	return in != c.negate
+}
+
+// Check reports whether v satisfies c.
+func (c Constraint) Check(v Version) bool {
+	for _, group := range c.groups {
+		ok := true
+		for _, cmp := range group {
+			if !cmp.check(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the expression c was parsed from.
+func (c Constraint) String() string {
+	return c.src
+}
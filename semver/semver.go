@@ -0,0 +1,213 @@
+// Package semver parses and compares Semantic Versioning 2.0.0 versions.
+//
+//	v, err := semver.Parse("v1.4.2-rc.1")
+//	c, err := semver.ParseConstraint(">=1.2.0 <2")
+//	c.Check(v) // true
+package semver
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidVersion is returned by Parse for malformed versions.
+var ErrInvalidVersion = errors.New("semver: invalid version")
+
+// Version is a parsed semantic version.
+type Version struct {
+	Major, Minor, Patch uint64
+	Prerelease          string // dot-separated identifiers after "-", without the "-"
+	Build               string // build metadata after "+", without the "+"; ignored by Compare
+}
+
+// Parse parses s as a semantic version. A leading "v" is accepted.
+func Parse(s string) (Version, error) {
+	v, n, err := parse(s)
+	if err != nil || n != 3 {
+		return Version{}, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+	}
+	return v, nil
+}
+
+// MustParse is like Parse but panics if s cannot be parsed.
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// parse parses a possibly partial version ("1", "1.2", "1.2.3-rc") and
+// returns the number of numeric components present.
+func parse(s string) (Version, int, error) {
+	var v Version
+	s = strings.TrimPrefix(s, "v")
+
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		v.Build = s[i+1:]
+		if !validIdents(v.Build, false) {
+			return Version{}, 0, ErrInvalidVersion
+		}
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.Prerelease = s[i+1:]
+		if !validIdents(v.Prerelease, true) {
+			return Version{}, 0, ErrInvalidVersion
+		}
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Version{}, 0, ErrInvalidVersion
+	}
+	nums := [3]*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, ok := parseNumber(p)
+		if !ok {
+			return Version{}, 0, ErrInvalidVersion
+		}
+		*nums[i] = n
+	}
+	if len(parts) < 3 && (v.Prerelease != "" || v.Build != "") {
+		return Version{}, 0, ErrInvalidVersion
+	}
+	return v, len(parts), nil
+}
+
+// parseNumber parses a numeric identifier without leading zeros.
+func parseNumber(s string) (uint64, bool) {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	return n, err == nil
+}
+
+// validIdents reports whether s is a non-empty dot-separated list of
+// [0-9A-Za-z-] identifiers. Prerelease numeric identifiers must not have
+// leading zeros.
+func validIdents(s string, prerelease bool) bool {
+	if s == "" {
+		return false
+	}
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		numeric := true
+		for _, c := range id {
+			switch {
+			case '0' <= c && c <= '9':
+			case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', c == '-':
+				numeric = false
+			default:
+				return false
+			}
+		}
+		if prerelease && numeric && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the canonical form of v, without a "v" prefix.
+func (v Version) String() string {
+	s := strconv.FormatUint(v.Major, 10) + "." + strconv.FormatUint(v.Minor, 10) + "." + strconv.FormatUint(v.Patch, 10)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or +1 depending on whether v has lower, equal or
+// higher precedence than w. Build metadata is ignored.
+func (v Version) Compare(w Version) int {
+	return Compare(v, w)
+}
+
+// Less reports whether v has lower precedence than w.
+func (v Version) Less(w Version) bool {
+	return Compare(v, w) < 0
+}
+
+// Compare returns -1, 0 or +1 depending on whether a has lower, equal or
+// higher precedence than b, following SemVer 2.0.0 §11.
+// Build metadata is ignored. Compare can be passed to slices.SortFunc.
+func Compare(a, b Version) int {
+	if c := cmpUint(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := cmpUint(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := cmpUint(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(a.Prerelease, b.Prerelease)
+}
+
+func cmpUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func comparePrerelease(a, b string) int {
+	// A version without a prerelease has higher precedence.
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := cmpUint(an, bn); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			return -1 // numeric identifiers sort before alphanumeric ones
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmpUint(uint64(len(as)), uint64(len(bs)))
+}
+
+// Sort sorts vs in ascending order of precedence.
+func Sort(vs []Version) {
+	slices.SortStableFunc(vs, Compare)
+}
+
+// UniqueSort returns a sorted copy of vs with versions of equal precedence
+// removed, keeping the first occurrence of each.
+func UniqueSort(vs []Version) []Version {
+	result := slices.Clone(vs)
+	Sort(result)
+	return slices.CompactFunc(result, func(a, b Version) bool { return Compare(a, b) == 0 })
+}
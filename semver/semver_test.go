@@ -0,0 +1,175 @@
+package semver_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/rin2yh/gouse/semver"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    semver.Version
+		wantErr bool
+	}{
+		"basic":            {"1.2.3", semver.Version{Major: 1, Minor: 2, Patch: 3}, false},
+		"v prefix":         {"v0.10.0", semver.Version{Minor: 10}, false},
+		"prerelease":       {"1.0.0-rc.1", semver.Version{Major: 1, Prerelease: "rc.1"}, false},
+		"build":            {"1.0.0+20240101", semver.Version{Major: 1, Build: "20240101"}, false},
+		"both":             {"1.0.0-alpha+sha.5114f85", semver.Version{Major: 1, Prerelease: "alpha", Build: "sha.5114f85"}, false},
+		"hyphen in pre":    {"1.0.0-x-y.1", semver.Version{Major: 1, Prerelease: "x-y.1"}, false},
+		"empty":            {"", semver.Version{}, true},
+		"partial":          {"1.2", semver.Version{}, true},
+		"too many":         {"1.2.3.4", semver.Version{}, true},
+		"leading zero":     {"01.2.3", semver.Version{}, true},
+		"negative":         {"-1.2.3", semver.Version{}, true},
+		"empty prerelease": {"1.2.3-", semver.Version{}, true},
+		"empty ident":      {"1.2.3-a..b", semver.Version{}, true},
+		"pre leading zero": {"1.2.3-01", semver.Version{}, true},
+		"bad char":         {"1.2.3-a_b", semver.Version{}, true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := semver.Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, semver.ErrInvalidVersion) {
+				t.Fatalf("Parse(%q) error = %v, want ErrInvalidVersion", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("string round trip", func(t *testing.T) {
+		const s = "1.0.0-alpha.1+build.5"
+		if got := semver.MustParse("v" + s).String(); got != s {
+			t.Fatalf("String() = %q, want %q", got, s)
+		}
+	})
+}
+
+func TestCompare(t *testing.T) {
+	// Ascending precedence, from the SemVer 2.0.0 specification.
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+		"10.0.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, b := semver.MustParse(ordered[i]), semver.MustParse(ordered[i+1])
+		if got := semver.Compare(a, b); got != -1 {
+			t.Errorf("Compare(%s, %s) = %d, want -1", a, b, got)
+		}
+		if got := b.Compare(a); got != 1 {
+			t.Errorf("Compare(%s, %s) = %d, want 1", b, a, got)
+		}
+	}
+
+	if got := semver.MustParse("1.0.0+a").Compare(semver.MustParse("1.0.0+b")); got != 0 {
+		t.Errorf("build metadata should be ignored, got %d", got)
+	}
+	if !semver.MustParse("1.0.0").Less(semver.MustParse("1.0.1")) {
+		t.Error("expected 1.0.0 < 1.0.1")
+	}
+}
+
+func TestSort(t *testing.T) {
+	parse := func(ss ...string) []semver.Version {
+		vs := make([]semver.Version, len(ss))
+		for i, s := range ss {
+			vs[i] = semver.MustParse(s)
+		}
+		return vs
+	}
+
+	t.Run("sort", func(t *testing.T) {
+		vs := parse("1.10.0", "1.2.0", "1.0.0-rc.1", "1.0.0")
+		semver.Sort(vs)
+		if want := parse("1.0.0-rc.1", "1.0.0", "1.2.0", "1.10.0"); !reflect.DeepEqual(vs, want) {
+			t.Fatalf("Sort() = %v, want %v", vs, want)
+		}
+	})
+
+	t.Run("unique sort", func(t *testing.T) {
+		in := parse("2.0.0", "1.0.0", "2.0.0+b", "1.0.0")
+		got := semver.UniqueSort(in)
+		if want := parse("1.0.0", "2.0.0"); !reflect.DeepEqual(got, want) {
+			t.Fatalf("UniqueSort() = %v, want %v", got, want)
+		}
+		if in[0].String() != "2.0.0" {
+			t.Fatal("UniqueSort modified its input")
+		}
+	})
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{">=1.2.0 <2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0"}},
+		{"1.2.3", []string{"1.2.3", "1.2.3+build"}, []string{"1.2.4"}},
+		{"=1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0", "1.1.9"}},
+		{"!=1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{"!=1", []string{"2.0.0"}, []string{"1.5.0"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{">1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{"<=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0", "1.2.2"}},
+		{"~1", []string{"1.9.0"}, []string{"2.0.0"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"2.0.0", "1.2.2"}},
+		{"^0.2.3", []string{"0.2.9"}, []string{"0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"^0", []string{"0.9.9"}, []string{"1.0.0"}},
+		{"^1.4 || ~2.0", []string{"1.5.0", "2.0.5"}, []string{"2.1.0", "1.3.0"}},
+		{"< 2", []string{"1.9.9"}, []string{"2.0.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := semver.ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint(%q) error = %v", tt.constraint, err)
+			}
+			for _, s := range tt.match {
+				if !c.Check(semver.MustParse(s)) {
+					t.Errorf("%q should match %s", tt.constraint, s)
+				}
+			}
+			for _, s := range tt.noMatch {
+				if c.Check(semver.MustParse(s)) {
+					t.Errorf("%q should not match %s", tt.constraint, s)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, s := range []string{"", "||", ">=", "=>1.0.0", "1.2.x", "1.2-rc", ">=1.0.0, <2"} {
+			if _, err := semver.ParseConstraint(s); !errors.Is(err, semver.ErrInvalidConstraint) {
+				t.Errorf("ParseConstraint(%q) error = %v, want ErrInvalidConstraint", s, err)
+			}
+		}
+	})
+
+	t.Run("string", func(t *testing.T) {
+		if got := semver.MustParseConstraint(">=1.0 <2").String(); got != ">=1.0 <2" {
+			t.Fatalf("String() = %q", got)
+		}
+	})
+}
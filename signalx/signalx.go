@@ -0,0 +1,186 @@
+// Package signalx provides signal handling helpers with injectable signal
+// delivery for tests.
+//
+// NotifyContext behaves like signal.NotifyContext but records which signal
+// cancelled the context:
+//
+//	ctx, stop := signalx.NotifyContext(context.Background(), nil, syscall.SIGINT, syscall.SIGTERM)
+//	defer stop()
+//	<-ctx.Done()
+//	if sig, ok := signalx.Received(ctx); ok {
+//	    log.Printf("shutting down on %v", sig)
+//	}
+//
+// OnSignal runs a callback for non-terminating signals such as SIGHUP:
+//
+//	stop := signalx.OnSignal(ctx, nil, syscall.SIGHUP, reloadConfig)
+//	defer stop()
+//
+// Every function takes a Notifier; nil means real process signals (OS).
+// Tests pass a *Fake and call Send instead of signalling the process.
+package signalx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+)
+
+// Notifier registers channels for signal delivery.
+// os/signal's Notify and Stop satisfy this contract.
+type Notifier interface {
+	Notify(c chan<- os.Signal, sig ...os.Signal)
+	Stop(c chan<- os.Signal)
+}
+
+// OS delivers real process signals via os/signal.
+var OS Notifier = osNotifier{}
+
+type osNotifier struct{}
+
+func (osNotifier) Notify(c chan<- os.Signal, sig ...os.Signal) { signal.Notify(c, sig...) }
+func (osNotifier) Stop(c chan<- os.Signal)                     { signal.Stop(c) }
+
+// SignalError is the cancellation cause recorded by NotifyContext.
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return "signalx: received signal " + e.Signal.String()
+}
+
+// NotifyContext returns a copy of parent that is cancelled when one of sig
+// arrives, when stop is called, or when parent is done, whichever happens
+// first. The signal that cancelled the context is available via Received.
+//
+// The stop function unregisters the signal behaviour; as with
+// signal.NotifyContext, it should be called as soon as the context is no
+// longer needed. If n is nil, OS is used.
+func NotifyContext(parent context.Context, n Notifier, sig ...os.Signal) (ctx context.Context, stop context.CancelFunc) {
+	if n == nil {
+		n = OS
+	}
+	ctx, cancel := context.WithCancelCause(parent)
+
+	ch := make(chan os.Signal, 1)
+	n.Notify(ch, sig...)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case s := <-ch:
+			cancel(&SignalError{Signal: s})
+		case <-ctx.Done():
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			cancel(nil)
+			<-done
+			n.Stop(ch)
+		})
+	}
+}
+
+// Received reports the signal that cancelled ctx, if ctx (or one of its
+// parents) was cancelled by NotifyContext.
+func Received(ctx context.Context) (os.Signal, bool) {
+	var se *SignalError
+	if errors.As(context.Cause(ctx), &se) {
+		return se.Signal, true
+	}
+	return nil, false
+}
+
+// OnSignal calls fn each time sig arrives until ctx is done or stop is
+// called. Unlike NotifyContext, receiving sig does not cancel anything,
+// which suits signals such as SIGHUP or SIGUSR1.
+//
+// Calls to fn are sequential; a signal that arrives while fn is running is
+// delivered once fn returns, and further duplicates are dropped.
+// The stop function unregisters sig and waits for a running fn to return.
+// If n is nil, OS is used.
+func OnSignal(ctx context.Context, n Notifier, sig os.Signal, fn func(os.Signal)) (stop func()) {
+	if n == nil {
+		n = OS
+	}
+	ch := make(chan os.Signal, 1)
+	n.Notify(ch, sig)
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case s := <-ch:
+				fn(s)
+			case <-ctx.Done():
+				return
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(quit)
+			<-done
+			n.Stop(ch)
+		})
+	}
+}
+
+// Fake is a Notifier whose signals are delivered by Send, for tests.
+// The zero value is ready to use.
+type Fake struct {
+	mu   sync.Mutex
+	subs map[chan<- os.Signal][]os.Signal
+}
+
+// Notify implements Notifier. As with signal.Notify, registering no
+// signals relays every signal sent.
+func (f *Fake) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subs == nil {
+		f.subs = make(map[chan<- os.Signal][]os.Signal)
+	}
+	f.subs[c] = append(f.subs[c], sig...)
+}
+
+// Stop implements Notifier.
+func (f *Fake) Stop(c chan<- os.Signal) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.subs, c)
+}
+
+// Send delivers sig to every channel registered for it. Like os/signal,
+// delivery does not block: a channel without room misses the signal.
+// It reports whether any channel was registered for sig.
+func (f *Fake) Send(sig os.Signal) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	registered := false
+	for c, sigs := range f.subs {
+		if len(sigs) > 0 && !slices.Contains(sigs, sig) {
+			continue
+		}
+		registered = true
+		select {
+		case c <- sig:
+		default:
+		}
+	}
+	return registered
+}
@@ -0,0 +1,144 @@
+package signalx_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/signalx"
+)
+
+const testTimeout = 5 * time.Second
+
+func TestNotifyContext(t *testing.T) {
+	t.Run("signal cancels with cause", func(t *testing.T) {
+		var fake signalx.Fake
+		ctx, stop := signalx.NotifyContext(context.Background(), &fake, syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		if !fake.Send(syscall.SIGTERM) {
+			t.Fatal("expected SIGTERM to be registered")
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(testTimeout):
+			t.Fatal("context was not cancelled")
+		}
+
+		sig, ok := signalx.Received(ctx)
+		if !ok || sig != syscall.SIGTERM {
+			t.Fatalf("Received() = %v, %v, want SIGTERM, true", sig, ok)
+		}
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			t.Fatalf("ctx.Err() = %v, want context.Canceled", ctx.Err())
+		}
+		var se *signalx.SignalError
+		if !errors.As(context.Cause(ctx), &se) {
+			t.Fatalf("expected *SignalError cause, got %v", context.Cause(ctx))
+		}
+	})
+
+	t.Run("unregistered signal ignored", func(t *testing.T) {
+		var fake signalx.Fake
+		ctx, stop := signalx.NotifyContext(context.Background(), &fake, syscall.SIGINT)
+		defer stop()
+
+		if fake.Send(syscall.SIGHUP) {
+			t.Fatal("expected SIGHUP not to be registered")
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("context cancelled unexpectedly: %v", ctx.Err())
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		var fake signalx.Fake
+		ctx, stop := signalx.NotifyContext(context.Background(), &fake, syscall.SIGINT)
+		stop()
+		stop() // idempotent
+
+		if ctx.Err() == nil {
+			t.Fatal("expected context to be cancelled by stop")
+		}
+		if _, ok := signalx.Received(ctx); ok {
+			t.Fatal("expected no signal after stop")
+		}
+		if fake.Send(syscall.SIGINT) {
+			t.Fatal("expected SIGINT to be unregistered after stop")
+		}
+	})
+
+	t.Run("parent cancelled", func(t *testing.T) {
+		var fake signalx.Fake
+		parent, cancel := context.WithCancel(context.Background())
+		ctx, stop := signalx.NotifyContext(parent, &fake, syscall.SIGINT)
+		defer stop()
+
+		cancel()
+		<-ctx.Done()
+		if _, ok := signalx.Received(ctx); ok {
+			t.Fatal("expected no signal when parent is cancelled")
+		}
+	})
+
+	t.Run("received from child", func(t *testing.T) {
+		var fake signalx.Fake
+		ctx, stop := signalx.NotifyContext(context.Background(), &fake, syscall.SIGINT)
+		defer stop()
+		child, cancel := context.WithTimeout(ctx, testTimeout)
+		defer cancel()
+
+		fake.Send(syscall.SIGINT)
+		<-child.Done()
+		if sig, ok := signalx.Received(child); !ok || sig != syscall.SIGINT {
+			t.Fatalf("Received() = %v, %v, want SIGINT, true", sig, ok)
+		}
+	})
+}
+
+func TestOnSignal(t *testing.T) {
+	t.Run("repeated signals", func(t *testing.T) {
+		var fake signalx.Fake
+		got := make(chan struct{})
+		stop := signalx.OnSignal(context.Background(), &fake, syscall.SIGHUP, func(s os.Signal) {
+			if s != syscall.SIGHUP {
+				t.Errorf("fn received %v, want SIGHUP", s)
+			}
+			got <- struct{}{}
+		})
+		defer stop()
+
+		for i := 0; i < 3; i++ {
+			fake.Send(syscall.SIGHUP)
+			select {
+			case <-got:
+			case <-time.After(testTimeout):
+				t.Fatalf("fn not called for signal %d", i+1)
+			}
+		}
+	})
+
+	t.Run("stop unregisters", func(t *testing.T) {
+		var fake signalx.Fake
+		stop := signalx.OnSignal(context.Background(), &fake, syscall.SIGUSR1, func(os.Signal) {})
+		stop()
+		if fake.Send(syscall.SIGUSR1) {
+			t.Fatal("expected SIGUSR1 to be unregistered after stop")
+		}
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		var fake signalx.Fake
+		ctx, cancel := context.WithCancel(context.Background())
+		called := false
+		stop := signalx.OnSignal(ctx, &fake, syscall.SIGHUP, func(os.Signal) { called = true })
+		cancel()
+		stop()
+		if called {
+			t.Fatal("fn called without a signal")
+		}
+	})
+}
@@ -0,0 +1,83 @@
+// Package timeutil provides human-friendly duration parsing and formatting.
+//
+//	d, err := timeutil.ParseDuration("1d12h")   // 36h0m0s
+//	timeutil.FormatDuration(93*time.Minute, 0)   // "1h 33m"
+//	timeutil.FormatDuration(uptime, 2)           // "3d 4h"
+package timeutil
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rin2yh/gouse/parsex"
+)
+
+// Units longer than an hour, as understood by ParseDuration and produced
+// by FormatDuration. A year is always 365 days.
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+	Year = 365 * Day
+)
+
+// ParseDuration parses s like time.ParseDuration, additionally accepting
+// "d" (24h), "w" (7d) and "y" (365d) units, e.g. "1d12h", "2w", "-1y".
+// It is parsex.ParseDuration, so errors are *parsex.Error values.
+func ParseDuration(s string) (time.Duration, error) {
+	return parsex.ParseDuration(s)
+}
+
+var formatUnits = []struct {
+	name string
+	size time.Duration
+}{
+	{"y", Year},
+	{"d", Day},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+	{"µs", time.Microsecond},
+	{"ns", time.Nanosecond},
+}
+
+// FormatDuration formats d as space-separated components such as
+// "1d 2h 3m", omitting zero components.
+//
+// precision limits the output to that many of the most significant
+// components, truncating the rest: FormatDuration(26*time.Hour+90*time.Second, 2)
+// is "1d 2h". A precision of zero or less prints every component.
+// A zero duration is formatted as "0s".
+func FormatDuration(d time.Duration, precision int) string {
+	if d == 0 {
+		return "0s"
+	}
+
+	// Work with the magnitude as uint64 so that math.MinInt64 is handled.
+	sign := ""
+	u := uint64(d)
+	if d < 0 {
+		sign = "-"
+		u = -u
+	}
+
+	var parts []string
+	counted := 0
+	for _, unit := range formatUnits {
+		if u == 0 || precision > 0 && counted == precision {
+			break
+		}
+		size := uint64(unit.size)
+		if u >= size {
+			parts = append(parts, strconv.FormatUint(u/size, 10)+unit.name)
+			u %= size
+		}
+		// Count every unit from the first non-zero one, so that precision
+		// selects adjacent units: 1h 0m 5s at precision 2 is "1h", not "1h 5s".
+		if len(parts) > 0 {
+			counted++
+		}
+	}
+	return sign + strings.Join(parts, " ")
+}
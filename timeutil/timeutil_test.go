@@ -0,0 +1,89 @@
+package timeutil_test
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rin2yh/gouse/parsex"
+	"github.com/rin2yh/gouse/timeutil"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		"standard":       {"1h30m", 90 * time.Minute, false},
+		"zero":           {"0", 0, false},
+		"day":            {"1d12h", 36 * time.Hour, false},
+		"week":           {"2w", 2 * timeutil.Week, false},
+		"year":           {"1y", timeutil.Year, false},
+		"all units":      {"1y1w1d1h1m1s", timeutil.Year + timeutil.Week + timeutil.Day + time.Hour + time.Minute + time.Second, false},
+		"fractional":     {"0.5d", 12 * time.Hour, false},
+		"negative":       {"-1d", -timeutil.Day, false},
+		"plus sign":      {"+1d", timeutil.Day, false},
+		"sub-second":     {"1d1ms", timeutil.Day + time.Millisecond, false},
+		"empty":          {"", 0, true},
+		"sign only":      {"-", 0, true},
+		"missing unit":   {"10", 0, true},
+		"missing number": {"d", 0, true},
+		"unknown unit":   {"1x", 0, true},
+		"inner sign":     {"1d-1h", 0, true},
+		"overflow":       {"300y", 0, true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := timeutil.ParseDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			var pe *parsex.Error
+			if err != nil && !errors.As(err, &pe) {
+				t.Fatalf("ParseDuration(%q) error is %T, want *parsex.Error", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[string]struct {
+		d         time.Duration
+		precision int
+		want      string
+	}{
+		"zero":               {0, 0, "0s"},
+		"hours minutes":      {2*time.Hour + 3*time.Minute, 0, "2h 3m"},
+		"days":               {26*time.Hour + 90*time.Second, 0, "1d 2h 1m 30s"},
+		"precision":          {26*time.Hour + 90*time.Second, 2, "1d 2h"},
+		"precision adjacent": {time.Hour + 5*time.Second, 2, "1h"},
+		"precision exceeds":  {90 * time.Second, 5, "1m 30s"},
+		"years":              {timeutil.Year + timeutil.Day, 0, "1y 1d"},
+		"weeks as days":      {2 * timeutil.Week, 0, "14d"},
+		"sub-second":         {1500 * time.Microsecond, 0, "1ms 500µs"},
+		"nanoseconds":        {7, 0, "7ns"},
+		"negative":           {-90 * time.Minute, 0, "-1h 30m"},
+		"min duration":       {math.MinInt64, 1, "-292y"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := timeutil.FormatDuration(tt.d, tt.precision); got != tt.want {
+				t.Errorf("FormatDuration(%v, %d) = %q, want %q", tt.d, tt.precision, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		d := timeutil.Year + 3*timeutil.Day + 4*time.Hour + 5*time.Millisecond
+		got, err := timeutil.ParseDuration(strings.ReplaceAll(timeutil.FormatDuration(d, 0), " ", ""))
+		if err != nil || got != d {
+			t.Fatalf("round trip = %v, %v, want %v", got, err, d)
+		}
+	})
+}